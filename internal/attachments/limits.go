@@ -0,0 +1,88 @@
+package attachments
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"GoTodo/internal/config"
+)
+
+// allowedTypes are the sniffed content types accepted for upload. SVG and HTML are left
+// out on purpose: both can carry script.
+var allowedTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+	"application/zip": true,
+}
+
+// SniffLen is how many leading bytes Sniff needs.
+const SniffLen = 512
+
+// Sniff detects the content type of an upload from its first bytes, ignoring whatever
+// the client claimed. ok is false for types that may not be uploaded.
+func Sniff(head []byte) (contentType string, ok bool) {
+	detected := http.DetectContentType(head)
+	mediaType, _, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return detected, false
+	}
+	if mediaType == "text/plain" {
+		// Keep the charset so text is decoded the same way it was sniffed
+		return detected, true
+	}
+	return mediaType, allowedTypes[mediaType]
+}
+
+// IsImage reports whether the content type can be shown inline as an image.
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// MaxBytes is the largest single upload allowed.
+func MaxBytes() int64 {
+	return int64(config.Cfg.AttachmentMaxMB) << 20
+}
+
+// QuotaBytes is the total attachment size allowed per user.
+func QuotaBytes() int64 {
+	return int64(config.Cfg.AttachmentQuotaMB) << 20
+}
+
+// CleanFilename reduces a client-supplied file name to a display name without any
+// directory part or control characters.
+func CleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name
+}
+
+// FormatSize renders a byte count for display, e.g. "1.5 MB".
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
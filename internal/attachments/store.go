@@ -0,0 +1,143 @@
+// Package attachments stores the files attached to tasks. Metadata lives in the
+// task_attachments table; the bytes live in a Store addressed by an opaque key.
+package attachments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"GoTodo/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// Store keeps attachment contents. Implementations only need to handle the opaque keys
+// returned by NewKey.
+type Store interface {
+	// Save writes r under key and returns the number of bytes written. It fails without
+	// leaving a partial file once more than limit bytes have been read.
+	Save(key string, r io.Reader, limit int64) (int64, error)
+	// Open returns the contents stored under key.
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+// ErrTooLarge is returned by Save when the content exceeds the limit.
+var ErrTooLarge = errors.New("attachment is too large")
+
+// ErrInvalidKey is returned for keys that were not produced by NewKey.
+var ErrInvalidKey = errors.New("invalid attachment key")
+
+// NewKey returns a fresh random key. User-supplied file names are never used on disk.
+func NewKey() string {
+	return uuid.NewString()
+}
+
+// LocalStore keeps attachments as files below Dir, fanned out by the first two
+// characters of the key.
+type LocalStore struct {
+	Dir string
+}
+
+// NewLocalStore returns a LocalStore rooted at dir, creating the directory if needed.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create attachments directory: %v", err)
+	}
+	return &LocalStore{Dir: dir}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if _, err := uuid.Parse(key); err != nil || len(key) != 36 {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Dir, key[:2], key), nil
+}
+
+func (s *LocalStore) Save(key string, r io.Reader, limit int64) (int64, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return 0, fmt.Errorf("failed to create attachments directory: %v", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial upload
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create attachment file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write attachment: %v", err)
+	}
+	if n > limit {
+		return 0, ErrTooLarge
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return 0, fmt.Errorf("failed to store attachment: %v", err)
+	}
+	return n, nil
+}
+
+func (s *LocalStore) Open(key string) (io.ReadSeekCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (s *LocalStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete attachment: %v", err)
+	}
+	return nil
+}
+
+var (
+	defaultStore     Store
+	defaultStoreErr  error
+	defaultStoreOnce sync.Once
+)
+
+// Default returns the store configured by config.Cfg.AttachmentsDir.
+func Default() (Store, error) {
+	defaultStoreOnce.Do(func() {
+		defaultStore, defaultStoreErr = NewLocalStore(config.Cfg.AttachmentsDir)
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// DeleteFiles removes the given keys from the default store, logging failures. It is
+// used after rows that own attachments have been deleted.
+func DeleteFiles(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	store, err := Default()
+	if err != nil {
+		fmt.Printf("attachments: %v\n", err)
+		return
+	}
+	for _, key := range keys {
+		if err := store.Delete(key); err != nil {
+			fmt.Printf("attachments: failed to delete %s: %v\n", key, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +17,12 @@ type Config struct {
 	SiteName        string `json:"siteName,omitempty"`
 	SiteVersion     string `json:"siteVersion,omitempty"`
 	DefaultTimezone string `json:"defaultTimezone,omitempty"`
+	// Attachments are stored below AttachmentsDir; sizes are in megabytes
+	AttachmentsDir    string `json:"attachmentsDir,omitempty"`
+	AttachmentMaxMB   int    `json:"attachmentMaxMB,omitempty"`
+	AttachmentQuotaMB int    `json:"attachmentQuotaMB,omitempty"`
+	// Trashed tasks are purged for good after this many days
+	TrashRetentionDays int `json:"trashRetentionDays,omitempty"`
 }
 
 var Cfg Config
@@ -99,6 +106,9 @@ func Load() {
 			Cfg.SiteVersion = "v0.0.0"
 		}
 	}
+
+	loadAttachmentSettings()
+	loadTrashSettings()
 }
 
 func loadFromEnv() {
@@ -137,4 +147,41 @@ func loadFromEnv() {
 	} else {
 		Cfg.SiteVersion = "v0.0.0"
 	}
+	loadAttachmentSettings()
+	loadTrashSettings()
+}
+
+// loadAttachmentSettings fills the attachment settings missing from the config file from
+// ATTACHMENTS_DIR, ATTACHMENT_MAX_MB and ATTACHMENT_QUOTA_MB, or defaults.
+func loadAttachmentSettings() {
+	if Cfg.AttachmentsDir == "" {
+		if v := os.Getenv("ATTACHMENTS_DIR"); v != "" {
+			Cfg.AttachmentsDir = v
+		} else {
+			Cfg.AttachmentsDir = "data/attachments"
+		}
+	}
+	if Cfg.AttachmentMaxMB <= 0 {
+		Cfg.AttachmentMaxMB = 10
+		if v, err := strconv.Atoi(os.Getenv("ATTACHMENT_MAX_MB")); err == nil && v > 0 {
+			Cfg.AttachmentMaxMB = v
+		}
+	}
+	if Cfg.AttachmentQuotaMB <= 0 {
+		Cfg.AttachmentQuotaMB = 100
+		if v, err := strconv.Atoi(os.Getenv("ATTACHMENT_QUOTA_MB")); err == nil && v > 0 {
+			Cfg.AttachmentQuotaMB = v
+		}
+	}
+}
+
+// loadTrashSettings fills the trash retention missing from the config file from
+// TRASH_RETENTION_DAYS, or the default of 30 days.
+func loadTrashSettings() {
+	if Cfg.TrashRetentionDays <= 0 {
+		Cfg.TrashRetentionDays = 30
+		if v, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && v > 0 {
+			Cfg.TrashRetentionDays = v
+		}
+	}
 }
@@ -0,0 +1,318 @@
+// Package quickadd parses a single line of quick-add text such as
+// "Pay rent tomorrow 9am #home !high" into a task title, due date and time,
+// priority and #labels. It has no dependencies on the rest of the application:
+// relative dates are resolved against the time and location passed in.
+package quickadd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is what Parse recognised in a line. Words that are not part of a
+// date, time, priority or label make up the title, in their original order.
+type Result struct {
+	Title    string
+	DueDate  string   // YYYY-MM-DD in the given location, empty when none was typed
+	DueTime  string   // HH:MM, empty when none was typed
+	Priority string   // priority level name ("low", "medium", "high" or "urgent"), empty when none
+	Labels   []string // #words without the '#', in the order typed
+}
+
+// priorityNames are the levels accepted after '!', by name or by number 1-4.
+var priorityNames = []string{"low", "medium", "high", "urgent"}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "weds": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+// Parse reads a quick-add line. Relative dates are resolved from now in loc:
+//
+//   - today, tomorrow (tmr, tmrw)
+//   - a weekday (fri, friday, next fri, this fri): the first such day after today
+//   - weekend: the coming Saturday; next week: the coming Monday; next month: its 1st
+//   - in N days, weeks or months (N may be "a" or "an")
+//   - 2026-10-20, oct 20, 20 october (the next such day when no year is given)
+//
+// Times are 9am, 9:30pm, 9 pm, 21:00, noon or midnight; a time without a date
+// is today, or tomorrow once that time has passed. "on", "by" and "due" before a
+// date and "at" before a time are dropped. Only the first date and the first time
+// are used; later ones stay in the title. A '!' word is a priority and a '#' word
+// a label.
+func Parse(text string, now time.Time, loc *time.Location) Result {
+	return parse(text, now, loc, "")
+}
+
+// ParseOnDay is Parse for a line added on a given day (YYYY-MM-DD), such as a
+// calendar cell: a line without a date is due on that day, at its time if one
+// was typed. A date typed in the line still wins. An invalid day is ignored.
+func ParseOnDay(text, day string, now time.Time, loc *time.Location) Result {
+	return parse(text, now, loc, day)
+}
+
+func parse(text string, now time.Time, loc *time.Location, day string) Result {
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	var res Result
+	var due time.Time
+	hasDate := false
+	words := strings.Fields(text)
+	title := make([]string, 0, len(words))
+	for i := 0; i < len(words); {
+		word := words[i]
+		if res.Priority == "" && len(word) > 1 && word[0] == '!' {
+			if p := priorityName(word[1:]); p != "" {
+				res.Priority = p
+				i++
+				continue
+			}
+		}
+		if len(word) > 1 && word[0] == '#' {
+			res.Labels = append(res.Labels, trimPunct(word[1:]))
+			i++
+			continue
+		}
+		rest := lowerWords(words[i:])
+		if !hasDate {
+			skip := 0
+			if len(rest) > 1 && (rest[0] == "on" || rest[0] == "by" || rest[0] == "due") {
+				skip = 1
+			}
+			if d, n := parseDate(rest[skip:], today); n > 0 {
+				due, hasDate = d, true
+				i += skip + n
+				continue
+			}
+		}
+		if res.DueTime == "" {
+			skip := 0
+			if len(rest) > 1 && rest[0] == "at" {
+				skip = 1
+			}
+			if t, n := parseTime(rest[skip:]); n > 0 {
+				res.DueTime = t
+				i += skip + n
+				continue
+			}
+		}
+		title = append(title, word)
+		i++
+	}
+
+	if d, err := time.Parse("2006-01-02", day); err == nil && !hasDate {
+		due, hasDate = d, true
+	}
+	if res.DueTime != "" && !hasDate {
+		due = today
+		if res.DueTime <= local.Format("15:04") {
+			due = today.AddDate(0, 0, 1)
+		}
+		hasDate = true
+	}
+	if hasDate {
+		res.DueDate = due.Format("2006-01-02")
+	}
+	res.Title = strings.Join(title, " ")
+	return res
+}
+
+// priorityName returns the level for a word typed after '!', or "" when it is not one.
+func priorityName(s string) string {
+	s = strings.ToLower(trimPunct(s))
+	for i, name := range priorityNames {
+		if s == name || s == strconv.Itoa(i+1) {
+			return name
+		}
+	}
+	return ""
+}
+
+// trimPunct drops trailing punctuation, so "tomorrow," still reads as a date.
+func trimPunct(s string) string {
+	return strings.TrimRight(s, ",.;:")
+}
+
+func lowerWords(words []string) []string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = strings.ToLower(trimPunct(w))
+	}
+	return out
+}
+
+// parseDate reads a date at the start of words (lower-cased) and returns it with the
+// number of words used, or 0 words when they do not start with a date.
+func parseDate(words []string, today time.Time) (time.Time, int) {
+	if len(words) == 0 {
+		return time.Time{}, 0
+	}
+	w := words[0]
+	switch w {
+	case "today":
+		return today, 1
+	case "tomorrow", "tmr", "tmrw":
+		return today.AddDate(0, 0, 1), 1
+	case "weekend":
+		return today.AddDate(0, 0, daysAfter(today.Weekday(), time.Saturday)), 1
+	}
+	if d, err := time.Parse("2006-01-02", w); err == nil {
+		return d, 1
+	}
+	if day, ok := weekdays[w]; ok {
+		return today.AddDate(0, 0, daysAfter(today.Weekday(), day)), 1
+	}
+	if len(words) < 2 {
+		return time.Time{}, 0
+	}
+	next := words[1]
+	switch {
+	case (w == "next" || w == "this") && next == "weekend":
+		return today.AddDate(0, 0, daysAfter(today.Weekday(), time.Saturday)), 2
+	case w == "next" && next == "week":
+		return today.AddDate(0, 0, daysAfter(today.Weekday(), time.Monday)), 2
+	case w == "next" && next == "month":
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, time.UTC), 2
+	case w == "next" || w == "this":
+		if day, ok := weekdays[next]; ok {
+			return today.AddDate(0, 0, daysAfter(today.Weekday(), day)), 2
+		}
+	case w == "in" && len(words) >= 3:
+		n, err := strconv.Atoi(next)
+		if next == "a" || next == "an" {
+			n, err = 1, nil
+		}
+		if err != nil || n < 0 || n > 3650 {
+			return time.Time{}, 0
+		}
+		switch strings.TrimSuffix(words[2], "s") {
+		case "day":
+			return today.AddDate(0, 0, n), 3
+		case "week":
+			return today.AddDate(0, 0, 7*n), 3
+		case "month":
+			return today.AddDate(0, n, 0), 3
+		}
+	}
+	// Month and day in either order, e.g. "oct 20" or "20 october"
+	if m, ok := months[w]; ok {
+		if day, err := strconv.Atoi(strings.TrimSuffix(next, "th")); err == nil {
+			if d, ok := nextDate(today, m, day); ok {
+				return d, 2
+			}
+		}
+	}
+	if m, ok := months[next]; ok {
+		if day, err := strconv.Atoi(strings.TrimSuffix(w, "th")); err == nil {
+			if d, ok := nextDate(today, m, day); ok {
+				return d, 2
+			}
+		}
+	}
+	return time.Time{}, 0
+}
+
+// nextDate returns the first m/day on or after today, checking the day exists in that month.
+func nextDate(today time.Time, m time.Month, day int) (time.Time, bool) {
+	if day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	for year := today.Year(); year <= today.Year()+4; year++ {
+		d := time.Date(year, m, day, 0, 0, 0, 0, time.UTC)
+		if d.Month() == m && !d.Before(today) {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// daysAfter returns how many days after from the next to weekday is, from 1 to 7.
+func daysAfter(from, to time.Weekday) int {
+	n := (int(to) - int(from) + 7) % 7
+	if n == 0 {
+		n = 7
+	}
+	return n
+}
+
+// parseTime reads a time of day at the start of words (lower-cased) and returns it as
+// HH:MM with the number of words used, or 0 words when they do not start with a time.
+func parseTime(words []string) (string, int) {
+	if len(words) == 0 {
+		return "", 0
+	}
+	w := words[0]
+	switch w {
+	case "noon":
+		return "12:00", 1
+	case "midnight":
+		return "00:00", 1
+	}
+	used := 1
+	suffix := ""
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(w, s) {
+			suffix, w = s, strings.TrimSuffix(w, s)
+			break
+		}
+	}
+	if suffix == "" && len(words) > 1 && (words[1] == "am" || words[1] == "pm") {
+		suffix, used = words[1], 2
+	}
+
+	hourPart, minutePart, hasMinutes := strings.Cut(w, ":")
+	hour, err := strconv.Atoi(hourPart)
+	if err != nil || len(hourPart) > 2 || hour < 0 {
+		return "", 0
+	}
+	minute := 0
+	if hasMinutes {
+		if len(minutePart) != 2 {
+			return "", 0
+		}
+		if minute, err = strconv.Atoi(minutePart); err != nil || minute < 0 || minute > 59 {
+			return "", 0
+		}
+	}
+	switch suffix {
+	case "":
+		// A bare number is not a time ("buy 2 apples"); 24-hour times need minutes
+		if !hasMinutes || hour > 23 {
+			return "", 0
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return "", 0
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+	return time.Date(2000, 1, 1, hour, minute, 0, 0, time.UTC).Format("15:04"), used
+}
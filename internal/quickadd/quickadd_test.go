@@ -0,0 +1,106 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Friday 2026-10-16, 14:30 in a fixed UTC-4 zone.
+var (
+	testLoc = time.FixedZone("UTC-4", -4*60*60)
+	testNow = time.Date(2026, 10, 16, 14, 30, 0, 0, testLoc)
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Result
+	}{
+		{"Pay rent tomorrow 9am #home !high", Result{Title: "Pay rent", DueDate: "2026-10-17", DueTime: "09:00", Priority: "high", Labels: []string{"home"}}},
+		{"Buy milk", Result{Title: "Buy milk"}},
+		{"Buy 2 apples", Result{Title: "Buy 2 apples"}},
+
+		// Relative dates
+		{"Call mom today", Result{Title: "Call mom", DueDate: "2026-10-16"}},
+		{"Call mom tmrw", Result{Title: "Call mom", DueDate: "2026-10-17"}},
+		{"Report mon", Result{Title: "Report", DueDate: "2026-10-19"}},
+		{"Report fri", Result{Title: "Report", DueDate: "2026-10-23"}},
+		{"Report next fri", Result{Title: "Report", DueDate: "2026-10-23"}},
+		{"Report this sunday", Result{Title: "Report", DueDate: "2026-10-18"}},
+		{"Hike weekend", Result{Title: "Hike", DueDate: "2026-10-17"}},
+		{"Plan next week", Result{Title: "Plan", DueDate: "2026-10-19"}},
+		{"Budget next month", Result{Title: "Budget", DueDate: "2026-11-01"}},
+		{"Renew in 3 days", Result{Title: "Renew", DueDate: "2026-10-19"}},
+		{"Renew in a week", Result{Title: "Renew", DueDate: "2026-10-23"}},
+		{"Renew in 2 months", Result{Title: "Renew", DueDate: "2026-12-16"}},
+
+		// Absolute dates, month and day in either order
+		{"Ship on 2026-12-01", Result{Title: "Ship", DueDate: "2026-12-01"}},
+		{"Ship oct 20", Result{Title: "Ship", DueDate: "2026-10-20"}},
+		{"Ship 20 october", Result{Title: "Ship", DueDate: "2026-10-20"}},
+		{"Ship by dec 1", Result{Title: "Ship", DueDate: "2026-12-01"}},
+		{"Ship oct 10", Result{Title: "Ship", DueDate: "2027-10-10"}},
+		{"Ship feb 29", Result{Title: "Ship", DueDate: "2028-02-29"}},
+		{"Ship feb 30", Result{Title: "Ship feb 30"}},
+
+		// Times, today until they have passed
+		{"Call 9:30pm", Result{Title: "Call", DueDate: "2026-10-16", DueTime: "21:30"}},
+		{"Call 9 pm", Result{Title: "Call", DueDate: "2026-10-16", DueTime: "21:00"}},
+		{"Call at 15:00", Result{Title: "Call", DueDate: "2026-10-16", DueTime: "15:00"}},
+		{"Call at 9am", Result{Title: "Call", DueDate: "2026-10-17", DueTime: "09:00"}},
+		{"Call 14:30", Result{Title: "Call", DueDate: "2026-10-17", DueTime: "14:30"}},
+		{"Call 12am", Result{Title: "Call", DueDate: "2026-10-17", DueTime: "00:00"}},
+		{"Lunch noon", Result{Title: "Lunch", DueDate: "2026-10-17", DueTime: "12:00"}},
+		{"Backup midnight", Result{Title: "Backup", DueDate: "2026-10-17", DueTime: "00:00"}},
+		{"Call tomorrow at 5pm", Result{Title: "Call", DueDate: "2026-10-17", DueTime: "17:00"}},
+		{"Call 13pm", Result{Title: "Call 13pm"}},
+		{"Call 24:00", Result{Title: "Call 24:00"}},
+		{"Call 9:5", Result{Title: "Call 9:5"}},
+		{"Call -1:00", Result{Title: "Call -1:00"}},
+		{"Call 10:-1", Result{Title: "Call 10:-1"}},
+
+		// Only the first date, time and priority are used
+		{"today or tomorrow", Result{Title: "or tomorrow", DueDate: "2026-10-16"}},
+		{"Call 5pm or 6pm", Result{Title: "Call or 6pm", DueDate: "2026-10-16", DueTime: "17:00"}},
+		{"Fix bug !1 !urgent #work #urgent,", Result{Title: "Fix bug !urgent", Priority: "low", Labels: []string{"work", "urgent"}}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.in, testNow, testLoc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseUsesLocation(t *testing.T) {
+	// 02:00 UTC on Saturday is still Friday evening in testLoc
+	now := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+	got := Parse("Call today", now, testLoc)
+	if got.DueDate != "2026-10-16" {
+		t.Errorf("Parse in %s: due %q, want 2026-10-16", testLoc, got.DueDate)
+	}
+	got = Parse("Call today", now, nil)
+	if got.DueDate != "2026-10-17" {
+		t.Errorf("Parse without a location: due %q, want 2026-10-17", got.DueDate)
+	}
+}
+
+func TestParseOnDay(t *testing.T) {
+	tests := []struct {
+		in   string
+		day  string
+		want Result
+	}{
+		{"Standup", "2026-11-02", Result{Title: "Standup", DueDate: "2026-11-02"}},
+		{"Standup 9am", "2026-11-02", Result{Title: "Standup", DueDate: "2026-11-02", DueTime: "09:00"}},
+		{"Standup 9am", "2026-10-16", Result{Title: "Standup", DueDate: "2026-10-16", DueTime: "09:00"}},
+		{"Standup tomorrow", "2026-11-02", Result{Title: "Standup", DueDate: "2026-10-17"}},
+		{"Standup", "", Result{Title: "Standup"}},
+		{"Standup 9am", "not a day", Result{Title: "Standup", DueDate: "2026-10-17", DueTime: "09:00"}},
+	}
+	for _, tt := range tests {
+		if got := ParseOnDay(tt.in, tt.day, testNow, testLoc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOnDay(%q, %q) = %+v, want %+v", tt.in, tt.day, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"GoTodo/internal/storage"
+	"fmt"
+	"time"
+)
+
+// archiveInterval is how often completed tasks are checked for automatic archiving.
+const archiveInterval = time.Hour
+
+// startArchiveWorker archives completed tasks once they pass their owner's
+// archive_after_days setting, for the lifetime of the process.
+func startArchiveWorker() {
+	go func() {
+		ticker := time.NewTicker(archiveInterval)
+		defer ticker.Stop()
+		for {
+			archiveCompletedTasks()
+			<-ticker.C
+		}
+	}()
+}
+
+// archiveCompletedTasks runs one archiving pass.
+func archiveCompletedTasks() {
+	n, err := storage.ArchiveCompletedTasks()
+	if err != nil {
+		fmt.Printf("Archive: failed to archive completed tasks: %v\n", err)
+		return
+	}
+	if n > 0 {
+		fmt.Printf("Archive: archived %d completed task(s)\n", n)
+	}
+}
@@ -14,6 +14,26 @@ import (
 
 const MaxDescriptionLength = 100
 
+// MaxNotesLength caps the Markdown notes of a task.
+const MaxNotesLength = 10000
+
+// addTaskError reports a validation error from APIAddTask. It returns a 200 status with
+// X-Validation-Error, so the client keeps the sidebar open, and retargets the message to
+// the task form's error div, or to the quick-add box when APIQuickAdd handed over the
+// request.
+func addTaskError(w http.ResponseWriter, r *http.Request, msg string) {
+	if r.FormValue(quickAddFormFlag) == "1" {
+		quickAddError(w, msg)
+		return
+	}
+	w.Header().Set("X-Validation-Error", "true")
+	w.Header().Set("HX-Trigger", "description-error")
+	w.Header().Set("HX-Retarget", "#description-error")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, msg)
+}
+
 func APIAddTask(w http.ResponseWriter, r *http.Request) {
 	// fmt.Println("Request method: ", r.Method)
 	if r.Method != http.MethodPost {
@@ -23,6 +43,7 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 
 	title := strings.TrimSpace(r.FormValue("title"))
 	description := strings.TrimSpace(r.FormValue("description"))
+	notes := strings.TrimSpace(r.FormValue("notes"))
 	dueDate := strings.TrimSpace(r.FormValue("due_date"))
 	pageStr := strings.TrimSpace(r.FormValue("currentPage"))
 
@@ -33,31 +54,51 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 
 	// Validate description length
 	if len(description) > MaxDescriptionLength {
-		// On validation failure, return a 200 status with the error message
-		// and use HX-Retarget and HX-Reswap to update the error div specifically
-		// Tell the client this was a validation error so JS won't close the sidebar
-		w.Header().Set("X-Validation-Error", "true")
-		w.Header().Set("HX-Trigger", "description-error")   // Keep trigger for potential JS handling
-		w.Header().Set("HX-Retarget", "#description-error") // Target the specific error div
-		w.Header().Set("HX-Reswap", "innerHTML")            // Swap the content inside the error div
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "Description must be %d characters or less", MaxDescriptionLength) // The content to swap
+		addTaskError(w, r, fmt.Sprintf("Description must be %d characters or less", MaxDescriptionLength))
 		return
 	}
 
 	if title == "" {
-		// Title missing — return validation error and appropriate message
-		w.Header().Set("X-Validation-Error", "true")
-		w.Header().Set("HX-Trigger", "description-error")   // Keep trigger for potential JS handling
-		w.Header().Set("HX-Retarget", "#description-error") // Target the specific error div
-		w.Header().Set("HX-Reswap", "innerHTML")            // Swap the content inside the error div
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "Title is required")
+		addTaskError(w, r, "Title is required")
 		return
 		// http.Error(w, "Title is required", http.StatusBadRequest)
 		// return
 	}
 
+	if len(notes) > MaxNotesLength {
+		addTaskError(w, r, fmt.Sprintf("Notes must be %d characters or less", MaxNotesLength))
+		return
+	}
+
+	repeatRule, err := repeatRuleFromForm(r)
+	if err != nil {
+		addTaskError(w, r, fmt.Sprintf("Invalid repeat rule: %v", err))
+		return
+	}
+
+	priority, err := tasks.ParsePriority(r.FormValue("priority"))
+	if err != nil {
+		addTaskError(w, r, "Invalid priority")
+		return
+	}
+
+	estimate, estimateUnit, estimateSubmitted, err := formEstimate(r)
+	if err != nil {
+		addTaskError(w, r, fmt.Sprintf("Invalid estimate: %v", err))
+		return
+	}
+
+	dueTime, err := dueTimeFromForm(r, dueDate)
+	if err != nil {
+		addTaskError(w, r, fmt.Sprintf("Invalid due time: %v", err))
+		return
+	}
+	reminderOffsets, remindersSubmitted := formReminderOffsets(r)
+	if len(reminderOffsets) > 0 && dueDate == "" {
+		addTaskError(w, r, "Reminders need a due date")
+		return
+	}
+
 	db, err := storage.OpenDatabase()
 	if err != nil {
 		fmt.Println("We failed to open the database.")
@@ -96,10 +137,15 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	fieldValues, fieldsSubmitted, err := formFieldValues(r, userID)
+	if err != nil {
+		addTaskError(w, r, err.Error())
+		return
+	}
 
 	// Determine next position within non-favorite group for this user
 	var nextPos int
-	err = db.QueryRow(context.Background(), "SELECT COALESCE(MAX(position),0) + 1 FROM tasks WHERE user_id = $1 AND (is_favorite IS NULL OR is_favorite = false)", userID).Scan(&nextPos)
+	err = db.QueryRow(context.Background(), "SELECT COALESCE(MAX(position),0) + 1 FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND (is_favorite IS NULL OR is_favorite = false)", userID).Scan(&nextPos)
 	if err != nil {
 		fmt.Printf("Error determining next position: %v\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -109,14 +155,15 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 	// Handle optional project association
 	projectIDStr := strings.TrimSpace(r.FormValue("project_id"))
 	var newTaskProject *int
+	var newTaskID int
 	if projectIDStr == "" {
 		// Insert without project_id (NULL)
 		dueSQL := "NULL"
 		if dueDate != "" {
 			dueSQL = "$6"
-			_, err = db.Exec(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, due_date) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, "+dueSQL+")", title, description, false, userID, nextPos, dueDate)
+			err = db.QueryRow(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, repeat_rule, priority, due_time, notes, due_date) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, $7, $8, CAST($9 AS TIME), $10, "+dueSQL+") RETURNING id", title, description, false, userID, nextPos, dueDate, nullableRule(repeatRule), priority, nullableTime(dueTime), notes).Scan(&newTaskID)
 		} else {
-			_, err = db.Exec(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5)", title, description, false, userID, nextPos)
+			err = db.QueryRow(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, repeat_rule, priority, notes) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, $6, $7, $8) RETURNING id", title, description, false, userID, nextPos, nullableRule(repeatRule), priority, notes).Scan(&newTaskID)
 		}
 	} else {
 		pid, errConv := strconv.Atoi(projectIDStr)
@@ -130,9 +177,9 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if dueDate != "" {
-			_, err = db.Exec(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id, due_date) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, $6, $7)", title, description, false, userID, nextPos, pid, dueDate)
+			err = db.QueryRow(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id, due_date, repeat_rule, priority, due_time, notes) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, $6, $7, $8, $9, CAST($10 AS TIME), $11) RETURNING id", title, description, false, userID, nextPos, pid, dueDate, nullableRule(repeatRule), priority, nullableTime(dueTime), notes).Scan(&newTaskID)
 		} else {
-			_, err = db.Exec(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, $6)", title, description, false, userID, nextPos, pid)
+			err = db.QueryRow(context.Background(), "INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id, repeat_rule, priority, notes) VALUES ($1, $2, $3, $4, NOW() AT TIME ZONE 'UTC', $5, $6, $7, $8, $9) RETURNING id", title, description, false, userID, nextPos, pid, nullableRule(repeatRule), priority, notes).Scan(&newTaskID)
 		}
 		if err == nil {
 			newTaskProject = &pid
@@ -145,6 +192,27 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if tagIDs, ok := formTagIDs(r); ok && len(tagIDs) > 0 {
+		if err := storage.SetTaskTags(newTaskID, userID, tagIDs); err != nil {
+			fmt.Printf("Error assigning tags to task %d: %v\n", newTaskID, err)
+		}
+	}
+	if remindersSubmitted && len(reminderOffsets) > 0 {
+		if err := storage.SetTaskReminders(newTaskID, userID, reminderOffsets); err != nil {
+			fmt.Printf("Error saving reminders for new task: %v\n", err)
+		}
+	}
+	if fieldsSubmitted {
+		if err := storage.SetTaskFieldValues(newTaskID, userID, fieldValues); err != nil {
+			fmt.Printf("Error saving custom fields for task %d: %v\n", newTaskID, err)
+		}
+	}
+	if estimateSubmitted && estimateUnit != "" {
+		if err := storage.SetTaskEstimate(newTaskID, userID, estimate, estimateUnit); err != nil {
+			fmt.Printf("Error saving estimate for task %d: %v\n", newTaskID, err)
+		}
+	}
+
 	// After successful insertion, determine the correct page to display
 	pageSize := utils.AppConstants.PageSize
 	if sess, err := sessionstore.Store.Get(r, "session"); err == nil && sess != nil {
@@ -187,21 +255,8 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var totalTasks int
 	// Count tasks scoped to project if filter is active, otherwise count all
-	if projectFilterPtr == nil {
-		err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1", userID).Scan(&totalTasks)
-	} else {
-		projectCond := ""
-		args := []interface{}{userID}
-		if *projectFilterPtr == 0 {
-			projectCond = " AND project_id IS NULL"
-		} else {
-			projectCond = " AND project_id = $2"
-			args = append(args, *projectFilterPtr)
-		}
-		err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1"+projectCond, args...).Scan(&totalTasks)
-	}
+	totalTasks, err := storage.CountTopLevelTasks(userID, projectFilterPtr)
 	if err != nil {
 		http.Error(w, "Error counting tasks after add: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -220,9 +275,9 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 
 	var taskList []tasks.Task
 	if projectFilterPtr != nil {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, &userID, timezone, projectFilterPtr)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, &userID, timezone, projectFilterPtr, sessionTaskOrder(r))
 	} else {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, &userID, timezone)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, &userID, timezone, nil, sessionTaskOrder(r))
 	}
 	if err != nil {
 		http.Error(w, "Error fetching tasks after add: "+err.Error(), http.StatusInternalServerError)
@@ -293,16 +348,8 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Count tasks scoped to target project
-	var totalTasksTarget int
-	projectCond := ""
-	args := []interface{}{userID}
-	if *targetFilterPtr == 0 {
-		projectCond = " AND project_id IS NULL"
-	} else {
-		projectCond = " AND project_id = $2"
-		args = append(args, *targetFilterPtr)
-	}
-	if err := db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1"+projectCond, args...).Scan(&totalTasksTarget); err != nil {
+	totalTasksTarget, err := storage.CountTopLevelTasks(userID, targetFilterPtr)
+	if err != nil {
 		http.Error(w, "Error counting tasks for new project: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -362,7 +409,7 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch tasks for the target project and page
-	taskListTarget, totalTasksTarget, err := tasks.ReturnPaginationForUserWithProject(page, pageSize, &userID, timezone, targetFilterPtr)
+	taskListTarget, totalTasksTarget, err := tasks.ReturnPaginationForUserFiltered(page, pageSize, &userID, timezone, targetFilterPtr, sessionTaskOrder(r))
 	if err != nil {
 		http.Error(w, "Error fetching tasks for new project: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -395,16 +442,7 @@ func APIAddTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Compute completed/incomplete counts for target
-	completedCountT := 0
-	incompleteCountT := 0
-	if db != nil {
-		if err := db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&completedCountT); err != nil {
-			completedCountT = 0
-		}
-		if err := db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&incompleteCountT); err != nil {
-			incompleteCountT = 0
-		}
-	}
+	completedCountT, incompleteCountT, _ := storage.CountTasksByDone(userID, targetFilterPtr)
 
 	ctxT := map[string]interface{}{
 		"FavoriteTasks":    favsT,
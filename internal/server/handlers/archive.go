@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// archiveContext builds the template data shared by archive.html and archive_list.html.
+// A page past the end is clamped to the last page.
+func archiveContext(r *http.Request, userID int, timezone string, page int) (map[string]interface{}, error) {
+	pageSize := sessionPageSize(r)
+	if page < 1 {
+		page = 1
+	}
+	list, total, err := storage.GetArchive(userID, timezone, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if lastPage := max((total+pageSize-1)/pageSize, 1); page > lastPage {
+		page = lastPage
+		if list, total, err = storage.GetArchive(userID, timezone, page, pageSize); err != nil {
+			return nil, err
+		}
+	}
+	pagination := utils.GetPaginationData(page, pageSize, total, userID)
+	return map[string]interface{}{
+		"Archive":          list,
+		"TotalArchived":    total,
+		"CurrentPage":      pagination.CurrentPage,
+		"TotalPages":       pagination.TotalPages,
+		"Pages":            pagination.Pages,
+		"HasRightEllipsis": pagination.HasRightEllipsis,
+	}, nil
+}
+
+// renderArchiveList renders one page of the archive list fragment.
+func renderArchiveList(w http.ResponseWriter, r *http.Request, userID int, timezone string) {
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	ctx, err := archiveContext(r, userID, timezone, page)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+	utils.RenderTemplate(w, r, "archive_list.html", ctx)
+}
+
+// ArchivePageHandler shows the user's archived tasks, one page at a time.
+func ArchivePageHandler(w http.ResponseWriter, r *http.Request) {
+	email, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	ctx, err := archiveContext(r, *uidPtr, timezone, page)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+	archiveAfterDays := 0
+	if user, err := storage.GetUserByEmail(email); err == nil {
+		archiveAfterDays = user.ArchiveAfterDays
+	}
+	ctx["ArchiveAfterDays"] = archiveAfterDays
+	ctx["LoggedIn"] = loggedIn
+	utils.RenderTemplate(w, r, "archive.html", ctx)
+}
+
+// APIArchiveList renders a page of the archive list.
+func APIArchiveList(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	renderArchiveList(w, r, userID, timezone)
+}
+
+// APIArchiveTask archives a completed task and reloads the task list page it was on.
+func APIArchiveTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("HX-Reswap", "none")
+	if err := storage.ArchiveTask(id, userID); err != nil {
+		if errors.Is(err, storage.ErrArchiveNotAllowed) {
+			triggerToast(w, "Only completed tasks can be archived", true)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to archive task: %v", err), http.StatusInternalServerError)
+		return
+	}
+	triggerToast(w, "Task archived", false)
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": r.FormValue("project")})
+	w.WriteHeader(http.StatusOK)
+}
+
+// APIUnarchiveTask brings a task back from the archive. From the archive view the list
+// is re-rendered; from a search result the task row is.
+func APIUnarchiveTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.UnarchiveTask(id, userID); err != nil {
+		if errors.Is(err, storage.ErrArchivedTaskNotFound) {
+			http.Error(w, "Task not found in archive.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to unarchive task: %v", err), http.StatusInternalServerError)
+		return
+	}
+	triggerToast(w, "Task moved back to your list", false)
+	if r.FormValue("view") == "archive" {
+		renderArchiveList(w, r, userID, timezone)
+		return
+	}
+	renderTaskRow(w, r, id, userID, timezone)
+}
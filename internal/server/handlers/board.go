@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxBoardTasks caps the number of cards loaded on a board.
+const MaxBoardTasks = 500
+
+// boardColumn is one status of the board's workflow with its cards.
+type boardColumn struct {
+	Status storage.TaskStatus
+	Tasks  []tasks.Task
+}
+
+// boardContext builds the board of a project: the columns are the statuses of the
+// project's workflow. A board always shows a single workflow, so an empty or "none"
+// project shows the tasks without a project. Cards are loaded through the task list
+// query, so snoozed tasks stay hidden and favorites lead each column.
+func boardContext(userID int, timezone, projectParam string) (map[string]interface{}, error) {
+	projectID := 0
+	if filter := parseProjectFilter(projectParam); filter != nil {
+		projectID = *filter
+	}
+
+	workflow, own, err := storage.GetWorkflow(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := tasks.ReturnPaginationForUserFiltered(1, MaxBoardTasks, &userID, timezone, &projectID, tasks.TaskFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]boardColumn, len(workflow))
+	index := make(map[int]int, len(workflow))
+	for i, s := range workflow {
+		columns[i].Status = s
+		index[s.ID] = i
+	}
+	for _, t := range list {
+		if i, ok := index[t.Status.ID]; ok {
+			columns[i].Tasks = append(columns[i].Tasks, t)
+		}
+	}
+
+	projectsList := make([]map[string]interface{}, 0)
+	projs, err := storage.GetProjectsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projs {
+		projectsList = append(projectsList, map[string]interface{}{"ID": p.ID, "Name": p.Name, "Selected": p.ID == projectID})
+	}
+
+	return map[string]interface{}{
+		"Columns":       columns,
+		"Projects":      projectsList,
+		"ProjectID":     projectID,
+		"ProjectFilter": strconv.Itoa(projectID),
+		"OwnWorkflow":   own,
+		"Truncated":     total > MaxBoardTasks,
+		"MaxTasks":      MaxBoardTasks,
+	}, nil
+}
+
+// renderBoard renders the board columns fragment.
+func renderBoard(w http.ResponseWriter, r *http.Request, userID int, timezone, projectParam string) {
+	ctx, err := boardContext(userID, timezone, projectParam)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching board: %v", err), http.StatusInternalServerError)
+		return
+	}
+	utils.RenderTemplate(w, r, "board_columns.html", ctx)
+}
+
+// BoardPageHandler shows the tasks of a project as cards in columns, one per status.
+func BoardPageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	ctx, err := boardContext(*uidPtr, timezone, r.URL.Query().Get("project"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching board: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx["LoggedIn"] = loggedIn
+	utils.RenderTemplate(w, r, "board.html", ctx)
+}
+
+// APIBoard re-renders the board of the project in project.
+func APIBoard(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	renderBoard(w, r, userID, timezone, r.FormValue("project"))
+}
+
+// APIMoveBoardCard persists a card dropped on the board, like APIReorderTasks does for
+// the list: the task in id moves to the status in status, and ids gives the order of
+// that column after the drop. Moving a card into the done status completes the task
+// (scheduling the next occurrence of a recurring one) unless open tasks block it. The
+// board is re-rendered, so a refused move puts the card back, and the move can be undone
+// from the toast.
+func APIMoveBoardCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	statusID, err := strconv.Atoi(r.FormValue("status"))
+	if err != nil {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	order, err := formTaskIDs(r)
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	projectParam := r.FormValue("project")
+
+	task, err := tasks.ReturnTaskForUser(id, userID, timezone)
+	if err != nil || task.ParentID != 0 || task.Archived {
+		triggerToast(w, "This task can no longer be moved; the board has been refreshed", true)
+		renderBoard(w, r, userID, timezone, projectParam)
+		return
+	}
+	var status *storage.TaskStatus
+	for i := range task.Statuses {
+		if task.Statuses[i].ID == statusID {
+			status = &task.Statuses[i]
+		}
+	}
+	if status == nil {
+		http.Error(w, "Status not found.", http.StatusNotFound)
+		return
+	}
+	if status.IsDone && !task.Completed && task.IsBlocked() {
+		titles := make([]string, 0)
+		for _, b := range task.OpenBlockers() {
+			titles = append(titles, b.Title)
+		}
+		triggerToast(w, fmt.Sprintf("Blocked by %s. Complete those tasks first.", strings.Join(titles, ", ")), true)
+		renderBoard(w, r, userID, timezone, projectParam)
+		return
+	}
+
+	// Remember the column's order and the task's status so the move can be undone
+	undoState, undoErr := storage.SnapshotTasks(userID, order)
+
+	completedNow, err := storage.MoveTaskOnBoard(userID, id, *status, order)
+	if err != nil {
+		if errors.Is(err, storage.ErrBoardNotAllowed) {
+			triggerToast(w, "This task can no longer be moved; the board has been refreshed", true)
+			renderBoard(w, r, userID, timezone, projectParam)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to move task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Completing a recurring task schedules its next occurrence
+	nextOccurrenceID := 0
+	if completedNow {
+		nextOccurrenceID, err = tasks.CreateNextOccurrence(id, userID, timezone)
+		if err != nil {
+			fmt.Printf("Error creating next occurrence for task %d: %v\n", id, err)
+		}
+	}
+
+	msg := "Cards reordered"
+	if statusID != task.Status.ID {
+		msg = "Task moved to " + status.Name
+	}
+	if undoErr == nil {
+		offerUndo(w, userID, msg, storage.UndoRecord{Kind: "board", Tasks: undoState, Created: nextOccurrenceID}, 1, projectParam)
+	} else {
+		triggerToast(w, msg, false)
+	}
+	renderBoard(w, r, userID, timezone, projectParam)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bulkMessages are the toast messages for each bulk action, formatted with the task count.
+var bulkMessages = map[string]string{
+	storage.BulkComplete:   "%d %s completed",
+	storage.BulkUncomplete: "%d %s marked incomplete",
+	storage.BulkDelete:     "%d %s moved to the trash",
+	storage.BulkMove:       "%d %s moved",
+	storage.BulkDue:        "Due date updated on %d %s",
+	storage.BulkFavorite:   "%d %s added to favorites",
+	storage.BulkUnfavorite: "%d %s removed from favorites",
+}
+
+// formTaskIDs reads the selected task ids, each given as an ids value or a
+// comma-separated list, without duplicates.
+func formTaskIDs(r *http.Request) ([]int, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	ids := make([]int, 0)
+	for _, value := range r.Form["ids"] {
+		for _, s := range strings.Split(value, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// APIBulkTasks applies one action to every selected task in a single transaction:
+// complete, uncomplete, delete (to the trash), move to target_project, set due_date
+// (empty clears it), favorite or unfavorite. Like APIReorderTasks, every id must be one
+// of the user's listed tasks or nothing changes. The list page is reloaded, and every
+// action but setting the due date can be undone from the toast.
+func APIBulkTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	ids, err := formTaskIDs(r)
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	action := storage.BulkAction{Kind: r.FormValue("action")}
+	if !storage.IsValidBulkAction(action.Kind) {
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("HX-Reswap", "none")
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	projectParam := r.FormValue("project")
+	refuse := func(msg string) {
+		triggerToast(w, msg, true)
+		w.WriteHeader(http.StatusOK)
+	}
+	if len(ids) == 0 {
+		refuse("Select at least one task")
+		return
+	}
+	switch action.Kind {
+	case storage.BulkMove:
+		if target := r.FormValue("target_project"); target != "" && target != "0" {
+			pid, err := strconv.Atoi(target)
+			if err != nil {
+				http.Error(w, "Invalid project", http.StatusBadRequest)
+				return
+			}
+			action.ProjectID = &pid
+		}
+	case storage.BulkDue:
+		action.DueDate = strings.TrimSpace(r.FormValue("due_date"))
+		if action.DueDate != "" {
+			if _, err := time.Parse("2006-01-02", action.DueDate); err != nil {
+				refuse("Pick a valid due date")
+				return
+			}
+		}
+	}
+
+	// Remember the prior state of the tasks so the action can be undone
+	undoState, undoErr := storage.SnapshotTasks(userID, ids)
+
+	changed, err := storage.ApplyBulkAction(userID, ids, action)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrBulkNotAllowed):
+			triggerToast(w, "Some of the selected tasks can no longer be changed; the list has been refreshed", true)
+			addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": projectParam})
+			w.WriteHeader(http.StatusOK)
+		case errors.Is(err, storage.ErrBulkBlocked):
+			refuse("Some of the selected tasks are blocked by open tasks. Complete those first.")
+		case errors.Is(err, storage.ErrBulkProjectNotFound):
+			refuse("Project not found")
+		default:
+			http.Error(w, fmt.Sprintf("Failed to update tasks: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Completing recurring tasks schedules their next occurrences
+	created := make([]int, 0)
+	if action.Kind == storage.BulkComplete {
+		for _, id := range changed {
+			next, err := tasks.CreateNextOccurrence(id, userID, timezone)
+			if err != nil {
+				fmt.Printf("Error creating next occurrence for task %d: %v\n", id, err)
+				continue
+			}
+			if next > 0 {
+				created = append(created, next)
+			}
+		}
+	}
+
+	noun := "tasks"
+	if len(changed) == 1 {
+		noun = "task"
+	}
+	msg := fmt.Sprintf(bulkMessages[action.Kind], len(changed), noun)
+	if len(changed) == 0 || action.Kind == storage.BulkDue || undoErr != nil {
+		triggerToast(w, msg, false)
+	} else {
+		rec := storage.UndoRecord{Kind: "bulk", Tasks: undoState, CreatedIDs: created}
+		if action.Kind == storage.BulkDelete {
+			rec.TrashedIDs = changed
+		}
+		offerUndo(w, userID, msg, rec, page, projectParam)
+	}
+	addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": projectParam})
+	w.WriteHeader(http.StatusOK)
+}
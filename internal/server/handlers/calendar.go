@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MaxCalendarTasks caps the number of tasks loaded for one month or week.
+const MaxCalendarTasks = 1000
+
+// calendarDay is one cell of the calendar with the tasks due that day.
+type calendarDay struct {
+	Date    string // YYYY-MM-DD
+	Day     int
+	InMonth bool // false for the days of the neighbouring months in a month view
+	IsToday bool
+	Tasks   []tasks.Task
+}
+
+// calendarRange returns the days a calendar view covers, from a Monday to a Sunday,
+// and the dates of the previous and next views. A month view shows the weeks touching
+// the month of date; a week view shows the week of date.
+func calendarRange(view string, date time.Time) (start, end, prev, next time.Time) {
+	weekStart := func(d time.Time) time.Time {
+		return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+	}
+	if view == "week" {
+		start = weekStart(date)
+		return start, start.AddDate(0, 0, 6), date.AddDate(0, 0, -7), date.AddDate(0, 0, 7)
+	}
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	return weekStart(first), weekStart(last).AddDate(0, 0, 6), first.AddDate(0, -1, 0), first.AddDate(0, 1, 0)
+}
+
+// calendarContext builds a month or week of tasks by due date. Dates are the user's
+// local dates, so "today" and the default view follow their timezone. The project
+// filter works as in the task list; snoozed tasks stay hidden as they do there.
+func calendarContext(userID int, timezone, view, dateParam, projectParam string) (map[string]interface{}, error) {
+	if view != "week" {
+		view = "month"
+	}
+	today := tasks.LocalToday(timezone)
+	date, err := time.Parse("2006-01-02", dateParam)
+	if err != nil {
+		date, _ = time.Parse("2006-01-02", today)
+	}
+	start, end, prev, next := calendarRange(view, date)
+
+	projectFilter := parseProjectFilter(projectParam)
+	filter := tasks.TaskFilter{DueFrom: start.Format("2006-01-02"), DueTo: end.Format("2006-01-02")}
+	list, total, err := tasks.ReturnPaginationForUserFiltered(1, MaxCalendarTasks, &userID, timezone, projectFilter, filter)
+	if err != nil {
+		return nil, err
+	}
+	// Within a day, timed tasks come first in time order, then the rest in list order
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].DueTime, list[j].DueTime
+		return a != "" && (b == "" || a < b)
+	})
+	byDate := make(map[string][]tasks.Task)
+	for _, t := range list {
+		byDate[t.DueDate] = append(byDate[t.DueDate], t)
+	}
+
+	weeks := make([][]calendarDay, 0, 6)
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 7) {
+		week := make([]calendarDay, 7)
+		for i := range week {
+			day := d.AddDate(0, 0, i)
+			key := day.Format("2006-01-02")
+			week[i] = calendarDay{
+				Date:    key,
+				Day:     day.Day(),
+				InMonth: view == "week" || day.Month() == date.Month(),
+				IsToday: key == today,
+				Tasks:   byDate[key],
+			}
+		}
+		weeks = append(weeks, week)
+	}
+
+	title := date.Format("January 2006")
+	if view == "week" {
+		title = start.Format("Jan 2") + " – " + end.Format("Jan 2, 2006")
+	}
+
+	projectsList := make([]map[string]interface{}, 0)
+	projs, err := storage.GetProjectsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projs {
+		projectsList = append(projectsList, map[string]interface{}{"ID": p.ID, "Name": p.Name, "Selected": projectParam == strconv.Itoa(p.ID)})
+	}
+
+	return map[string]interface{}{
+		"View":          view,
+		"Date":          date.Format("2006-01-02"),
+		"Today":         today,
+		"Title":         title,
+		"Prev":          prev.Format("2006-01-02"),
+		"Next":          next.Format("2006-01-02"),
+		"Weekdays":      []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+		"Weeks":         weeks,
+		"Projects":      projectsList,
+		"ProjectFilter": projectParam,
+		"Truncated":     total > MaxCalendarTasks,
+		"MaxTasks":      MaxCalendarTasks,
+	}, nil
+}
+
+// renderCalendar renders the calendar fragment for the view, date and project of the request.
+func renderCalendar(w http.ResponseWriter, r *http.Request, userID int, timezone string) {
+	ctx, err := calendarContext(userID, timezone, r.FormValue("view"), r.FormValue("date"), r.FormValue("project"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+	utils.RenderTemplate(w, r, "calendar_grid.html", ctx)
+}
+
+// CalendarPageHandler shows the tasks with a due date on a month or week calendar.
+func CalendarPageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	q := r.URL.Query()
+	ctx, err := calendarContext(*uidPtr, timezone, q.Get("view"), q.Get("date"), q.Get("project"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx["LoggedIn"] = loggedIn
+	utils.RenderTemplate(w, r, "calendar.html", ctx)
+}
+
+// APICalendar re-renders the calendar for view (month or week), the day in date and
+// the project filter in project.
+func APICalendar(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	renderCalendar(w, r, userID, timezone)
+}
+
+// APIMoveCalendarTask moves the task in id to the day in due_date when it is dropped on
+// another day of the calendar. The due time is kept. The change goes through the bulk
+// due date action, so it is recorded in the task's history, and the calendar is
+// re-rendered, putting a refused card back.
+func APIMoveCalendarTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	due, err := time.Parse("2006-01-02", r.FormValue("due_date"))
+	if err != nil {
+		http.Error(w, "Invalid due date", http.StatusBadRequest)
+		return
+	}
+
+	action := storage.BulkAction{Kind: storage.BulkDue, DueDate: due.Format("2006-01-02")}
+	if _, err := storage.ApplyBulkAction(userID, []int{id}, action); err != nil {
+		if !errors.Is(err, storage.ErrBulkNotAllowed) {
+			http.Error(w, fmt.Sprintf("Failed to move task: %v", err), http.StatusInternalServerError)
+			return
+		}
+		triggerToast(w, "This task can no longer be moved; the calendar has been refreshed", true)
+	} else {
+		triggerToast(w, "Task due "+due.Format("Mon, Jan 2"), false)
+	}
+	renderCalendar(w, r, userID, timezone)
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxCommentLength caps a single task comment.
+const MaxCommentLength = 2000
+
+// commentView is a comment prepared for task_comments.html.
+type commentView struct {
+	storage.TaskComment
+	BodyHTML template.HTML
+	Own      bool // written by the viewer, so it can be edited
+}
+
+// renderCommentThread renders the comment thread of a task. editingID selects the comment
+// shown as an edit form, 0 for none.
+func renderCommentThread(w http.ResponseWriter, taskID, userID int, timezone string, editingID int) {
+	comments, err := storage.GetTaskComments(taskID, userID, timezone)
+	if err != nil {
+		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+	views := make([]commentView, 0, len(comments))
+	for _, c := range comments {
+		views = append(views, commentView{TaskComment: c, BodyHTML: tasks.RenderNotes(c.Body), Own: c.UserID == userID})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := map[string]interface{}{
+		"TaskID":    taskID,
+		"Comments":  views,
+		"EditingID": editingID,
+		"MaxLength": MaxCommentLength,
+	}
+	if err := utils.Templates.ExecuteTemplate(w, "task_comments.html", data); err != nil {
+		http.Error(w, "Error rendering comments: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// commentBodyFromForm validates the comment text. On failure it shows a toast, leaves the
+// thread as it is and returns false.
+func commentBodyFromForm(w http.ResponseWriter, r *http.Request) (string, bool) {
+	body := strings.TrimSpace(r.FormValue("body"))
+	msg := ""
+	if body == "" {
+		msg = "Comment cannot be empty"
+	} else if len(body) > MaxCommentLength {
+		msg = fmt.Sprintf("Comments must be %d characters or less", MaxCommentLength)
+	}
+	if msg != "" {
+		triggerToast(w, msg, true)
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusOK)
+		return "", false
+	}
+	return body, true
+}
+
+// APITaskComments renders the comment thread of a task.
+func APITaskComments(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	renderCommentThread(w, taskID, userID, timezone, 0)
+}
+
+// APIAddComment adds a comment to a task and re-renders its thread.
+func APIAddComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.FormValue("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	body, ok := commentBodyFromForm(w, r)
+	if !ok {
+		return
+	}
+
+	if err := storage.AddTaskComment(taskID, userID, body); err != nil {
+		if errors.Is(err, storage.ErrCommentNotFound) {
+			http.Error(w, "Task not found.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to add comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	renderCommentThread(w, taskID, userID, timezone, 0)
+}
+
+// APIEditCommentForm re-renders the thread with one of the user's comments as an edit form.
+func APIEditCommentForm(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := storage.GetTaskComment(commentID, userID)
+	if err != nil {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return
+	}
+	renderCommentThread(w, comment.TaskID, userID, timezone, comment.ID)
+}
+
+// APIUpdateComment saves an edited comment and re-renders the thread.
+func APIUpdateComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid comment id", http.StatusBadRequest)
+		return
+	}
+	body, ok := commentBodyFromForm(w, r)
+	if !ok {
+		return
+	}
+
+	// Only the author may edit, enforced in the storage layer
+	taskID, err := storage.UpdateTaskComment(commentID, userID, body)
+	if err != nil {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return
+	}
+	renderCommentThread(w, taskID, userID, timezone, 0)
+}
+
+// APIDeleteComment removes a comment and re-renders the thread.
+func APIDeleteComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := storage.DeleteTaskComment(commentID, userID)
+	if err != nil {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return
+	}
+	renderCommentThread(w, taskID, userID, timezone, 0)
+}
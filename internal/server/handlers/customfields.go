@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxFieldNameLength = 50
+	MaxFieldOptions    = 30
+)
+
+// renderProjectFields renders the custom field list and create form of a project for
+// the projects page.
+func renderProjectFields(w http.ResponseWriter, r *http.Request, projectID, userID int, errMsg string) {
+	fields, err := storage.GetProjectFields(projectID, userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching fields: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx := map[string]interface{}{
+		"ProjectID":  projectID,
+		"Fields":     fields,
+		"FieldTypes": storage.FieldTypes,
+		"Error":      errMsg,
+	}
+	utils.RenderTemplate(w, r, "project_fields.html", ctx)
+}
+
+// APIProjectFields renders the custom fields of one of the user's projects.
+func APIProjectFields(w http.ResponseWriter, r *http.Request) {
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	projectID, err := strconv.Atoi(r.URL.Query().Get("project_id"))
+	if err != nil {
+		http.Error(w, "Invalid project id", http.StatusBadRequest)
+		return
+	}
+	renderProjectFields(w, r, projectID, *uidPtr, "")
+}
+
+// APICreateProjectField adds a custom field to a project. Select fields take their
+// options as a comma-separated list.
+func APICreateProjectField(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		http.Redirect(w, r, "/", http.StatusUnauthorized)
+		return
+	}
+	projectID, err := strconv.Atoi(r.FormValue("project_id"))
+	if err != nil {
+		http.Error(w, "Invalid project id", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	fieldType := r.FormValue("type")
+	var options []string
+	seen := map[string]bool{}
+	for _, o := range strings.Split(r.FormValue("options"), ",") {
+		if o = strings.TrimSpace(o); o != "" && !seen[o] {
+			seen[o] = true
+			options = append(options, o)
+		}
+	}
+
+	msg := ""
+	switch {
+	case name == "":
+		msg = "Field name is required"
+	case len(name) > MaxFieldNameLength:
+		msg = fmt.Sprintf("Field name must be %d characters or less", MaxFieldNameLength)
+	case !storage.IsValidFieldType(fieldType):
+		msg = "Choose a field type"
+	case fieldType == storage.FieldSelect && len(options) == 0:
+		msg = "A select field needs at least one option"
+	case len(options) > MaxFieldOptions:
+		msg = fmt.Sprintf("A select field can have at most %d options", MaxFieldOptions)
+	}
+	if fieldType != storage.FieldSelect {
+		options = nil
+	}
+	if msg == "" {
+		if _, err := storage.CreateProjectField(projectID, *uidPtr, name, fieldType, options); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrProjectFieldExists):
+				msg = "A field with that name already exists"
+			case errors.Is(err, storage.ErrProjectFieldNotFound):
+				http.Error(w, "Project not found.", http.StatusNotFound)
+				return
+			default:
+				http.Error(w, fmt.Sprintf("Failed to create field: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	renderProjectFields(w, r, projectID, *uidPtr, msg)
+}
+
+// APIDeleteProjectField removes a custom field and its values from a project.
+func APIDeleteProjectField(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		http.Redirect(w, r, "/", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid field id", http.StatusBadRequest)
+		return
+	}
+	projectID, err := strconv.Atoi(r.FormValue("project_id"))
+	if err != nil {
+		http.Error(w, "Invalid project id", http.StatusBadRequest)
+		return
+	}
+	if err := storage.DeleteProjectField(id, *uidPtr); err != nil && !errors.Is(err, storage.ErrProjectFieldNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to delete field: %v", err), http.StatusInternalServerError)
+		return
+	}
+	renderProjectFields(w, r, projectID, *uidPtr, "")
+}
+
+// fieldInput is a custom field with its current value for the task form.
+type fieldInput struct {
+	storage.ProjectField
+	Value string
+}
+
+// APITaskFieldInputs renders the custom field inputs of the project chosen in the
+// add/edit task form. When task_id is given, the task's values are filled in.
+func APITaskFieldInputs(w http.ResponseWriter, r *http.Request) {
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	inputs := make([]fieldInput, 0)
+	if projectID, err := strconv.Atoi(r.URL.Query().Get("project_id")); err == nil {
+		fields, err := storage.GetProjectFields(projectID, *uidPtr)
+		if err != nil {
+			http.Error(w, "Failed to fetch fields", http.StatusInternalServerError)
+			return
+		}
+		values := map[int]string{}
+		if taskID, err := strconv.Atoi(r.URL.Query().Get("task_id")); err == nil {
+			if values, err = storage.GetTaskFieldValues(taskID, *uidPtr); err != nil {
+				http.Error(w, "Failed to fetch task fields", http.StatusInternalServerError)
+				return
+			}
+		}
+		for _, f := range fields {
+			inputs = append(inputs, fieldInput{ProjectField: f, Value: values[f.ID]})
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "field_inputs.html", map[string]interface{}{"Inputs": inputs}); err != nil {
+		http.Error(w, "Error rendering fields: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// formFieldValues reads the custom field values from the task form for the project it
+// assigns, normalized for storage. The bool reports whether the field inputs were part
+// of the form at all (they load lazily).
+func formFieldValues(r *http.Request, userID int) (map[int]string, bool, error) {
+	if r.FormValue("fields_submitted") == "" {
+		return nil, false, nil
+	}
+	projectID, err := strconv.Atoi(strings.TrimSpace(r.FormValue("project_id")))
+	if err != nil {
+		return nil, false, nil
+	}
+	fields, err := storage.GetProjectFields(projectID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	values := make(map[int]string, len(fields))
+	for _, f := range fields {
+		v, err := tasks.NormalizeFieldValue(f, r.FormValue("field_"+strconv.Itoa(f.ID)))
+		if err != nil {
+			return nil, true, err
+		}
+		values[f.ID] = v
+	}
+	return values, true, nil
+}
+
+// applyFieldFilter reads the custom field filter ("field_filter" id and "field_value")
+// and sort ("field_sort" id, negative for descending) of a project view into f. Fields
+// must belong to the filtered project; anything else is ignored.
+func applyFieldFilter(r *http.Request, f *tasks.TaskFilter, userID *int, projectFilter *int) {
+	if userID == nil || projectFilter == nil || *projectFilter <= 0 {
+		return
+	}
+	filterID, _ := strconv.Atoi(r.FormValue("field_filter"))
+	sortID, _ := strconv.Atoi(r.FormValue("field_sort"))
+	if filterID == 0 && sortID == 0 {
+		return
+	}
+	fields, err := storage.GetProjectFields(*projectFilter, *userID)
+	if err != nil {
+		return
+	}
+	for i := range fields {
+		field := &fields[i]
+		if field.ID == filterID {
+			if raw := r.FormValue("field_value"); raw != "" {
+				if v, err := tasks.NormalizeFieldValue(*field, raw); err == nil {
+					f.FilterField, f.FilterValue = field, v
+				}
+			}
+		}
+		if field.ID == sortID || field.ID == -sortID {
+			f.SortField, f.SortDesc = field, sortID < 0
+		}
+	}
+}
+
+// addFieldFilterContext exposes the active custom field filter and sort to the pagination links.
+func addFieldFilterContext(ctx map[string]interface{}, r *http.Request, f tasks.TaskFilter) {
+	ctx["FieldFilter"], ctx["FieldValue"], ctx["FieldSort"] = "", "", ""
+	if f.FilterField != nil {
+		ctx["FieldFilter"] = strconv.Itoa(f.FilterField.ID)
+		ctx["FieldValue"] = r.FormValue("field_value")
+	}
+	if f.SortField != nil {
+		ctx["FieldSort"] = r.FormValue("field_sort")
+	}
+}
+
+// fieldFilterBar builds the template data for the custom field filter and sort controls
+// of a project view.
+func fieldFilterBar(r *http.Request, userID *int, projectFilter *int) map[string]interface{} {
+	bar := map[string]interface{}{"Fields": []storage.ProjectField{}}
+	if userID == nil || projectFilter == nil || *projectFilter <= 0 {
+		return bar
+	}
+	fields, err := storage.GetProjectFields(*projectFilter, *userID)
+	if err != nil {
+		return bar
+	}
+	filterID, _ := strconv.Atoi(r.FormValue("field_filter"))
+	var selected *storage.ProjectField
+	for i := range fields {
+		if fields[i].ID == filterID {
+			selected = &fields[i]
+		}
+	}
+	bar["Fields"] = fields
+	bar["Selected"] = selected
+	bar["Value"] = r.FormValue("field_value")
+	bar["Sort"] = r.FormValue("field_sort")
+	return bar
+}
+
+// APIFieldFilterBar renders the custom field filter and sort controls for the project
+// chosen in the toolbar. Choosing another field resets its value, so the task list is
+// reloaded once the new controls are in place.
+func APIFieldFilterBar(w http.ResponseWriter, r *http.Request) {
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	projectParam := r.FormValue("project")
+	bar := fieldFilterBar(r, uidPtr, parseProjectFilter(projectParam))
+	if r.FormValue("reload") == "1" {
+		payload, _ := json.Marshal(map[string]interface{}{"reloadPage": map[string]interface{}{"page": 1, "project": projectParam}})
+		w.Header().Set("HX-Trigger-After-Swap", string(payload))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "field_filter.html", bar); err != nil {
+		http.Error(w, "Error rendering field filter: "+err.Error(), http.StatusInternalServerError)
+	}
+}
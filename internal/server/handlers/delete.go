@@ -64,21 +64,26 @@ func APIDeleteTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Delete the task from the database (only if it belongs to the user)
-	result, err := db.Exec(context.Background(), "DELETE FROM tasks WHERE id = $1 AND user_id = $2", taskID, userID)
+	taskIDNum, err := strconv.Atoi(taskID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Error deleting task")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid task ID")
 		return
 	}
 
-	// Check if any rows were actually deleted
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "Task not found or you don't have permission to delete it")
+	// Move the task to the trash (only if it belongs to the user). Subtasks go with it
+	// and come back when it is restored; the trash purges them for good later.
+	if err := storage.TrashTask(taskIDNum, userID); err != nil {
+		if errors.Is(err, storage.ErrTrashedTaskNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Task not found or you don't have permission to delete it")
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error deleting task")
 		return
 	}
+	offerUndo(w, userID, "Task moved to trash", storage.UndoRecord{Kind: "delete", Trashed: taskIDNum}, currentPage, r.URL.Query().Get("project"))
 
 	// Determine active project filter
 	projectParam := r.URL.Query().Get("project")
@@ -95,20 +100,7 @@ func APIDeleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total number of tasks for this user after deletion (scoped to project if filter active)
-	var totalTasks int
-	if projectFilter == nil {
-		err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1", userID).Scan(&totalTasks)
-	} else {
-		projectCond := ""
-		args := []interface{}{userID}
-		if *projectFilter == 0 {
-			projectCond = " AND project_id IS NULL"
-		} else {
-			projectCond = " AND project_id = $2"
-			args = append(args, *projectFilter)
-		}
-		err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1"+projectCond, args...).Scan(&totalTasks)
-	}
+	totalTasks, err := storage.CountTopLevelTasks(userID, projectFilter)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprintf(w, "Error counting tasks")
@@ -156,9 +148,9 @@ func APIDeleteTask(w http.ResponseWriter, r *http.Request) {
 	// Fetch tasks for the reload page (respect project filter)
 	var taskList []tasks.Task
 	if projectFilter != nil {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(reloadPage, pageSize, &userID, timezone, projectFilter)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(reloadPage, pageSize, &userID, timezone, projectFilter, sessionTaskOrder(r))
 	} else {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUser(reloadPage, pageSize, &userID, timezone)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(reloadPage, pageSize, &userID, timezone, nil, sessionTaskOrder(r))
 	}
 	if err != nil {
 		http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
@@ -184,24 +176,7 @@ func APIDeleteTask(w http.ResponseWriter, r *http.Request) {
 	completedCount := utils.GetCompletedTasksCount(&userID)
 	incompleteCount := utils.GetIncompleteTasksCount(&userID)
 	if projectFilter != nil {
-		pool, err := storage.OpenDatabase()
-		if err == nil {
-			defer storage.CloseDatabase(pool)
-			projectCond := ""
-			args := []interface{}{userID}
-			if *projectFilter == 0 {
-				projectCond = " AND project_id IS NULL"
-			} else {
-				projectCond = " AND project_id = $2"
-				args = append(args, *projectFilter)
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&completedCount); err != nil {
-				completedCount = 0
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&incompleteCount); err != nil {
-				incompleteCount = 0
-			}
-		}
+		completedCount, incompleteCount, _ = storage.CountTasksByDone(userID, projectFilter)
 	}
 
 	// Fetch projects and mark selected
@@ -280,8 +255,7 @@ func APIGetNextItem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total number of tasks for this user
-	var totalTasks int
-	err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1", userID).Scan(&totalTasks)
+	totalTasks, err := storage.CountTopLevelTasks(userID, nil)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -303,7 +277,7 @@ func APIGetNextItem(w http.ResponseWriter, r *http.Request) {
 	// Check how many items are on the current page for this user
 	var itemsOnPage int
 	err = db.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM (SELECT id FROM tasks WHERE user_id = $1 ORDER BY id LIMIT $2 OFFSET $3) AS page_tasks",
+		"SELECT COUNT(*) FROM (SELECT id FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL ORDER BY id LIMIT $2 OFFSET $3) AS page_tasks",
 		userID, pageSize, offset).Scan(&itemsOnPage)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -339,7 +313,7 @@ func APIGetNextItem(w http.ResponseWriter, r *http.Request) {
 
 	row := db.QueryRow(context.Background(),
 		`SELECT id, title, description, completed, TO_CHAR(time_stamp, 'YYYY/MM/DD HH:MI AM') AS date_added
-		 FROM tasks WHERE user_id = $1 ORDER BY id LIMIT 1 OFFSET $2`, userID, nextItemOffset)
+		 FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL ORDER BY id LIMIT 1 OFFSET $2`, userID, nextItemOffset)
 
 	var task tasks.Task
 	err = row.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.DateAdded)
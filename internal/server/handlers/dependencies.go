@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// APIAddDependency marks a task as blocked by another of the user's tasks and re-renders the row.
+func APIAddDependency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.FormValue("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	blockerID, err := strconv.Atoi(r.FormValue("blocker_id"))
+	if err != nil {
+		triggerToast(w, "Choose a task that blocks this one", true)
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := storage.AddTaskDependency(taskID, blockerID, userID); err != nil {
+		msg := ""
+		switch {
+		case errors.Is(err, storage.ErrDependencyCycle):
+			msg = "That task already waits on this one, so it cannot block it"
+		case errors.Is(err, storage.ErrDependencyNotAllowed):
+			msg = "A task can only be blocked by another of your own tasks"
+		}
+		if msg != "" {
+			triggerToast(w, msg, true)
+			w.Header().Set("HX-Reswap", "none")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to add dependency: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	renderTaskRowWith(w, r, taskID, userID, timezone, taskRow{DependenciesOpen: true})
+}
+
+// APIRemoveDependency removes a blocked-by link and re-renders the row.
+func APIRemoveDependency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.FormValue("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	blockerID, err := strconv.Atoi(r.FormValue("blocker_id"))
+	if err != nil {
+		http.Error(w, "Invalid blocker id", http.StatusBadRequest)
+		return
+	}
+
+	// Ownership enforced in storage layer
+	if err := storage.RemoveTaskDependency(taskID, blockerID, userID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove dependency: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	renderTaskRowWith(w, r, taskID, userID, timezone, taskRow{DependenciesOpen: true})
+}
+
+// APIDependencyOptions renders the picker of tasks that could block the given task.
+func APIDependencyOptions(w http.ResponseWriter, r *http.Request) {
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	candidates, err := storage.GetDependencyCandidates(taskID, *uidPtr)
+	if err != nil {
+		http.Error(w, "Failed to fetch tasks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "dependency_options.html", map[string]interface{}{"Candidates": candidates}); err != nil {
+		http.Error(w, "Error rendering dependency options: "+err.Error(), http.StatusInternalServerError)
+	}
+}
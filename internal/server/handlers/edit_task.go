@@ -40,12 +40,17 @@ func APIEditTaskForm(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
-	var title, description string
+	var title, description, notes string
 	var completed bool
 	var ownerID int
 	var projectID sql.NullInt64
 	var dueDate sql.NullString
-	err = db.QueryRow(context.Background(), "SELECT title, description, completed, user_id, project_id, COALESCE(CAST(due_date AS TEXT), '') FROM tasks WHERE id = $1", id).Scan(&title, &description, &completed, &ownerID, &projectID, &dueDate)
+	var repeatRule string
+	var priority int
+	var dueTime string
+	var estimate float64
+	var estimateUnit string
+	err = db.QueryRow(context.Background(), "SELECT title, description, completed, user_id, project_id, COALESCE(CAST(due_date AS TEXT), ''), COALESCE(repeat_rule, ''), COALESCE(priority, 0), COALESCE(TO_CHAR(due_time, 'HH24:MI'), ''), COALESCE(notes, ''), CAST(COALESCE(estimate, 0) AS DOUBLE PRECISION), COALESCE(estimate_unit, '') FROM tasks WHERE id = $1", id).Scan(&title, &description, &completed, &ownerID, &projectID, &dueDate, &repeatRule, &priority, &dueTime, &notes, &estimate, &estimateUnit)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "Task not found.", http.StatusNotFound)
@@ -96,6 +101,7 @@ func APIEditTaskForm(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		FormTitle     string
 		Description   string
+		Notes         string
 		CurrentPage   string
 		ID            string
 		FormAction    string
@@ -103,11 +109,17 @@ func APIEditTaskForm(w http.ResponseWriter, r *http.Request) {
 		SidebarTitle  string
 		Error         string
 		DueDate       string
+		DueTime       string
+		Repeat        *repeatForm
+		Priority      int
+		Estimate      string
+		EstimateUnit  string
 		Projects      []map[string]interface{}
 		ProjectFilter string
 	}{
 		FormTitle:     strings.TrimSpace(title),
 		Description:   strings.TrimSpace(description),
+		Notes:         notes,
 		CurrentPage:   page,
 		ID:            id,
 		FormAction:    utils.GetBasePath() + "/api/edit-task",
@@ -115,6 +127,11 @@ func APIEditTaskForm(w http.ResponseWriter, r *http.Request) {
 		SidebarTitle:  "Edit Task",
 		Error:         "",
 		DueDate:       dueDate.String,
+		DueTime:       dueTime,
+		Repeat:        newRepeatForm(repeatRule),
+		Priority:      priority,
+		Estimate:      estimateInput(estimate),
+		EstimateUnit:  estimateUnit,
 		Projects:      projectsList,
 		ProjectFilter: projectFilterParam,
 	}
@@ -137,6 +154,7 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimSpace(r.FormValue("id"))
 	title := strings.TrimSpace(r.FormValue("title"))
 	description := strings.TrimSpace(r.FormValue("description"))
+	notes := strings.TrimSpace(r.FormValue("notes"))
 	dueDate := strings.TrimSpace(r.FormValue("due_date"))
 	pageStr := strings.TrimSpace(r.FormValue("currentPage"))
 
@@ -165,6 +183,71 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(notes) > MaxNotesLength {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Notes must be %d characters or less", MaxNotesLength)
+		return
+	}
+
+	// Choosing "Does not repeat" stops the recurrence
+	repeatRule, err := repeatRuleFromForm(r)
+	if err != nil {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Invalid repeat rule: %v", err)
+		return
+	}
+
+	priority, err := tasks.ParsePriority(r.FormValue("priority"))
+	if err != nil {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Invalid priority")
+		return
+	}
+
+	estimate, estimateUnit, estimateSubmitted, err := formEstimate(r)
+	if err != nil {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Invalid estimate: %v", err)
+		return
+	}
+
+	dueTime, err := dueTimeFromForm(r, dueDate)
+	if err != nil {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Invalid due time: %v", err)
+		return
+	}
+	reminderOffsets, remindersSubmitted := formReminderOffsets(r)
+	if len(reminderOffsets) > 0 && dueDate == "" {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Reminders need a due date")
+		return
+	}
+
 	db, err := storage.OpenDatabase()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -219,16 +302,31 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not authorized to edit this task.", http.StatusForbidden)
 		return
 	}
+	fieldValues, fieldsSubmitted, err := formFieldValues(r, userID)
+	if err != nil {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "description-error")
+		w.Header().Set("HX-Retarget", "#description-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	// Remember the tracked fields so the change can be recorded in the task's history
+	revisionTaskID, _ := strconv.Atoi(id)
+	revisionBefore, revisionErr := storage.GetRevisionState(revisionTaskID, userID)
 
-	// Handle optional project association
+	// Handle optional project association; a task moved to another project takes the
+	// matching status of that project's workflow
 	projectIDStr := strings.TrimSpace(r.FormValue("project_id"))
 	if projectIDStr == "" {
 		// Clear project association
 		var err2 error
 		if dueDate == "" {
-			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = NULL, due_date = NULL, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $3", title, description, id)
+			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = NULL, status_id = "+storage.StatusAfterSQL("COALESCE(tasks.completed, false)", "NULL")+", due_date = NULL, due_time = NULL, repeat_rule = $4, priority = $5, notes = $6, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $3", title, description, id, nullableRule(repeatRule), priority, notes)
 		} else {
-			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = NULL, due_date = $3, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $4", title, description, dueDate, id)
+			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = NULL, status_id = "+storage.StatusAfterSQL("COALESCE(tasks.completed, false)", "NULL")+", due_date = $3, repeat_rule = $5, priority = $6, due_time = CAST($7 AS TIME), notes = $8, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $4", title, description, dueDate, id, nullableRule(repeatRule), priority, nullableTime(dueTime), notes)
 		}
 		err = err2
 		if err != nil {
@@ -248,9 +346,9 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 		}
 		var err2 error
 		if dueDate == "" {
-			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = $3, due_date = NULL, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $4", title, description, pid, id)
+			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = $3, status_id = "+storage.StatusAfterSQL("COALESCE(tasks.completed, false)", "CAST($3 AS INTEGER)")+", due_date = NULL, due_time = NULL, repeat_rule = $5, priority = $6, notes = $7, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $4", title, description, pid, id, nullableRule(repeatRule), priority, notes)
 		} else {
-			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = $3, due_date = $4, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $5", title, description, pid, dueDate, id)
+			_, err2 = db.Exec(context.Background(), "UPDATE tasks SET title = $1, description = $2, project_id = $3, status_id = "+storage.StatusAfterSQL("COALESCE(tasks.completed, false)", "CAST($3 AS INTEGER)")+", due_date = $4, repeat_rule = $6, priority = $7, due_time = CAST($8 AS TIME), notes = $9, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $5", title, description, pid, dueDate, id, nullableRule(repeatRule), priority, nullableTime(dueTime), notes)
 		}
 		err = err2
 		if err != nil {
@@ -259,6 +357,41 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if revisionErr == nil {
+		if err := storage.RecordTaskRevision(revisionTaskID, userID, revisionBefore); err != nil {
+			fmt.Printf("Error recording revision for task %d: %v\n", revisionTaskID, err)
+		}
+	}
+
+	if tagIDs, ok := formTagIDs(r); ok {
+		taskID, _ := strconv.Atoi(id)
+		if err := storage.SetTaskTags(taskID, userID, tagIDs); err != nil {
+			http.Error(w, "Failed to update task tags.", http.StatusInternalServerError)
+			return
+		}
+	}
+	if remindersSubmitted {
+		taskID, _ := strconv.Atoi(id)
+		if err := storage.SetTaskReminders(taskID, userID, reminderOffsets); err != nil {
+			http.Error(w, "Failed to update task reminders.", http.StatusInternalServerError)
+			return
+		}
+	}
+	if fieldsSubmitted {
+		taskID, _ := strconv.Atoi(id)
+		if err := storage.SetTaskFieldValues(taskID, userID, fieldValues); err != nil {
+			http.Error(w, "Failed to update task fields.", http.StatusInternalServerError)
+			return
+		}
+	}
+	if estimateSubmitted {
+		taskID, _ := strconv.Atoi(id)
+		if err := storage.SetTaskEstimate(taskID, userID, estimate, estimateUnit); err != nil {
+			http.Error(w, "Failed to update task estimate.", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Re-render pagination like add_task does
 	// Determine page size
 	pageSize := utils.AppConstants.PageSize
@@ -303,9 +436,9 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 	var taskList []tasks.Task
 	var totalTasks int
 	if projectFilter != nil {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, &userID, timezone, projectFilter)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, &userID, timezone, projectFilter, sessionTaskOrder(r))
 	} else {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, &userID, timezone)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, &userID, timezone, nil, sessionTaskOrder(r))
 	}
 	if err != nil {
 		http.Error(w, "Error fetching tasks after edit: "+err.Error(), http.StatusInternalServerError)
@@ -341,30 +474,7 @@ func APIEditTask(w http.ResponseWriter, r *http.Request) {
 	completedCount := utils.GetCompletedTasksCount(&userID)
 	incompleteCount := utils.GetIncompleteTasksCount(&userID)
 	if projectFilter != nil {
-		pool, err := storage.OpenDatabase()
-		if err == nil {
-			defer storage.CloseDatabase(pool)
-			projectCond := ""
-			args := []interface{}{userID}
-			if *projectFilter == 0 {
-				projectCond = " AND project_id IS NULL"
-			} else {
-				projectCond = " AND project_id = $2"
-				args = append(args, *projectFilter)
-			}
-			var ccount int
-			var icount int
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&ccount); err == nil {
-				completedCount = ccount
-			} else {
-				completedCount = 0
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&icount); err == nil {
-				incompleteCount = icount
-			} else {
-				incompleteCount = 0
-			}
-		}
+		completedCount, incompleteCount, _ = storage.CountTasksByDone(userID, projectFilter)
 	}
 
 	// Fetch projects and mark selected
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"GoTodo/internal/tasks"
+	"net/http"
+	"strconv"
+)
+
+// formEstimate reads the effort estimate from the add/edit task form. The bool reports
+// whether the form carried the estimate fields at all, so older forms leave it untouched.
+func formEstimate(r *http.Request) (float64, string, bool, error) {
+	if _, ok := r.Form["estimate"]; !ok {
+		return 0, "", false, nil
+	}
+	estimate, unit, err := tasks.ParseEstimate(r.FormValue("estimate"), r.FormValue("estimate_unit"))
+	return estimate, unit, true, err
+}
+
+// estimateInput formats a stored estimate for the form's number input, "" when unset.
+func estimateInput(estimate float64) string {
+	if estimate <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(estimate, 'f', -1, 64)
+}
@@ -6,9 +6,12 @@ import (
 	"GoTodo/internal/storage"
 	"GoTodo/internal/tasks"
 	"context"
+	"fmt"
+	"html/template"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 func getUserIDFromEmail(email string) *int {
@@ -31,6 +34,116 @@ func getUserIDFromEmail(email string) *int {
 	return &userID
 }
 
+// sessionPageSize returns the items-per-page preference stored in the session,
+// falling back to the application default.
+func sessionPageSize(r *http.Request) int {
+	pageSize := utils.AppConstants.PageSize
+	if sess, err := sessionstore.Store.Get(r, "session"); err == nil && sess != nil {
+		if val, ok := sess.Values["items_per_page"]; ok {
+			switch tv := val.(type) {
+			case int:
+				if tv > 0 {
+					pageSize = tv
+				}
+			case int64:
+				if int(tv) > 0 {
+					pageSize = int(tv)
+				}
+			case float64:
+				if int(tv) > 0 {
+					pageSize = int(tv)
+				}
+			case string:
+				if v, err := strconv.Atoi(tv); err == nil && v > 0 {
+					pageSize = v
+				}
+			}
+		}
+	}
+	return pageSize
+}
+
+// sessionSortByPriority reports whether the user chose to order task lists by priority
+// (then position) instead of by position only.
+func sessionSortByPriority(r *http.Request) bool {
+	if sess, err := sessionstore.Store.Get(r, "session"); err == nil && sess != nil {
+		if val, ok := sess.Values["sort_order"].(string); ok {
+			return val == "priority"
+		}
+	}
+	return false
+}
+
+// sessionTaskOrder returns an otherwise empty TaskFilter carrying the session sort order,
+// for handlers that re-render the list without a tag filter.
+func sessionTaskOrder(r *http.Request) tasks.TaskFilter {
+	return tasks.TaskFilter{SortByPriority: sessionSortByPriority(r)}
+}
+
+// applySortParam stores the "sort" request value (priority or position) in the session when
+// present, and returns whether lists should be ordered by priority.
+func applySortParam(w http.ResponseWriter, r *http.Request) bool {
+	sort := r.FormValue("sort")
+	if sort != "priority" && sort != "position" {
+		return sessionSortByPriority(r)
+	}
+	if sess, err := sessionstore.Store.Get(r, "session"); err == nil && sess != nil {
+		sess.Values["sort_order"] = sort
+		_ = sess.Save(r, w)
+	}
+	return sort == "priority"
+}
+
+// parseProjectFilter converts a project query value into a filter:
+// empty = all projects (nil), "0" or "none" = no project, numeric id = specific project.
+func parseProjectFilter(projectParam string) *int {
+	if projectParam == "" {
+		return nil
+	}
+	if projectParam == "none" || projectParam == "0" {
+		zero := 0
+		return &zero
+	}
+	if pid, err := strconv.Atoi(projectParam); err == nil {
+		return &pid
+	}
+	return nil
+}
+
+// triggerToast asks the client to show a toast message via the show-toast HX-Trigger
+// event, alongside any other events already set on the response.
+func triggerToast(w http.ResponseWriter, message string, isError bool) {
+	addTrigger(w, "show-toast", map[string]interface{}{"message": message, "error": isError})
+}
+
+// requireActiveUser resolves the logged-in user for an API action, applying the same
+// ban check as the task handlers. When ok is false a response has already been written.
+func requireActiveUser(w http.ResponseWriter, r *http.Request) (userID int, timezone string, ok bool) {
+	email, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	if !loggedIn {
+		w.WriteHeader(http.StatusUnauthorized)
+		return 0, timezone, false
+	}
+
+	// Prevent banned users from performing actions
+	if isBanned, err := storage.IsUserBanned(email); err == nil && isBanned {
+		sessionstore.ClearSessionCookie(w, r)
+		w.Header().Set("HX-Redirect", utils.GetBasePath())
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, " ")
+		return 0, timezone, false
+	}
+
+	if uid := utils.GetSessionUserID(r); uid != nil {
+		return *uid, timezone, true
+	}
+	if uid := getUserIDFromEmail(email); uid != nil {
+		return *uid, timezone, true
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	return 0, timezone, false
+}
+
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	page := 1
 	// determine page size from session if present
@@ -93,14 +206,20 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if searchQuery != "" {
-		taskList, totalTasks, err = tasks.SearchTasksForUser(page, pageSize, searchQuery, userID, timezone)
+	// Optional tag filter (any/all of the selected tags) and custom field filter of the project
+	taskFilter := parseTaskFilter(r)
+	taskFilter.SortByPriority = applySortParam(w, r)
+	applyFieldFilter(r, &taskFilter, userID, projectFilter)
+	// A saved view is evaluated as a search for its text and filters
+	savedText, saved := applySavedView(r, userID, &taskFilter)
+	if saved && searchQuery == "" {
+		searchQuery = savedText
+	}
+
+	if searchQuery != "" || saved {
+		taskList, totalTasks, err = tasks.SearchTasksForUserFiltered(page, pageSize, searchQuery, userID, timezone, taskFilter)
 	} else {
-		if projectFilter != nil {
-			taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, userID, timezone, projectFilter)
-		} else {
-			taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, userID, timezone)
-		}
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userID, timezone, projectFilter, taskFilter)
 	}
 
 	if err != nil {
@@ -113,9 +232,8 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 
 	if searchQuery != "" {
 		isSearching = true
-		for i, task := range taskList {
-			taskList[i].Title = highlightMatches(task.Title, searchQuery)
-			taskList[i].Description = highlightMatches(task.Description, searchQuery)
+		for i := range taskList {
+			highlightTask(&taskList[i], searchQuery)
 		}
 	}
 
@@ -188,35 +306,27 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		// If projectFilter is set, compute completed/incomplete counts scoped to project
 		if projectFilter != nil {
-			pool, err := storage.OpenDatabase()
-			if err == nil {
-				defer storage.CloseDatabase(pool)
-				projectCond := ""
-				args := []interface{}{*userID}
-				if *projectFilter == 0 {
-					projectCond = " AND project_id IS NULL"
-				} else {
-					projectCond = " AND project_id = $2"
-					args = append(args, *projectFilter)
-				}
-				var ccount int
-				var icount int
-				if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&ccount); err == nil {
-					completedCount = ccount
-				} else {
-					completedCount = 0
-				}
-				if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&icount); err == nil {
-					incompleteCount = icount
-				} else {
-					incompleteCount = 0
-				}
-				// update context values
-				tplContext["CompletedTasks"] = completedCount
-				tplContext["IncompleteTasks"] = incompleteCount
+			completedCount, incompleteCount, _ = storage.CountTasksByDone(*userID, projectFilter)
+			// update context values
+			tplContext["CompletedTasks"] = completedCount
+			tplContext["IncompleteTasks"] = incompleteCount
+		}
+		// A tag filter scopes the counts further
+		if !taskFilter.IsEmpty() {
+			if ccount, icount, err := tasks.CountCompletionForUser(*userID, projectFilter, taskFilter); err == nil {
+				tplContext["CompletedTasks"] = ccount
+				tplContext["IncompleteTasks"] = icount
 			}
 		}
+		tplContext["TagOptions"] = tagFilterOptions(*userID, taskFilter)
 	}
+	tplContext["TagFilter"] = tagFilterParam(taskFilter)
+	tplContext["TagMode"] = r.FormValue("tag_mode")
+	tplContext["Deferred"] = deferredParam(taskFilter)
+	tplContext["View"] = taskFilter.View
+	addFieldFilterContext(tplContext, r, taskFilter)
+	tplContext["FieldBar"] = fieldFilterBar(r, userID, projectFilter)
+	tplContext["SortByPriority"] = taskFilter.SortByPriority
 
 	// Expose the active project filter to the template so the toolbar select can reflect it
 	tplContext["ProjectFilter"] = projectParam
@@ -289,11 +399,20 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if searchQuery != "" {
+	// The search form includes the toolbar tag filter
+	taskFilter := parseTaskFilter(r)
+	taskFilter.SortByPriority = sessionSortByPriority(r)
+	// Searching within a saved view keeps its filters; typed text replaces its text
+	savedText, saved := applySavedView(r, userID, &taskFilter)
+	if saved && searchQuery == "" {
+		searchQuery = savedText
+	}
+
+	if searchQuery != "" || saved {
 		isSearching = true
-		taskList, totalTasks, err = tasks.SearchTasksForUser(page, pageSize, searchQuery, userID, timezone)
+		taskList, totalTasks, err = tasks.SearchTasksForUserFiltered(page, pageSize, searchQuery, userID, timezone, taskFilter)
 	} else {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, userID, timezone)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userID, timezone, nil, taskFilter)
 	}
 
 	if err != nil {
@@ -305,9 +424,8 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if searchQuery != "" {
-		for i, task := range taskList {
-			taskList[i].Title = highlightMatches(task.Title, searchQuery)
-			taskList[i].Description = highlightMatches(task.Description, searchQuery)
+		for i := range taskList {
+			highlightTask(&taskList[i], searchQuery)
 		}
 	}
 
@@ -351,6 +469,10 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 		"TotalTasks":       totalTasks,
 		"CompletedTasks":   utils.GetCompletedTasksCount(userID),
 		"IncompleteTasks":  utils.GetIncompleteTasksCount(userID),
+		"TagFilter":        tagFilterParam(taskFilter),
+		"TagMode":          r.FormValue("tag_mode"),
+		"Deferred":         deferredParam(taskFilter),
+		"View":             taskFilter.View,
 	}
 
 	if err := utils.RenderTemplate(w, r, "pagination.html", context); err != nil {
@@ -362,8 +484,28 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func highlightMatches(text, searchQuery string) string {
+// highlightTask marks the search matches in the task's title and description for the
+// list. Title and Description stay plain text; the marked copies go to TitleHTML and
+// DescriptionHTML, which the row shows only while searching.
+func highlightTask(t *tasks.Task, searchQuery string) {
+	t.TitleHTML = highlightMatches(t.Title, searchQuery)
+	t.DescriptionHTML = highlightMatches(t.Description, searchQuery)
+}
+
+// highlightMatches escapes text and wraps each case-insensitive match of searchQuery
+// in <mark>.
+func highlightMatches(text, searchQuery string) template.HTML {
+	if searchQuery == "" {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
 	re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(searchQuery))
-	text = re.ReplaceAllString(text, "<mark>$0</mark>")
-	return text
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:m[0]]))
+		b.WriteString("<mark>" + template.HTMLEscapeString(text[m[0]:m[1]]) + "</mark>")
+		last = m[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+	return template.HTML(b.String())
 }
@@ -13,7 +13,10 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// APIUpdateProfile updates the user's name and timezone
+// MaxArchiveAfterDays caps the automatic archiving delay a user can choose.
+const MaxArchiveAfterDays = 365
+
+// APIUpdateProfile updates the user's name, timezone and task list preferences
 func APIUpdateProfile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
@@ -35,6 +38,16 @@ func APIUpdateProfile(w http.ResponseWriter, r *http.Request) {
 			itemsPerPage = v
 		}
 	}
+	// Empty leaves the setting unchanged; 0 turns automatic archiving off
+	archiveAfterDays := -1
+	if v := r.FormValue("archive_after_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 || days > MaxArchiveAfterDays {
+			http.Error(w, "Invalid archive setting", http.StatusBadRequest)
+			return
+		}
+		archiveAfterDays = days
+	}
 	if userName == "" {
 		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
@@ -60,6 +73,12 @@ func APIUpdateProfile(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if archiveAfterDays >= 0 {
+		if _, err := db.Exec(context.Background(), "UPDATE users SET archive_after_days = $1 WHERE email = $2", archiveAfterDays, email); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
 
 	session, err := sessionstore.Store.Get(r, "session")
 	if err != nil {
@@ -110,16 +129,22 @@ func ProfilePage(w http.ResponseWriter, r *http.Request) {
 	if user != nil && user.ItemsPerPage > 0 {
 		itemsPerPage = user.ItemsPerPage
 	}
+	archiveAfterDays := 0
+	if user != nil {
+		archiveAfterDays = user.ArchiveAfterDays
+	}
 
 	context := map[string]interface{}{
-		"UserEmail":    email,
-		"Email":        email,
-		"Timezone":     timezone,
-		"Status":       statusMsg,
-		"Name":         user_name,
-		"ItemsPerPage": itemsPerPage,
-		"LoggedIn":     loggedIn,
-		"Permissions":  permissions,
+		"UserEmail":        email,
+		"Email":            email,
+		"Timezone":         timezone,
+		"Status":           statusMsg,
+		"Name":             user_name,
+		"ItemsPerPage":     itemsPerPage,
+		"ArchiveAfterDays": archiveAfterDays,
+		"ArchiveChoices":   []int{1, 3, 7, 14, 30, 60, 90},
+		"LoggedIn":         loggedIn,
+		"Permissions":      permissions,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -3,6 +3,7 @@ package handlers
 import (
 	"GoTodo/internal/server/utils"
 	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,6 +13,62 @@ import (
 
 const MaxProjectNameLength = 50
 
+// projectRow is a project listed with the time logged on its tasks and their estimated effort.
+type projectRow struct {
+	storage.Project
+	TrackedSeconds int64
+	TimeLabel      string
+	Effort         storage.ProjectEffort
+	EstimatedLabel string
+	RemainingLabel string
+	CompletedLabel string
+}
+
+// projectRows loads the user's projects with their logged time and effort totals for the projects list.
+func projectRows(userID int) ([]projectRow, error) {
+	projects, err := storage.GetProjectsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := storage.GetProjectTimeTotals(userID)
+	if err != nil {
+		return nil, err
+	}
+	efforts, err := storage.GetProjectEffortTotals(userID)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]projectRow, 0, len(projects))
+	for _, p := range projects {
+		e := efforts[p.ID]
+		rows = append(rows, projectRow{
+			Project:        p,
+			TrackedSeconds: totals[p.ID],
+			TimeLabel:      tasks.FormatDuration(totals[p.ID]),
+			Effort:         e,
+			EstimatedLabel: effortLabel(e.EstimatedHours, e.EstimatedPoints),
+			RemainingLabel: effortLabel(e.RemainingHours, e.RemainingPoints),
+			CompletedLabel: effortLabel(e.CompletedHours, e.CompletedPoints),
+		})
+	}
+	return rows, nil
+}
+
+// effortLabel formats an amount of effort that may be in hours, points or both, e.g. "6h, 5 pts".
+func effortLabel(hours, points float64) string {
+	parts := make([]string, 0, 2)
+	if hours > 0 {
+		parts = append(parts, tasks.FormatEffort(hours, tasks.EstimateHours))
+	}
+	if points > 0 {
+		parts = append(parts, tasks.FormatEffort(points, tasks.EstimatePoints))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
 // ProjectsPageHandler shows the user's projects and a simple create form.
 func ProjectsPageHandler(w http.ResponseWriter, r *http.Request) {
 	_, _, _, loggedIn := utils.GetSessionUser(r)
@@ -28,7 +85,7 @@ func ProjectsPageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	projects, err := storage.GetProjectsForUser(*uidPtr)
+	projects, err := projectRows(*uidPtr)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error fetching projects: %v", err), http.StatusInternalServerError)
 		return
@@ -94,7 +151,7 @@ func APICreateProject(w http.ResponseWriter, r *http.Request) {
 
 	// If this is an HTMX request, return the updated list fragment
 	if r.Header.Get("HX-Request") == "true" {
-		projects, err := storage.GetProjectsForUser(*uidPtr)
+		projects, err := projectRows(*uidPtr)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error fetching projects: %v", err), http.StatusInternalServerError)
 			return
@@ -158,7 +215,7 @@ func APIDeleteProject(w http.ResponseWriter, r *http.Request) {
 
 	// If HTMX request, return updated fragment
 	if r.Header.Get("HX-Request") == "true" {
-		projects, err := storage.GetProjectsForUser(*uidPtr)
+		projects, err := projectRows(*uidPtr)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error fetching projects: %v", err), http.StatusInternalServerError)
 			return
@@ -179,7 +236,8 @@ func APIDeleteProject(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, " ")
 }
 
-// APIProjectsJSON returns a JSON list of the user's projects (id and name)
+// APIProjectsJSON returns a JSON list of the user's projects (id, name, logged seconds and
+// estimated, remaining and completed effort in hours and in points)
 func APIProjectsJSON(w http.ResponseWriter, r *http.Request) {
 	_, _, _, loggedIn := utils.GetSessionUser(r)
 	if !loggedIn {
@@ -195,18 +253,35 @@ func APIProjectsJSON(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"error":"Unauthorized"}`))
 		return
 	}
-	projects, err := storage.GetProjectsForUser(*uidPtr)
+	projects, err := projectRows(*uidPtr)
 	if err != nil {
 		http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
 		return
 	}
 	type pj struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
+		ID              int     `json:"id"`
+		Name            string  `json:"name"`
+		TrackedSeconds  int64   `json:"tracked_seconds"`
+		EstimatedHours  float64 `json:"estimated_hours"`
+		RemainingHours  float64 `json:"remaining_hours"`
+		CompletedHours  float64 `json:"completed_hours"`
+		EstimatedPoints float64 `json:"estimated_points"`
+		RemainingPoints float64 `json:"remaining_points"`
+		CompletedPoints float64 `json:"completed_points"`
 	}
 	out := make([]pj, 0, len(projects))
 	for _, p := range projects {
-		out = append(out, pj{ID: p.ID, Name: p.Name})
+		out = append(out, pj{
+			ID:              p.ID,
+			Name:            p.Name,
+			TrackedSeconds:  p.TrackedSeconds,
+			EstimatedHours:  p.Effort.EstimatedHours,
+			RemainingHours:  p.Effort.RemainingHours,
+			CompletedHours:  p.Effort.CompletedHours,
+			EstimatedPoints: p.Effort.EstimatedPoints,
+			RemainingPoints: p.Effort.RemainingPoints,
+			CompletedPoints: p.Effort.CompletedPoints,
+		})
 	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	json.NewEncoder(w).Encode(out)
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"GoTodo/internal/quickadd"
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxQuickAddLength caps the quick-add line.
+const MaxQuickAddLength = 500
+
+// quickAddFormFlag marks a request APIQuickAdd hands to APIAddTask, so validation errors
+// are shown under the quick-add box.
+const quickAddFormFlag = "quick_add"
+
+// quickAddTask is a parsed quick-add line with its #labels resolved against the user's
+// projects and tags. The first label naming a project sets the project; the others
+// must name tags.
+type quickAddTask struct {
+	quickadd.Result
+	Project  *storage.Project
+	Tags     []storage.Tag
+	Unknown  []string // labels that match neither a project nor a tag
+	DueLabel string   // due date and time for display
+	Badge    tasks.Task
+}
+
+// labelKey normalizes a project or tag name for matching a #label, so "#side-project"
+// matches "Side project".
+func labelKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer("-", " ", "_", " ").Replace(s)
+}
+
+// parseQuickAdd parses a quick-add line in the user's timezone and resolves its labels.
+// A line without a date is due on day (YYYY-MM-DD) when one is given, as when adding
+// from a calendar day.
+func parseQuickAdd(text, day string, userID int, timezone string) (quickAddTask, error) {
+	q := quickAddTask{Result: quickadd.ParseOnDay(text, day, time.Now(), tasks.UserLocation(timezone))}
+	if len(q.Labels) > 0 {
+		projects, err := storage.GetProjectsForUser(userID)
+		if err != nil {
+			return q, err
+		}
+		tags, err := storage.GetTagsForUser(userID)
+		if err != nil {
+			return q, err
+		}
+	labels:
+		for _, label := range q.Labels {
+			key := labelKey(label)
+			if q.Project == nil {
+				for i := range projects {
+					if labelKey(projects[i].Name) == key {
+						q.Project = &projects[i]
+						continue labels
+					}
+				}
+			}
+			for _, tag := range tags {
+				if labelKey(tag.Name) == key {
+					q.Tags = append(q.Tags, tag)
+					continue labels
+				}
+			}
+			q.Unknown = append(q.Unknown, label)
+		}
+	}
+	// The priority badge is shown as on a task row
+	q.Badge.Priority, _ = tasks.ParsePriority(q.Priority)
+	if d, err := time.Parse("2006-01-02", q.DueDate); err == nil {
+		q.DueLabel = d.Format("Mon, Jan 2 2006")
+		if t, err := time.Parse("15:04", q.DueTime); err == nil {
+			q.DueLabel += " " + t.Format("3:04 PM")
+		}
+	}
+	return q, nil
+}
+
+// quickAddError shows a validation message under the quick-add box, in the same way
+// APIAddTask reports errors in the task form.
+func quickAddError(w http.ResponseWriter, msg string) {
+	w.Header().Set("X-Validation-Error", "true")
+	w.Header().Set("HX-Retarget", "#quick-add-error")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, msg)
+}
+
+// APIQuickAddPreview renders the fields parsed from the quick-add box as the user types.
+func APIQuickAddPreview(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	text := strings.TrimSpace(r.FormValue("text"))
+	data := map[string]interface{}{"Empty": text == ""}
+	if text != "" {
+		q, err := parseQuickAdd(text, r.FormValue("date"), userID, timezone)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read projects and tags: %v", err), http.StatusInternalServerError)
+			return
+		}
+		data["Task"] = q
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "quick_add_preview.html", data); err != nil {
+		http.Error(w, "Error rendering preview: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// APIQuickAdd creates a task from a quick-add line such as "Pay rent tomorrow 9am #home
+// !high", due on the day in date when the line has no date. The parsed title, due date
+// and time, priority, project and tags are filled into the request form and handed to
+// APIAddTask, so the task is inserted and the list re-rendered exactly as when it is
+// added from the task form.
+func APIQuickAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	text := strings.TrimSpace(r.FormValue("text"))
+	if len(text) > MaxQuickAddLength {
+		quickAddError(w, fmt.Sprintf("Quick add text must be %d characters or less", MaxQuickAddLength))
+		return
+	}
+	q, err := parseQuickAdd(text, r.FormValue("date"), userID, timezone)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read projects and tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if q.Title == "" {
+		quickAddError(w, "Title is required")
+		return
+	}
+	if len(q.Unknown) > 0 {
+		quickAddError(w, "No project or tag named #"+strings.Join(q.Unknown, ", #"))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	r.Form.Set(quickAddFormFlag, "1")
+	r.Form.Set("title", q.Title)
+	r.Form.Set("due_date", q.DueDate)
+	r.Form.Set("due_time", q.DueTime)
+	r.Form.Set("priority", q.Priority)
+	r.Form.Del("project_id")
+	if q.Project != nil {
+		r.Form.Set("project_id", strconv.Itoa(q.Project.ID))
+	}
+	if len(q.Tags) > 0 {
+		r.Form.Set("tags_submitted", "1")
+		for _, tag := range q.Tags {
+			r.Form.Add("tag_ids", strconv.Itoa(tag.ID))
+		}
+	}
+	APIAddTask(w, r)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"GoTodo/internal/tasks"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// repeatForm carries a stored repeat rule into the sidebar form fields.
+type repeatForm struct {
+	Mode     string // daily, weekly, monthly, yearly or custom; empty when the task does not repeat
+	Interval int
+	Days     map[string]bool // weekday codes (MO, TU, ...) selected for weekly rules
+	MonthDay int
+	LastDay  bool
+	RRule    string // raw rule, shown for custom rules
+	Summary  string
+}
+
+// newRepeatForm maps a stored rule onto the simple form controls. Rules using parts
+// the controls can't express (UNTIL, COUNT, a yearly day) are shown as a custom RRULE
+// instead, so saving the form keeps them.
+func newRepeatForm(stored string) *repeatForm {
+	f := &repeatForm{Interval: 1, Days: map[string]bool{}}
+	rule, err := tasks.ParseRepeatRule(stored)
+	if err != nil {
+		f.Mode = "custom"
+		f.RRule = stored
+		return f
+	}
+	if rule == nil {
+		return f
+	}
+
+	f.Summary = rule.Describe()
+	f.RRule = rule.String()
+	if !rule.Until.IsZero() || rule.Count > 0 || (rule.Freq == tasks.FreqYearly && rule.MonthDay != 0) {
+		f.Mode = "custom"
+		return f
+	}
+	f.Mode = strings.ToLower(rule.Freq)
+	f.Interval = rule.Interval
+	for _, wd := range rule.Weekdays {
+		f.Days[strings.ToUpper(wd.String()[:2])] = true
+	}
+	if rule.MonthDay == -1 {
+		f.LastDay = true
+	} else {
+		f.MonthDay = rule.MonthDay
+	}
+	return f
+}
+
+// repeatRuleFromForm builds the canonical repeat rule from the sidebar form fields.
+// It returns an empty string when the task should not repeat.
+func repeatRuleFromForm(r *http.Request) (string, error) {
+	mode := strings.TrimSpace(r.FormValue("repeat"))
+	switch mode {
+	case "", "none":
+		return "", nil
+	case "custom":
+		rule, err := tasks.ParseRepeatRule(r.FormValue("repeat_rrule"))
+		if err != nil {
+			return "", err
+		}
+		if rule == nil {
+			return "", fmt.Errorf("enter a repeat rule, e.g. FREQ=WEEKLY;BYDAY=MO,FR")
+		}
+		return rule.String(), nil
+	case "daily", "weekly", "monthly", "yearly":
+	default:
+		return "", fmt.Errorf("unknown repeat option")
+	}
+
+	parts := []string{"FREQ=" + strings.ToUpper(mode)}
+	if v := strings.TrimSpace(r.FormValue("repeat_interval")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("repeat interval must be a number")
+		}
+		parts = append(parts, "INTERVAL="+strconv.Itoa(n))
+	}
+	switch mode {
+	case "weekly":
+		if days := r.Form["repeat_weekdays"]; len(days) > 0 {
+			parts = append(parts, "BYDAY="+strings.Join(days, ","))
+		}
+	case "monthly":
+		if r.FormValue("repeat_lastday") != "" {
+			parts = append(parts, "BYMONTHDAY=-1")
+		} else if v := strings.TrimSpace(r.FormValue("repeat_monthday")); v != "" {
+			parts = append(parts, "BYMONTHDAY="+v)
+		}
+	}
+
+	// Round-trip through the parser so the same validation applies to every mode
+	rule, err := tasks.ParseRepeatRule(strings.Join(parts, ";"))
+	if err != nil {
+		return "", err
+	}
+	return rule.String(), nil
+}
+
+// nullableRule converts an empty rule to NULL for storage.
+func nullableRule(rule string) interface{} {
+	if rule == "" {
+		return nil
+	}
+	return rule
+}
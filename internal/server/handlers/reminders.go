@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dueTimeFromForm validates the optional due time (HH:MM). A due time needs a due date.
+func dueTimeFromForm(r *http.Request, dueDate string) (string, error) {
+	dueTime := strings.TrimSpace(r.FormValue("due_time"))
+	if dueTime == "" {
+		return "", nil
+	}
+	if dueDate == "" {
+		return "", fmt.Errorf("a due time needs a due date")
+	}
+	t, err := time.Parse("15:04", dueTime)
+	if err != nil {
+		return "", fmt.Errorf("due time must look like 15:30")
+	}
+	return t.Format("15:04"), nil
+}
+
+// nullableTime returns nil for an empty due time so it is stored as NULL.
+func nullableTime(dueTime string) interface{} {
+	if dueTime == "" {
+		return nil
+	}
+	return dueTime
+}
+
+// formReminderOffsets returns the reminder offsets checked in the task form, and whether
+// the reminder options were part of the form at all (they load lazily).
+func formReminderOffsets(r *http.Request) ([]int, bool) {
+	if r.FormValue("reminders_submitted") == "" {
+		return nil, false
+	}
+	offsets := make([]int, 0)
+	for _, v := range r.Form["reminder_offsets"] {
+		if m, err := strconv.Atoi(v); err == nil && tasks.IsReminderPreset(m) {
+			offsets = append(offsets, m)
+		}
+	}
+	return offsets, true
+}
+
+// APIReminderOptions renders the reminder checkboxes for the add/edit task form. When
+// task_id is given, the task's current reminders are pre-checked.
+func APIReminderOptions(w http.ResponseWriter, r *http.Request) {
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	checked := map[int]bool{}
+	if taskID, err := strconv.Atoi(r.URL.Query().Get("task_id")); err == nil {
+		offsets, err := storage.GetReminderOffsets(taskID, *uidPtr)
+		if err != nil {
+			http.Error(w, "Failed to fetch task reminders", http.StatusInternalServerError)
+			return
+		}
+		for _, m := range offsets {
+			checked[m] = true
+		}
+	}
+
+	options := make([]map[string]interface{}, 0, len(tasks.ReminderPresets))
+	for _, p := range tasks.ReminderPresets {
+		options = append(options, map[string]interface{}{"Minutes": p.Minutes, "Label": p.Label, "Checked": checked[p.Minutes]})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "reminder_options.html", map[string]interface{}{"Options": options, "DefaultTime": tasks.DefaultReminderTime}); err != nil {
+		http.Error(w, "Error rendering reminder options: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -117,7 +118,7 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 				args = append(args, *projectFilter)
 			}
 		}
-		query := "SELECT EXISTS(SELECT 1 FROM tasks WHERE id = $1 AND user_id = $2 AND COALESCE(is_favorite,false) = $3" + projectCond + ")"
+		query := "SELECT EXISTS(SELECT 1 FROM tasks WHERE id = $1 AND user_id = $2 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL AND COALESCE(is_favorite,false) = $3" + projectCond + ")"
 		err = db.QueryRow(context.Background(), query, args...).Scan(&exists)
 		if err != nil {
 			http.Error(w, "Error validating tasks", http.StatusInternalServerError)
@@ -132,7 +133,7 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 	// Fetch all task IDs in this user's group ordered by position so we can renumber globally
 	projectCondAll := ""
 	argsAll := []interface{}{userID, isFav}
-	q := "SELECT id FROM tasks WHERE user_id = $1 AND COALESCE(is_favorite,false) = $2"
+	q := "SELECT id, COALESCE(priority,0) FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL AND COALESCE(is_favorite,false) = $2"
 	if projectFilter != nil {
 		if *projectFilter == 0 {
 			projectCondAll = " AND project_id IS NULL"
@@ -152,43 +153,30 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 	defer rowsAll.Close()
 
 	allIDs := make([]int, 0)
+	priorityOf := make(map[int]int)
 	for rowsAll.Next() {
-		var tid int
-		if err := rowsAll.Scan(&tid); err != nil {
+		var tid, priority int
+		if err := rowsAll.Scan(&tid, &priority); err != nil {
 			http.Error(w, "Error reading task ids", http.StatusInternalServerError)
 			return
 		}
 		allIDs = append(allIDs, tid)
+		priorityOf[tid] = priority
 	}
 
+	// When the list is sorted by priority the client's order is relative to that view
+	sortByPriority := sessionSortByPriority(r)
+	manualIDs := allIDs
+	if sortByPriority {
+		allIDs = append([]int(nil), manualIDs...)
+		sort.SliceStable(allIDs, func(i, j int) bool { return priorityOf[allIDs[i]] > priorityOf[allIDs[j]] })
+	}
+
+	pageSize := sessionPageSize(r)
 	if len(allIDs) == 0 {
 		// Nothing to reorder
 	} else {
 		// Compute page window start index and clamp
-		pageSize := utils.AppConstants.PageSize
-		if sess, err := sessionstore.Store.Get(r, "session"); err == nil && sess != nil {
-			if val, ok := sess.Values["items_per_page"]; ok {
-				switch tv := val.(type) {
-				case int:
-					if tv > 0 {
-						pageSize = tv
-					}
-				case int64:
-					if int(tv) > 0 {
-						pageSize = int(tv)
-					}
-				case float64:
-					if int(tv) > 0 {
-						pageSize = int(tv)
-					}
-				case string:
-					if v, err := strconv.Atoi(tv); err == nil && v > 0 {
-						pageSize = v
-					}
-				}
-			}
-		}
-
 		start := (page - 1) * pageSize
 		if start < 0 {
 			start = 0
@@ -201,6 +189,9 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Remember the current order so the move can be undone
+		undoState, undoErr := storage.SnapshotTasks(userID, manualIDs)
+
 		// Replace the slice segment with the new ordering provided by the client
 		for i, id := range ids {
 			if start+i < len(allIDs) {
@@ -219,8 +210,16 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 		}
 		defer tx.Rollback(context.Background())
 
-		for idx, id := range allIDs {
-			pos := idx + 1
+		positions := make(map[int]int, len(allIDs))
+		if sortByPriority {
+			positions = priorityBandPositions(manualIDs, allIDs, priorityOf)
+		} else {
+			for idx, id := range allIDs {
+				positions[id] = idx + 1
+			}
+		}
+
+		for id, pos := range positions {
 			_, err := tx.Exec(context.Background(), "UPDATE tasks SET position = $1 WHERE id = $2 AND user_id = $3", pos, id, userID)
 			if err != nil {
 				http.Error(w, "Error updating positions", http.StatusInternalServerError)
@@ -232,43 +231,18 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Error committing position updates", http.StatusInternalServerError)
 			return
 		}
-	}
-
-	// Determine page size from session
-	pageSize := utils.AppConstants.PageSize
-	if sess, err := sessionstore.Store.Get(r, "session"); err == nil && sess != nil {
-		if val, ok := sess.Values["items_per_page"]; ok {
-			switch tv := val.(type) {
-			case int:
-				if tv > 0 {
-					pageSize = tv
-				}
-			case int64:
-				if int(tv) > 0 {
-					pageSize = int(tv)
-				}
-			case float64:
-				if int(tv) > 0 {
-					pageSize = int(tv)
-				}
-			case string:
-				if v, err := strconv.Atoi(tv); err == nil && v > 0 {
-					pageSize = v
-				}
-			}
+		if undoErr == nil {
+			offerUndo(w, userID, "Tasks reordered", storage.UndoRecord{Kind: "reorder", Tasks: undoState}, page, projectParam)
 		}
 	}
 
-	// Optional project filter
-	// (projectParam and projectFilter were parsed earlier)
-
 	userPtr := &userID
 	var taskList []tasks.Task
 	var totalTasks int
 	if projectFilter != nil {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, userPtr, timezone, projectFilter)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userPtr, timezone, projectFilter, sessionTaskOrder(r))
 	} else {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, userPtr, timezone)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userPtr, timezone, nil, sessionTaskOrder(r))
 	}
 	if err != nil {
 		http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
@@ -294,24 +268,7 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 	completedCount := pagination.TotalCompletedTasks
 	incompleteCount := pagination.TotalIncompleteTasks
 	if projectFilter != nil {
-		pool, err := storage.OpenDatabase()
-		if err == nil {
-			defer storage.CloseDatabase(pool)
-			projectCond := ""
-			args := []interface{}{uid}
-			if *projectFilter == 0 {
-				projectCond = " AND project_id IS NULL"
-			} else {
-				projectCond = " AND project_id = $2"
-				args = append(args, *projectFilter)
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&completedCount); err != nil {
-				completedCount = 0
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&incompleteCount); err != nil {
-				incompleteCount = 0
-			}
-		}
+		completedCount, incompleteCount, _ = storage.CountTasksByDone(uid, projectFilter)
 	}
 
 	// Fetch projects for user and mark selected
@@ -354,3 +311,27 @@ func APIReorderTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// priorityBandPositions assigns positions after a drag in the priority-sorted view. Tasks
+// never leave their priority band: one dropped among another band snaps back to the nearest
+// edge of its own band. Each band keeps the slots it held in the manual order, so reordering
+// inside a band does not disturb the manual order of the other bands.
+func priorityBandPositions(manualIDs, displayed []int, priorityOf map[int]int) map[int]int {
+	sort.SliceStable(displayed, func(i, j int) bool { return priorityOf[displayed[i]] > priorityOf[displayed[j]] })
+
+	slots := make(map[int][]int)
+	for idx, id := range manualIDs {
+		slots[priorityOf[id]] = append(slots[priorityOf[id]], idx+1)
+	}
+
+	positions := make(map[int]int, len(displayed))
+	for _, id := range displayed {
+		band := priorityOf[id]
+		if len(slots[band]) == 0 {
+			continue
+		}
+		positions[id] = slots[band][0]
+		slots[band] = slots[band][1:]
+	}
+	return positions
+}
@@ -5,7 +5,6 @@ import (
 	"GoTodo/internal/sessionstore"
 	"GoTodo/internal/storage"
 	"GoTodo/internal/tasks"
-	"context"
 	"net/http"
 	"strconv"
 )
@@ -51,6 +50,11 @@ func APIReturnTasks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Optional tag filter (any/all of the selected tags)
+	taskFilter := parseTaskFilter(r)
+	// Sort order is remembered in the session once chosen in the toolbar
+	taskFilter.SortByPriority = applySortParam(w, r)
+
 	// Parse "page" query parameter
 	var currentPage int
 	if pageParam := r.URL.Query().Get("page"); pageParam != "" {
@@ -70,30 +74,31 @@ func APIReturnTasks(w http.ResponseWriter, r *http.Request) {
 	if loggedIn {
 		userID = getUserIDFromEmail(email)
 	}
+	applyFieldFilter(r, &taskFilter, userID, projectFilter)
+	// A saved view is evaluated as a search for its text and filters
+	savedText, saved := applySavedView(r, userID, &taskFilter)
+	if saved && searchQuery == "" {
+		searchQuery = savedText
+	}
 
 	// Fetch tasks for the current page
 	var taskList []tasks.Task
 	var totalTasks int
 	var err error
 
-	if searchQuery != "" {
-		taskList, totalTasks, err = tasks.SearchTasksForUser(page, pageSize, searchQuery, userID, timezone)
+	if searchQuery != "" || saved {
+		taskList, totalTasks, err = tasks.SearchTasksForUserFiltered(page, pageSize, searchQuery, userID, timezone, taskFilter)
 		if err != nil {
 			http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		// Highlight search matches
-		for i, task := range taskList {
-			taskList[i].Title = highlightMatches(task.Title, searchQuery)
-			taskList[i].Description = highlightMatches(task.Description, searchQuery)
+		for i := range taskList {
+			highlightTask(&taskList[i], searchQuery)
 		}
 	} else {
-		if projectFilter != nil {
-			taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, userID, timezone, projectFilter)
-		} else {
-			taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, userID, timezone)
-		}
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userID, timezone, projectFilter, taskFilter)
 		if err != nil {
 			http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -114,30 +119,21 @@ func APIReturnTasks(w http.ResponseWriter, r *http.Request) {
 
 	// If page was adjusted, we need to refetch with the correct page
 	if page != currentPage {
-		if searchQuery != "" {
-			taskList, totalTasks, err = tasks.SearchTasksForUser(page, pageSize, searchQuery, userID, timezone)
+		if searchQuery != "" || saved {
+			taskList, totalTasks, err = tasks.SearchTasksForUserFiltered(page, pageSize, searchQuery, userID, timezone, taskFilter)
 			if err != nil {
 				http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
 				return
 			}
 			// Highlight search matches
-			for i, task := range taskList {
-				taskList[i].Title = highlightMatches(task.Title, searchQuery)
-				taskList[i].Description = highlightMatches(task.Description, searchQuery)
+			for i := range taskList {
+				highlightTask(&taskList[i], searchQuery)
 			}
 		} else {
-			if projectFilter != nil {
-				taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, userID, timezone, projectFilter)
-				if err != nil {
-					http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
-					return
-				}
-			} else {
-				taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, userID, timezone)
-				if err != nil {
-					http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
-					return
-				}
+			taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userID, timezone, projectFilter, taskFilter)
+			if err != nil {
+				http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
+				return
 			}
 		}
 	}
@@ -170,35 +166,16 @@ func APIReturnTasks(w http.ResponseWriter, r *http.Request) {
 	if userID == nil {
 		completedCount = 0
 		incompleteCount = 0
+	} else if !taskFilter.IsEmpty() {
+		// Tag filters are applied in the tasks package
+		completedCount, incompleteCount, _ = tasks.CountCompletionForUser(*userID, projectFilter, taskFilter)
 	} else {
 		if projectFilter == nil {
 			// Use existing helpers for whole-user counts
 			completedCount = utils.GetCompletedTasksCount(userID)
 			incompleteCount = utils.GetIncompleteTasksCount(userID)
 		} else {
-			// Query DB for counts constrained by project
-			pool, err := storage.OpenDatabase()
-			if err == nil {
-				defer storage.CloseDatabase(pool)
-				projectCond := ""
-				// args: always include user_id as $1; project (if numeric) as $2
-				args := []interface{}{*userID}
-				if *projectFilter == 0 {
-					projectCond = " AND project_id IS NULL"
-				} else {
-					projectCond = " AND project_id = $2"
-					args = append(args, *projectFilter)
-				}
-
-				// completed
-				if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&completedCount); err != nil {
-					completedCount = 0
-				}
-				// incomplete
-				if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&incompleteCount); err != nil {
-					incompleteCount = 0
-				}
-			}
+			completedCount, incompleteCount, _ = storage.CountTasksByDone(*userID, projectFilter)
 		}
 	}
 
@@ -230,6 +207,7 @@ func APIReturnTasks(w http.ResponseWriter, r *http.Request) {
 		"PrevDisabled":     pagination.PrevDisabled,
 		"NextDisabled":     pagination.NextDisabled,
 		"SearchQuery":      searchQuery,
+		"IsSearching":      searchQuery != "",
 		"TotalTasks":       totalTasks,
 		"LoggedIn":         loggedIn,
 		"Timezone":         timezone,
@@ -241,7 +219,12 @@ func APIReturnTasks(w http.ResponseWriter, r *http.Request) {
 		"IncompleteTasks":  incompleteCount,
 		"ProjectFilter":    projectParam,
 		"Projects":         projectsList,
+		"TagFilter":        tagFilterParam(taskFilter),
+		"TagMode":          r.URL.Query().Get("tag_mode"),
+		"Deferred":         deferredParam(taskFilter),
+		"View":             taskFilter.View,
 	}
+	addFieldFilterContext(context, r, taskFilter)
 
 	if err := utils.RenderTemplate(w, r, "pagination.html", context); err != nil {
 		http.Error(w, "Error rendering template: "+err.Error(), http.StatusInternalServerError)
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// fieldChange is one field that differs between two versions of a task.
+type fieldChange struct {
+	Field string
+	Old   string
+	New   string
+	Long  bool // multi-line text, shown as blocks rather than inline
+}
+
+// revisionView is a revision prepared for task_history.html.
+type revisionView struct {
+	storage.TaskRevision
+	Changes []fieldChange
+	Current bool // the task is in the state this revision left it in
+}
+
+// revisionChanges lists the tracked fields that differ between two versions of a task.
+func revisionChanges(before, after storage.RevisionState) []fieldChange {
+	changes := make([]fieldChange, 0, 5)
+	if before.Title != after.Title {
+		changes = append(changes, fieldChange{Field: "Title", Old: before.Title, New: after.Title})
+	}
+	if before.Description != after.Description {
+		changes = append(changes, fieldChange{Field: "Description", Old: revisionTextLabel(before.Description), New: revisionTextLabel(after.Description), Long: true})
+	}
+	if before.DueDate != after.DueDate {
+		changes = append(changes, fieldChange{Field: "Due date", Old: revisionDueLabel(before), New: revisionDueLabel(after)})
+	}
+	if !sameProject(before.ProjectID, after.ProjectID) {
+		changes = append(changes, fieldChange{Field: "Project", Old: revisionProjectLabel(before), New: revisionProjectLabel(after)})
+	}
+	if before.Completed != after.Completed {
+		changes = append(changes, fieldChange{Field: "Status", Old: revisionStatusLabel(before), New: revisionStatusLabel(after)})
+	}
+	return changes
+}
+
+// sameProject reports whether two optional project ids refer to the same project (or both none).
+func sameProject(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func revisionTextLabel(text string) string {
+	if text == "" {
+		return "none"
+	}
+	return text
+}
+
+func revisionDueLabel(s storage.RevisionState) string {
+	if s.DueDate == "" {
+		return "none"
+	}
+	return s.DueDate
+}
+
+func revisionProjectLabel(s storage.RevisionState) string {
+	if s.ProjectID == nil {
+		return "none"
+	}
+	if s.ProjectName == "" {
+		return "deleted project"
+	}
+	return s.ProjectName
+}
+
+func revisionStatusLabel(s storage.RevisionState) string {
+	if s.Completed {
+		return "completed"
+	}
+	return "open"
+}
+
+// renderTaskHistory renders the revision history of a task, newest first.
+func renderTaskHistory(w http.ResponseWriter, r *http.Request, taskID, userID int, timezone string) {
+	revisions, err := storage.GetTaskRevisions(taskID, userID, timezone)
+	if err != nil {
+		http.Error(w, "Failed to fetch history", http.StatusInternalServerError)
+		return
+	}
+	current, err := storage.GetRevisionState(taskID, userID)
+	if err != nil {
+		http.Error(w, "Task not found.", http.StatusNotFound)
+		return
+	}
+
+	views := make([]revisionView, 0, len(revisions))
+	for _, rev := range revisions {
+		views = append(views, revisionView{
+			TaskRevision: rev,
+			Changes:      revisionChanges(rev.Before, rev.After),
+			Current:      rev.After.Equal(current),
+		})
+	}
+	// The state before the oldest revision can be restored as the original version
+	var original *revisionView
+	if n := len(views); n > 0 {
+		original = &revisionView{TaskRevision: revisions[n-1], Current: revisions[n-1].Before.Equal(current)}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := map[string]interface{}{
+		"TaskID":        taskID,
+		"Revisions":     views,
+		"Original":      original,
+		"Page":          r.FormValue("page"),
+		"ProjectFilter": r.FormValue("project"),
+	}
+	if err := utils.Templates.ExecuteTemplate(w, "task_history.html", data); err != nil {
+		http.Error(w, "Error rendering history: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// APITaskHistory renders the revision history of a task.
+func APITaskHistory(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	renderTaskHistory(w, r, taskID, userID, timezone)
+}
+
+// APIRevertTask puts a task back into the version left by a revision (or the version before
+// it when original is set). Title, description and due date changes re-render the row with
+// its history open; changes to the project or completion reload the list page, since the
+// task may leave the current filter and the counts change.
+func APIRevertTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	revisionID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid revision id", http.StatusBadRequest)
+		return
+	}
+	original := r.FormValue("original") == "true"
+
+	taskID, from, to, err := storage.RevertTaskToRevision(revisionID, userID, original)
+	if err != nil {
+		if errors.Is(err, storage.ErrRevisionNotFound) {
+			http.Error(w, "Revision not found.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to revert task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if from.Equal(to) {
+		triggerToast(w, "The task already matches that version", false)
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	triggerToast(w, "Task reverted", false)
+	if from.Completed != to.Completed || !sameProject(from.ProjectID, to.ProjectID) {
+		page, _ := strconv.Atoi(r.FormValue("page"))
+		addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": r.FormValue("project")})
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	renderTaskRowWith(w, r, taskID, userID, timezone, taskRow{HistoryOpen: true})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MaxSavedViewNameLength = 50
+	MaxSavedViewTextLength = 200
+)
+
+// savedViewPrefix marks a saved view in the view parameter of the task list, which
+// otherwise names a smart view: "saved-12" is the saved view with id 12.
+const savedViewPrefix = "saved-"
+
+// savedViewFilter returns the task filter of a saved view, without its text.
+func savedViewFilter(v storage.SavedView) tasks.TaskFilter {
+	f := tasks.TaskFilter{Project: parseProjectFilter(v.Project), DueFrom: v.DueFrom, DueTo: v.DueTo}
+	switch v.Completion {
+	case storage.CompletionOpen, storage.CompletionDone:
+		done := v.Completion == storage.CompletionDone
+		f.Completed = &done
+	}
+	return f
+}
+
+// applySavedView applies the saved view named in the view parameter to the filter and
+// returns its text. Saved views are evaluated as searches, like SearchHandler does, so
+// the text is matched against titles and descriptions. ok is false when the parameter
+// does not name one of the user's saved views.
+func applySavedView(r *http.Request, userID *int, f *tasks.TaskFilter) (string, bool) {
+	key := r.FormValue("view")
+	id, err := strconv.Atoi(strings.TrimPrefix(key, savedViewPrefix))
+	if userID == nil || !strings.HasPrefix(key, savedViewPrefix) || err != nil {
+		return "", false
+	}
+	v, err := storage.GetSavedView(id, *userID)
+	if err != nil {
+		return "", false
+	}
+	saved := savedViewFilter(v)
+	f.Project, f.Completed, f.DueFrom, f.DueTo = saved.Project, saved.Completed, saved.DueFrom, saved.DueTo
+	f.View = key
+	return v.Query, true
+}
+
+// APICreateSavedView saves the filters in the sidebar form under a name: the project in
+// saved_project, the completion state, the due_from and due_to range and the text.
+func APICreateSavedView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	v := storage.SavedView{
+		Name:       strings.TrimSpace(r.FormValue("name")),
+		Project:    r.FormValue("saved_project"),
+		Completion: r.FormValue("completion"),
+		DueFrom:    r.FormValue("due_from"),
+		DueTo:      r.FormValue("due_to"),
+		Query:      strings.TrimSpace(r.FormValue("text")),
+	}
+	if p := parseProjectFilter(v.Project); p != nil {
+		v.Project = strconv.Itoa(*p)
+	} else {
+		v.Project = ""
+	}
+	if v.Completion != storage.CompletionOpen && v.Completion != storage.CompletionDone {
+		v.Completion = ""
+	}
+
+	msg := ""
+	from, fromErr := time.Parse("2006-01-02", v.DueFrom)
+	to, toErr := time.Parse("2006-01-02", v.DueTo)
+	switch {
+	case v.Name == "":
+		msg = "View name is required"
+	case len(v.Name) > MaxSavedViewNameLength:
+		msg = fmt.Sprintf("View name must be %d characters or less", MaxSavedViewNameLength)
+	case (v.DueFrom != "" && fromErr != nil) || (v.DueTo != "" && toErr != nil):
+		msg = "Pick valid due dates"
+	case fromErr == nil && toErr == nil && to.Before(from):
+		msg = "The due range must end on or after its start"
+	case len(v.Query) > MaxSavedViewTextLength:
+		msg = fmt.Sprintf("Text must be %d characters or less", MaxSavedViewTextLength)
+	}
+	if msg == "" {
+		if _, err := storage.CreateSavedView(userID, v); err != nil {
+			if !errors.Is(err, storage.ErrSavedViewExists) {
+				http.Error(w, fmt.Sprintf("Failed to save view: %v", err), http.StatusInternalServerError)
+				return
+			}
+			msg = "A saved view with that name already exists"
+		}
+	}
+	renderViewsSidebar(w, r, userID, timezone, msg)
+}
+
+// APIDeleteSavedView removes the saved view in id.
+func APIDeleteSavedView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid view id", http.StatusBadRequest)
+		return
+	}
+	if err := storage.DeleteSavedView(id, userID); err != nil && !errors.Is(err, storage.ErrSavedViewNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to delete view: %v", err), http.StatusInternalServerError)
+		return
+	}
+	renderViewsSidebar(w, r, userID, timezone, "")
+}
+
+// APIReorderSavedViews stores the order of the saved views dragged in the sidebar,
+// given as their ids in ids.
+func APIReorderSavedViews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	ids, err := formTaskIDs(r)
+	if err != nil {
+		http.Error(w, "Invalid view id", http.StatusBadRequest)
+		return
+	}
+	if err := storage.ReorderSavedViews(userID, ids); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reorder views: %v", err), http.StatusInternalServerError)
+		return
+	}
+	renderViewsSidebar(w, r, userID, timezone, "")
+}
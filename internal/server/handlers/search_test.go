@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/tasks"
+	"strings"
+	"testing"
+)
+
+// TestSearchResultsHighlight renders a page of search results the way SearchHandler
+// and APIReturnTasks do: matches are marked and the task's own markup stays escaped.
+func TestSearchResultsHighlight(t *testing.T) {
+	t.Chdir("../../..")
+	if err := utils.InitializeTemplates(); err != nil {
+		t.Fatalf("InitializeTemplates: %v", err)
+	}
+
+	taskList := []tasks.Task{{
+		ID:          1,
+		Title:       `<script>alert("x")</script> Weekly report`,
+		Description: "Send the REPORT & notes",
+	}}
+	for i := range taskList {
+		highlightTask(&taskList[i], "report")
+	}
+
+	var out strings.Builder
+	err := utils.Templates.ExecuteTemplate(&out, "pagination.html", map[string]interface{}{
+		"Tasks":       taskList,
+		"CurrentPage": 2,
+		"TotalTasks":  1,
+		"LoggedIn":    true,
+		"IsSearching": true,
+		"SearchQuery": "report",
+	})
+	if err != nil {
+		t.Fatalf("rendering search results: %v", err)
+	}
+	html := out.String()
+
+	for _, want := range []string{
+		"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; Weekly <mark>report</mark>",
+		"Send the <mark>REPORT</mark> &amp; notes",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("search results do not contain %q", want)
+		}
+	}
+	if strings.Contains(html, "<script>alert") {
+		t.Error("search results contain an unescaped <script> from the task title")
+	}
+	if strings.Contains(html, "&lt;mark&gt;") {
+		t.Error("search results show escaped <mark> tags")
+	}
+}
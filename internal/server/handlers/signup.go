@@ -220,12 +220,19 @@ func APISignup(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, "INSERT INTO users (email, password, role_id, timezone) VALUES ($1, $2, $3, $4)", email, string(hashedPassword), defaultRoleID, timezone)
+	var newUserID int
+	err = tx.QueryRow(ctx, "INSERT INTO users (email, password, role_id, timezone) VALUES ($1, $2, $3, $4) RETURNING id", email, string(hashedPassword), defaultRoleID, timezone).Scan(&newUserID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, "Internal server error")
 		return
 	}
+	// New accounts start with the default workflow of statuses
+	if err := storage.SeedDefaultStatusesTx(ctx, tx, newUserID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "Internal server error")
+		return
+	}
 
 	if inviteOnly {
 		_, err = tx.Exec(ctx, "UPDATE invites SET inviteused = 1 WHERE id = $1", inviteID)
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APISnoozeTask hides an open task from the task list until a later day, or brings a
+// snoozed task back with until=clear. until is "tomorrow", "weekend", "next_week" or
+// "date" with a YYYY-MM-DD date, resolved in the user's timezone. The list page the
+// task was on is reloaded since the task leaves (or rejoins) it.
+func APISnoozeTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("HX-Reswap", "none")
+	until := ""
+	if preset := r.FormValue("until"); preset != "clear" {
+		if until, err = tasks.SnoozeUntil(preset, r.FormValue("date"), time.Now(), timezone); err != nil {
+			triggerToast(w, "Pick a date after today", true)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	if err := storage.SnoozeTask(id, userID, until); err != nil {
+		if errors.Is(err, storage.ErrSnoozeNotAllowed) {
+			triggerToast(w, "Only open tasks can be snoozed", true)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to snooze task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if until == "" {
+		triggerToast(w, "Task is back in your list", false)
+	} else {
+		triggerToast(w, "Snoozed until "+tasks.Task{HiddenUntil: until}.HiddenUntilLabel(), false)
+	}
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": r.FormValue("project")})
+	w.WriteHeader(http.StatusOK)
+}
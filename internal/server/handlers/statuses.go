@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const MaxStatusNameLength = 50
+
+// renderWorkflow renders the statuses of a workflow with the controls to change it, for
+// the projects page (a project's workflow) and the profile page (project_id 0, the
+// default workflow).
+func renderWorkflow(w http.ResponseWriter, r *http.Request, projectID, userID int, errMsg string) {
+	workflow, own, err := storage.GetWorkflow(userID, projectID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching statuses: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx := map[string]interface{}{
+		"ProjectID": projectID,
+		"Statuses":  workflow,
+		"Editable":  projectID == 0 || own,
+		"Error":     errMsg,
+	}
+	utils.RenderTemplate(w, r, "workflow_statuses.html", ctx)
+}
+
+// workflowRequest reads the session user and the project_id of a workflow request.
+// It writes the error response and returns false when either is missing.
+func workflowRequest(w http.ResponseWriter, r *http.Request, post bool) (int, int, bool) {
+	if post && r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return 0, 0, false
+	}
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	projectID := 0
+	if v := r.FormValue("project_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid project id", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		projectID = id
+	}
+	return *uidPtr, projectID, true
+}
+
+// statusError turns a workflow storage error into a message for the user. Other errors
+// are reported as a server error and an empty message is returned.
+func statusError(w http.ResponseWriter, err error) (string, bool) {
+	switch {
+	case errors.Is(err, storage.ErrStatusExists):
+		return "A status with that name already exists", true
+	case errors.Is(err, storage.ErrStatusRequired):
+		return "A workflow needs a done status and at least one other status", true
+	case errors.Is(err, storage.ErrStatusNotFound):
+		http.Error(w, "Status not found.", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to update workflow: %v", err), http.StatusInternalServerError)
+	}
+	return "", false
+}
+
+// APIWorkflow renders the default workflow (no project_id) or a project's workflow.
+func APIWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := workflowRequest(w, r, false)
+	if !ok {
+		return
+	}
+	renderWorkflow(w, r, projectID, userID, "")
+}
+
+// APICreateStatus adds an open status at the end of a workflow.
+func APICreateStatus(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := workflowRequest(w, r, true)
+	if !ok {
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	msg := ""
+	switch {
+	case name == "":
+		msg = "Status name is required"
+	case len(name) > MaxStatusNameLength:
+		msg = fmt.Sprintf("Status name must be %d characters or less", MaxStatusNameLength)
+	}
+	if msg == "" {
+		if err := storage.CreateStatus(userID, projectID, name); err != nil {
+			if msg, ok = statusError(w, err); !ok {
+				return
+			}
+		}
+	}
+	renderWorkflow(w, r, projectID, userID, msg)
+}
+
+// updateStatus applies a change to the status in id and re-renders its workflow.
+func updateStatus(w http.ResponseWriter, r *http.Request, apply func(statusID, userID int) error) {
+	userID, projectID, ok := workflowRequest(w, r, true)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid status id", http.StatusBadRequest)
+		return
+	}
+	msg := ""
+	if err := apply(id, userID); err != nil {
+		if msg, ok = statusError(w, err); !ok {
+			return
+		}
+	}
+	renderWorkflow(w, r, projectID, userID, msg)
+}
+
+// APIDeleteStatus removes a status; its tasks move to the first open status.
+func APIDeleteStatus(w http.ResponseWriter, r *http.Request) {
+	updateStatus(w, r, storage.DeleteStatus)
+}
+
+// APISetDoneStatus makes a status the done status of its workflow.
+func APISetDoneStatus(w http.ResponseWriter, r *http.Request) {
+	updateStatus(w, r, storage.SetDoneStatus)
+}
+
+// APIMoveStatus moves a status one place up (direction "up") or down in its workflow.
+func APIMoveStatus(w http.ResponseWriter, r *http.Request) {
+	delta := 1
+	if r.FormValue("direction") == "up" {
+		delta = -1
+	}
+	updateStatus(w, r, func(statusID, userID int) error {
+		return storage.MoveStatus(statusID, userID, delta)
+	})
+}
+
+// APICustomizeWorkflow gives a project its own workflow, starting as a copy of the default.
+func APICustomizeWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := workflowRequest(w, r, true)
+	if !ok {
+		return
+	}
+	if projectID == 0 {
+		http.Error(w, "Invalid project id", http.StatusBadRequest)
+		return
+	}
+	if err := storage.CustomizeProjectWorkflow(projectID, userID); err != nil {
+		if _, ok := statusError(w, err); !ok {
+			return
+		}
+	}
+	renderWorkflow(w, r, projectID, userID, "")
+}
+
+// APIResetWorkflow makes a project use the default workflow again.
+func APIResetWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := workflowRequest(w, r, true)
+	if !ok {
+		return
+	}
+	if projectID == 0 {
+		http.Error(w, "Invalid project id", http.StatusBadRequest)
+		return
+	}
+	if err := storage.ResetProjectWorkflow(projectID, userID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+	renderWorkflow(w, r, projectID, userID, "")
+}
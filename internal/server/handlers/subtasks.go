@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// taskRow is the todo.html context for a row rendered on its own. The Open flags keep an
+// expandable section open after an action inside it.
+type taskRow struct {
+	Task             tasks.Task
+	BasePath         string
+	ProjectFilter    string
+	SubtasksOpen     bool
+	DependenciesOpen bool
+	HistoryOpen      bool
+}
+
+// renderTaskRow re-renders a single top-level task row (including its subtasks)
+// so HTMX can swap it in place.
+func renderTaskRow(w http.ResponseWriter, r *http.Request, taskID, userID int, timezone string) {
+	renderTaskRowWith(w, r, taskID, userID, timezone, taskRow{SubtasksOpen: true})
+}
+
+// renderTaskRowWith re-renders a single top-level task row using the Open flags of row.
+func renderTaskRowWith(w http.ResponseWriter, r *http.Request, taskID, userID int, timezone string, row taskRow) {
+	task, err := tasks.ReturnTaskForUser(taskID, userID, timezone)
+	if err != nil {
+		http.Error(w, "Failed to fetch task", http.StatusInternalServerError)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	if page < 1 {
+		page = 1
+	}
+	task.Page = page
+
+	row.Task = *task
+	row.BasePath = utils.GetBasePath()
+	row.ProjectFilter = r.FormValue("project")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "todo.html", row); err != nil {
+		http.Error(w, "Error rendering task row: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// lookupParentTask returns the project of a top-level task owned by the user.
+// Subtasks can only be attached one level deep.
+func lookupParentTask(parentID, userID int) (sql.NullInt64, error) {
+	pool, err := storage.OpenDatabase()
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	defer storage.CloseDatabase(pool)
+
+	var projectID sql.NullInt64
+	var grandParent sql.NullInt64
+	err = pool.QueryRow(context.Background(), "SELECT project_id, parent_id FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL", parentID, userID).Scan(&projectID, &grandParent)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	if grandParent.Valid {
+		return sql.NullInt64{}, fmt.Errorf("subtasks cannot be nested")
+	}
+	return projectID, nil
+}
+
+// APIAddSubtask creates a child task under an existing top-level task.
+func APIAddSubtask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parentID, err := strconv.Atoi(r.FormValue("parent_id"))
+	if err != nil {
+		http.Error(w, "Invalid parent id", http.StatusBadRequest)
+		return
+	}
+	title := strings.TrimSpace(r.FormValue("title"))
+	if title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	projectID, err := lookupParentTask(parentID, userID)
+	if err != nil {
+		http.Error(w, "Parent task not found", http.StatusNotFound)
+		return
+	}
+
+	db, err := storage.OpenDatabase()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	// Subtasks inherit the parent's project and are appended after their siblings
+	_, err = db.Exec(context.Background(), `INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id, parent_id)
+		VALUES ($1, '', false, $2, NOW() AT TIME ZONE 'UTC',
+			(SELECT COALESCE(MAX(position),0) + 1 FROM tasks WHERE parent_id = $4), $3, $4)`,
+		title, userID, projectID, parentID)
+	if err != nil {
+		http.Error(w, "Failed to add subtask", http.StatusInternalServerError)
+		return
+	}
+
+	// Adding an open step means the parent is no longer done
+	_, _ = db.Exec(context.Background(), "UPDATE tasks SET completed = false, completed_at = NULL, archived_at = NULL, status_id = "+storage.StatusAfterSQL("false", "tasks.project_id")+", date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $1 AND user_id = $2 AND completed = true", parentID, userID)
+
+	renderTaskRow(w, r, parentID, userID, timezone)
+}
+
+// APIMoveSubtask moves a subtask one slot up or down among its siblings.
+func APIMoveSubtask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	direction := r.FormValue("direction")
+	if direction != "up" && direction != "down" {
+		http.Error(w, "Invalid direction", http.StatusBadRequest)
+		return
+	}
+
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	db, err := storage.OpenDatabase()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	var parentID sql.NullInt64
+	err = db.QueryRow(context.Background(), "SELECT parent_id FROM tasks WHERE id = $1 AND user_id = $2", id, userID).Scan(&parentID)
+	if err != nil || !parentID.Valid {
+		http.Error(w, "Subtask not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := db.Query(context.Background(), "SELECT id FROM tasks WHERE parent_id = $1 AND user_id = $2 AND deleted_at IS NULL ORDER BY position ASC, id ASC", parentID.Int64, userID)
+	if err != nil {
+		http.Error(w, "Error fetching subtasks", http.StatusInternalServerError)
+		return
+	}
+	siblings := make([]int, 0)
+	for rows.Next() {
+		var sid int
+		if err := rows.Scan(&sid); err != nil {
+			rows.Close()
+			http.Error(w, "Error reading subtasks", http.StatusInternalServerError)
+			return
+		}
+		siblings = append(siblings, sid)
+	}
+	rows.Close()
+
+	idx := -1
+	for i, sid := range siblings {
+		if sid == id {
+			idx = i
+			break
+		}
+	}
+	swapWith := idx - 1
+	if direction == "down" {
+		swapWith = idx + 1
+	}
+	if idx >= 0 && swapWith >= 0 && swapWith < len(siblings) {
+		siblings[idx], siblings[swapWith] = siblings[swapWith], siblings[idx]
+
+		// Renumber every sibling so positions stay dense
+		tx, err := db.Begin(context.Background())
+		if err != nil {
+			http.Error(w, "Error starting transaction", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(context.Background())
+		for i, sid := range siblings {
+			if _, err := tx.Exec(context.Background(), "UPDATE tasks SET position = $1 WHERE id = $2 AND user_id = $3", i+1, sid, userID); err != nil {
+				http.Error(w, "Error updating positions", http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(context.Background()); err != nil {
+			http.Error(w, "Error committing position updates", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	renderTaskRow(w, r, int(parentID.Int64), userID, timezone)
+}
+
+// APICompleteSubtasks marks every subtask of a task as complete, recording revisions and
+// offering undo like completing a single subtask does.
+func APICompleteSubtasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parentID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := lookupParentTask(parentID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Invalid task", http.StatusBadRequest)
+		return
+	}
+
+	db, err := storage.OpenDatabase()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+
+	rows, err := db.Query(context.Background(), "SELECT id FROM tasks WHERE parent_id = $1 AND user_id = $2 AND deleted_at IS NULL AND (completed IS NULL OR completed = false) ORDER BY position, id", parentID, userID)
+	if err != nil {
+		http.Error(w, "Failed to complete subtasks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	openIDs := make([]int, 0)
+	for rows.Next() {
+		var tid int
+		if err := rows.Scan(&tid); err != nil {
+			http.Error(w, "Failed to complete subtasks", http.StatusInternalServerError)
+			return
+		}
+		openIDs = append(openIDs, tid)
+	}
+	if rows.Err() != nil {
+		http.Error(w, "Failed to complete subtasks", http.StatusInternalServerError)
+		return
+	}
+	if len(openIDs) == 0 {
+		renderTaskRow(w, r, parentID, userID, timezone)
+		return
+	}
+
+	// Remember the prior state of the subtasks for undo and their revision history
+	undoState, undoErr := storage.SnapshotTasks(userID, openIDs)
+	revisionsBefore := make(map[int]storage.RevisionState, len(openIDs))
+	for _, tid := range openIDs {
+		if state, err := storage.GetRevisionState(tid, userID); err == nil {
+			revisionsBefore[tid] = state
+		}
+	}
+
+	_, err = db.Exec(context.Background(), `UPDATE tasks SET completed = true, completed_at = NOW() AT TIME ZONE 'UTC',
+		archived_at = NULL, date_modified = NOW() AT TIME ZONE 'UTC'
+		WHERE id = ANY($1) AND user_id = $2 AND (completed IS NULL OR completed = false)`, openIDs, userID)
+	if err != nil {
+		http.Error(w, "Failed to complete subtasks", http.StatusInternalServerError)
+		return
+	}
+
+	for tid, state := range revisionsBefore {
+		if err := storage.RecordTaskRevision(tid, userID, state); err != nil {
+			fmt.Printf("Error recording revision for task %d: %v\n", tid, err)
+		}
+	}
+	if undoErr == nil {
+		page, _ := strconv.Atoi(r.FormValue("page"))
+		offerUndo(w, userID, "Subtasks completed", storage.UndoRecord{Kind: "complete", Tasks: undoState}, page, r.FormValue("project"))
+	}
+
+	renderTaskRow(w, r, parentID, userID, timezone)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const MaxTagNameLength = 30
+
+// TagsPageHandler shows the user's tags and a simple create form.
+func TagsPageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, loggedIn := utils.GetSessionUser(r)
+	if !loggedIn {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	tags, err := storage.GetTagsForUser(*uidPtr)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	ctx := map[string]interface{}{
+		"LoggedIn":     loggedIn,
+		"Tags":         tags,
+		"DefaultColor": storage.DefaultTagColor,
+	}
+	utils.RenderTemplate(w, r, "tags.html", ctx)
+}
+
+// validateTagForm reads the tag name and color from the form. When the returned
+// message is not empty it should be shown as a validation error.
+func validateTagForm(r *http.Request) (name, color, msg string) {
+	name = strings.TrimSpace(r.FormValue("name"))
+	color = strings.TrimSpace(r.FormValue("color"))
+	if name == "" {
+		return name, color, "Tag name is required"
+	}
+	if len(name) > MaxTagNameLength {
+		return name, color, fmt.Sprintf("Tag name must be %d characters or less", MaxTagNameLength)
+	}
+	if !storage.IsValidTagColor(color) {
+		return name, color, "Tag color must be a hex color like #0d6efd"
+	}
+	return name, color, ""
+}
+
+// renderTagsList returns the updated tag list fragment for the management page.
+func renderTagsList(w http.ResponseWriter, r *http.Request, userID int) {
+	tags, err := storage.GetTagsForUser(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching tags: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx := map[string]interface{}{
+		"Tags": tags,
+	}
+	// Notify client that tags changed so filters can refresh
+	w.Header().Set("HX-Trigger", "tags-changed")
+	utils.RenderTemplate(w, r, "tags_list.html", ctx)
+}
+
+// APICreateTag handles creating a new tag for the logged-in user.
+func APICreateTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		http.Redirect(w, r, "/", http.StatusUnauthorized)
+		return
+	}
+
+	name, color, msg := validateTagForm(r)
+	if msg != "" {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Trigger", "tag-name-error")
+		w.Header().Set("HX-Retarget", "#tag-name-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, msg)
+		return
+	}
+
+	if _, err := storage.CreateTag(*uidPtr, name, color); err != nil {
+		w.Header().Set("X-Validation-Error", "true")
+		w.Header().Set("HX-Retarget", "#tag-name-error")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "A tag with that name already exists")
+		return
+	}
+
+	renderTagsList(w, r, *uidPtr)
+}
+
+// APIUpdateTag renames or recolors a tag owned by the logged-in user.
+func APIUpdateTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		http.Redirect(w, r, "/", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid tag id", http.StatusBadRequest)
+		return
+	}
+	name, color, msg := validateTagForm(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// Ownership enforced in storage layer
+	if err := storage.UpdateTag(id, *uidPtr, name, color); err != nil {
+		http.Error(w, "Failed to update tag (is the name already used?)", http.StatusConflict)
+		return
+	}
+
+	renderTagsList(w, r, *uidPtr)
+}
+
+// APIDeleteTag deletes a tag owned by the logged-in user.
+func APIDeleteTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		http.Redirect(w, r, "/", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid tag id", http.StatusBadRequest)
+		return
+	}
+
+	// Delete the tag (ownership enforced in storage layer)
+	if err := storage.DeleteTag(id, *uidPtr); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	renderTagsList(w, r, *uidPtr)
+}
+
+// APITagOptions renders the tag checkboxes for the add/edit task form. When task_id is
+// given, the task's current tags are pre-checked.
+func APITagOptions(w http.ResponseWriter, r *http.Request) {
+	uidPtr := utils.GetSessionUserID(r)
+	if uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	tags, err := storage.GetTagsForUser(*uidPtr)
+	if err != nil {
+		http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+
+	assigned := map[int]bool{}
+	if taskID, err := strconv.Atoi(r.URL.Query().Get("task_id")); err == nil {
+		ids, err := storage.GetTagIDsForTask(taskID, *uidPtr)
+		if err != nil {
+			http.Error(w, "Failed to fetch task tags", http.StatusInternalServerError)
+			return
+		}
+		for _, id := range ids {
+			assigned[id] = true
+		}
+	}
+
+	options := make([]map[string]interface{}, 0, len(tags))
+	for _, t := range tags {
+		options = append(options, map[string]interface{}{"Tag": t, "Checked": assigned[t.ID]})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := utils.Templates.ExecuteTemplate(w, "tag_options.html", map[string]interface{}{"Options": options}); err != nil {
+		http.Error(w, "Error rendering tag options: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// formTagIDs returns the tag ids checked in the task form, and whether the tag
+// options were part of the form at all (they load lazily).
+func formTagIDs(r *http.Request) ([]int, bool) {
+	if r.FormValue("tags_submitted") == "" {
+		return nil, false
+	}
+	ids := make([]int, 0)
+	for _, v := range r.Form["tag_ids"] {
+		if id, err := strconv.Atoi(v); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
+
+// parseTaskFilter reads the list tag filter: repeated or comma-separated "tags"
+// values plus "tag_mode" (any or all), and "deferred=1" for the snoozed tasks view.
+func parseTaskFilter(r *http.Request) tasks.TaskFilter {
+	_ = r.ParseForm()
+	var f tasks.TaskFilter
+	seen := map[int]bool{}
+	for _, v := range r.Form["tags"] {
+		for _, part := range strings.Split(v, ",") {
+			if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && !seen[id] {
+				seen[id] = true
+				f.TagIDs = append(f.TagIDs, id)
+			}
+		}
+	}
+	f.MatchAllTags = r.FormValue("tag_mode") == "all"
+	f.Deferred = r.FormValue("deferred") == "1"
+	if view := r.FormValue("view"); tasks.IsSmartView(view) {
+		f.View = view
+	}
+	_, _, _, timezone, _, _ := utils.GetSessionUserWithTimezone(r)
+	f.Today = tasks.LocalToday(timezone)
+	return f
+}
+
+// tagFilterParam serializes the tag filter ids for pagination links.
+func tagFilterParam(f tasks.TaskFilter) string {
+	ids := make([]string, 0, len(f.TagIDs))
+	for _, id := range f.TagIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return strings.Join(ids, ",")
+}
+
+// deferredParam serializes the Deferred view flag for pagination links.
+func deferredParam(f tasks.TaskFilter) string {
+	if f.Deferred {
+		return "1"
+	}
+	return ""
+}
+
+// tagFilterOptions lists the user's tags for the toolbar filter, marking selected ones.
+func tagFilterOptions(userID int, f tasks.TaskFilter) []map[string]interface{} {
+	selected := map[int]bool{}
+	for _, id := range f.TagIDs {
+		selected[id] = true
+	}
+	out := make([]map[string]interface{}, 0)
+	if tags, err := storage.GetTagsForUser(userID); err == nil {
+		for _, t := range tags {
+			out = append(out, map[string]interface{}{"ID": t.ID, "Name": t.Name, "Color": t.Color, "TextClass": t.TextClass(), "Selected": selected[t.ID]})
+		}
+	}
+	return out
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MaxTemplateNameLength = 100
+
+// templatesContext builds the template data shared by templates.html and templates_list.html.
+func templatesContext(userID int, timezone string) (map[string]interface{}, error) {
+	list, err := storage.GetTemplates(userID, timezone)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := storage.GetProjectsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Templates": list,
+		"Projects":  projects,
+		"Today":     tasks.LocalToday(timezone),
+	}, nil
+}
+
+// renderTemplatesList renders the template list fragment.
+func renderTemplatesList(w http.ResponseWriter, r *http.Request, userID int, timezone string) {
+	ctx, err := templatesContext(userID, timezone)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+	utils.RenderTemplate(w, r, "templates_list.html", ctx)
+}
+
+// templateName reads the name of a new template: the hx-prompt answer, or a name field.
+// It returns a message for the user when the name is missing or too long.
+func templateName(r *http.Request) (string, string) {
+	name := strings.TrimSpace(r.Header.Get("HX-Prompt"))
+	if name == "" {
+		name = strings.TrimSpace(r.FormValue("name"))
+	}
+	if name == "" {
+		return "", "Template name is required"
+	}
+	if len(name) > MaxTemplateNameLength {
+		return "", fmt.Sprintf("Template name must be %d characters or less", MaxTemplateNameLength)
+	}
+	return name, ""
+}
+
+// TemplatesPageHandler shows the user's task and project templates.
+func TemplatesPageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	ctx, err := templatesContext(*uidPtr, timezone)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx["LoggedIn"] = loggedIn
+	utils.RenderTemplate(w, r, "templates.html", ctx)
+}
+
+// saveTemplate saves a task (kind task, id in id) or a project (kind project, id in
+// project_id) as a template named by the user. The result is shown as a toast.
+func saveTemplate(w http.ResponseWriter, r *http.Request, kind string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	field := "id"
+	if kind == storage.TemplateKindProject {
+		field = "project_id"
+	}
+	id, err := strconv.Atoi(r.FormValue(field))
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("HX-Reswap", "none")
+	name, msg := templateName(r)
+	if msg != "" {
+		triggerToast(w, msg, true)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if kind == storage.TemplateKindProject {
+		_, err = storage.SaveProjectAsTemplate(id, userID, name)
+	} else {
+		_, err = storage.SaveTaskAsTemplate(id, userID, name)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTemplateExists):
+			triggerToast(w, "A template with that name already exists", true)
+		case errors.Is(err, storage.ErrTemplateSourceNotFound):
+			triggerToast(w, "There is nothing to save as a template", true)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to save template: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	triggerToast(w, "Saved as template \""+name+"\"", false)
+	w.WriteHeader(http.StatusOK)
+}
+
+// APISaveTaskTemplate saves a top-level task and its subtasks as a template.
+func APISaveTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	saveTemplate(w, r, storage.TemplateKindTask)
+}
+
+// APISaveProjectTemplate saves a project and its tasks as a template.
+func APISaveProjectTemplate(w http.ResponseWriter, r *http.Request) {
+	saveTemplate(w, r, storage.TemplateKindProject)
+}
+
+// APIUseTemplate creates the tasks of a template with due dates shifted to start on the
+// chosen date, then takes the user to the list showing them. Project templates create a
+// new project named project_name; task templates go into project_id when set.
+func APIUseTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("HX-Reswap", "none")
+	start := strings.TrimSpace(r.FormValue("start"))
+	if start == "" {
+		start = tasks.LocalToday(timezone)
+	} else if _, err := time.Parse("2006-01-02", start); err != nil {
+		triggerToast(w, "Pick a valid start date", true)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	projectName := strings.TrimSpace(r.FormValue("project_name"))
+	if len(projectName) > MaxProjectNameLength {
+		triggerToast(w, fmt.Sprintf("Project name must be %d characters or less", MaxProjectNameLength), true)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	projectID, created, err := storage.InstantiateTemplate(id, userID, start, projectName, parseProjectFilter(r.FormValue("project_id")))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTemplateNotFound):
+			http.Error(w, "Template not found.", http.StatusNotFound)
+		case errors.Is(err, storage.ErrTemplateProjectNotFound):
+			triggerToast(w, "Project not found", true)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to use template: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	plural := "s"
+	if created == 1 {
+		plural = ""
+	}
+	utils.SetFlash(w, r, fmt.Sprintf("Created %d task%s from the template.", created, plural))
+	target := utils.GetBasePath() + "/"
+	if projectID != 0 {
+		target += "?project=" + strconv.Itoa(projectID)
+	}
+	w.Header().Set("HX-Redirect", target)
+	w.WriteHeader(http.StatusOK)
+}
+
+// APIDeleteTemplate deletes a template and re-renders the template list.
+func APIDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+	if err := storage.DeleteTemplate(id, userID); err != nil {
+		if errors.Is(err, storage.ErrTemplateNotFound) {
+			http.Error(w, "Template not found.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete template: %v", err), http.StatusInternalServerError)
+		return
+	}
+	triggerToast(w, "Template deleted", false)
+	renderTemplatesList(w, r, userID, timezone)
+}
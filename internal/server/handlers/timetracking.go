@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxTimeNoteLength caps the note of a manual time entry.
+const MaxTimeNoteLength = 200
+
+// maxReportDays bounds the date range of a time report.
+const maxReportDays = 366
+
+// triggerReloadPage asks the client to reload the task list, used when a change
+// affects rows other than the one being re-rendered.
+func triggerReloadPage(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": r.FormValue("project")})
+}
+
+// APIStartTimer starts a timer on a task, stopping any other running timer of the user.
+func APIStartTimer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(r.FormValue("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	stoppedTaskID, err := storage.StartTimer(taskID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTimeEntryNotFound) {
+			http.Error(w, "Task not found.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to start timer: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// The row of the previously timed task shows a stale timer otherwise
+	if stoppedTaskID != 0 {
+		triggerReloadPage(w, r)
+	}
+	renderTaskRowWith(w, r, taskID, userID, timezone, taskRow{})
+}
+
+// APIStopTimer stops the user's running timer and re-renders the task row.
+func APIStopTimer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(r.FormValue("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	stoppedTaskID, err := storage.StopTimer(userID)
+	if err != nil && !errors.Is(err, storage.ErrNoRunningTimer) {
+		http.Error(w, fmt.Sprintf("Failed to stop timer: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if stoppedTaskID != 0 && stoppedTaskID != taskID {
+		triggerReloadPage(w, r)
+	}
+	renderTaskRowWith(w, r, taskID, userID, timezone, taskRow{})
+}
+
+// renderTimeLog renders the time entries of a task with its total.
+func renderTimeLog(w http.ResponseWriter, taskID, userID int, timezone string) {
+	entries, err := storage.GetTimeEntries(taskID, userID, timezone)
+	if err != nil {
+		http.Error(w, "Failed to fetch time entries", http.StatusInternalServerError)
+		return
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Seconds
+	}
+
+	type entryView struct {
+		storage.TimeEntry
+		DurationLabel string
+	}
+	views := make([]entryView, 0, len(entries))
+	for _, e := range entries {
+		views = append(views, entryView{TimeEntry: e, DurationLabel: tasks.FormatDuration(e.Seconds)})
+	}
+
+	totalLabel := ""
+	if len(entries) > 0 {
+		totalLabel = tasks.FormatDuration(total)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := map[string]interface{}{
+		"TaskID":     taskID,
+		"Entries":    views,
+		"TotalLabel": totalLabel,
+		"Today":      time.Now().In(tasks.UserLocation(timezone)).Format("2006-01-02"),
+	}
+	if err := utils.Templates.ExecuteTemplate(w, "task_time.html", data); err != nil {
+		http.Error(w, "Error rendering time entries: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// APITimeEntries renders the time log of a task.
+func APITimeEntries(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	renderTimeLog(w, taskID, userID, timezone)
+}
+
+// APIAddTimeEntry logs time on a task by hand. The entry starts at midnight of the
+// chosen day in the user's timezone, so it is reported under that day.
+func APIAddTimeEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	taskID, err := strconv.Atoi(r.FormValue("task_id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	refuse := func(msg string) {
+		triggerToast(w, msg, true)
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusOK)
+	}
+	duration, err := tasks.ParseDurationInput(r.FormValue("duration"))
+	if err != nil {
+		refuse("Invalid duration: " + err.Error())
+		return
+	}
+	loc := tasks.UserLocation(timezone)
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(r.FormValue("date")), loc)
+	if err != nil {
+		refuse("Choose the day the time was spent")
+		return
+	}
+	if day.After(time.Now().In(loc)) {
+		refuse("Time cannot be logged on a future day")
+		return
+	}
+	note := strings.TrimSpace(r.FormValue("note"))
+	if len(note) > MaxTimeNoteLength {
+		refuse(fmt.Sprintf("Notes must be %d characters or less", MaxTimeNoteLength))
+		return
+	}
+
+	if err := storage.AddManualTimeEntry(taskID, userID, day, duration, note); err != nil {
+		if errors.Is(err, storage.ErrTimeEntryNotFound) {
+			http.Error(w, "Task not found.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to add time entry: %v", err), http.StatusInternalServerError)
+		return
+	}
+	renderTimeLog(w, taskID, userID, timezone)
+}
+
+// APIDeleteTimeEntry removes a time entry and re-renders the time log.
+func APIDeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid time entry id", http.StatusBadRequest)
+		return
+	}
+	taskID, err := storage.DeleteTimeEntry(id, userID)
+	if err != nil {
+		http.Error(w, "Time entry not found.", http.StatusNotFound)
+		return
+	}
+	renderTimeLog(w, taskID, userID, timezone)
+}
+
+// timeReportRange reads the from/to dates of a report, defaulting to the current month
+// so far in the user's timezone.
+func timeReportRange(r *http.Request, timezone string) (from, to string, err error) {
+	now := time.Now().In(tasks.UserLocation(timezone))
+	from = strings.TrimSpace(r.URL.Query().Get("from"))
+	to = strings.TrimSpace(r.URL.Query().Get("to"))
+	if from == "" {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+	}
+	if to == "" {
+		to = now.Format("2006-01-02")
+	}
+	fromDay, err1 := time.Parse("2006-01-02", from)
+	toDay, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		return from, to, errors.New("dates must look like 2025-01-31")
+	}
+	if toDay.Before(fromDay) {
+		return from, to, errors.New("the start date must not be after the end date")
+	}
+	if toDay.Sub(fromDay) > maxReportDays*24*time.Hour {
+		return from, to, fmt.Errorf("reports can cover at most %d days", maxReportDays)
+	}
+	return from, to, nil
+}
+
+// reportProjectName labels the tasks without a project in reports.
+func reportProjectName(row storage.TimeReportRow) string {
+	if row.ProjectID == 0 {
+		return "No project"
+	}
+	return row.ProjectName
+}
+
+// TimeReportPageHandler shows the time logged by project and by day over a date range.
+func TimeReportPageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	from, to, rangeErr := timeReportRange(r, timezone)
+	rows := make([]storage.TimeReportRow, 0)
+	if rangeErr == nil {
+		var err error
+		rows, err = storage.GetTimeReport(*uidPtr, from, to, timezone)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error building time report: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type total struct {
+		Name  string
+		Label string
+	}
+	type dayGroup struct {
+		Day      string
+		Label    string
+		Projects []total
+	}
+	byProject := make(map[string]int64)
+	days := make([]dayGroup, 0)
+	var grand int64
+	for _, row := range rows {
+		name := reportProjectName(row)
+		byProject[name] += row.Seconds
+		grand += row.Seconds
+		if len(days) == 0 || days[len(days)-1].Day != row.Day {
+			days = append(days, dayGroup{Day: row.Day})
+		}
+		days[len(days)-1].Projects = append(days[len(days)-1].Projects, total{Name: name, Label: tasks.FormatDuration(row.Seconds)})
+	}
+	for i := range days {
+		var secs int64
+		for _, row := range rows {
+			if row.Day == days[i].Day {
+				secs += row.Seconds
+			}
+		}
+		days[i].Label = tasks.FormatDuration(secs)
+	}
+	projects := make([]total, 0, len(byProject))
+	names := make([]string, 0, len(byProject))
+	for name := range byProject {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		projects = append(projects, total{Name: name, Label: tasks.FormatDuration(byProject[name])})
+	}
+
+	rangeMsg := ""
+	if rangeErr != nil {
+		rangeMsg = rangeErr.Error()
+	}
+	ctx := map[string]interface{}{
+		"LoggedIn":   loggedIn,
+		"From":       from,
+		"To":         to,
+		"RangeError": rangeMsg,
+		"Timezone":   timezone,
+		"ByProject":  projects,
+		"ByDay":      days,
+		"TotalLabel": tasks.FormatDuration(grand),
+	}
+	utils.RenderTemplate(w, r, "time_report.html", ctx)
+}
+
+// TimeReportCSVHandler exports the time report as CSV with one line per day and project.
+func TimeReportCSVHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	from, to, err := timeReportRange(r, timezone)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := storage.GetTimeReport(*uidPtr, from, to, timezone)
+	if err != nil {
+		http.Error(w, "Error building time report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="time-report-%s-to-%s.csv"`, from, to))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "project", "minutes", "hours"})
+	for _, row := range rows {
+		_ = cw.Write([]string{row.Day, csvSafe(reportProjectName(row)), strconv.FormatInt(row.Seconds/60, 10), tasks.FormatHours(row.Seconds)})
+	}
+	cw.Flush()
+}
+
+// csvSafe keeps spreadsheet programs from treating a user-supplied cell as a formula.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
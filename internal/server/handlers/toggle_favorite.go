@@ -121,9 +121,9 @@ func APIToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	var taskList []tasks.Task
 	var totalTasks int
 	if projectFilter != nil {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUserWithProject(page, pageSize, userPtr, timezone, projectFilter)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userPtr, timezone, projectFilter, sessionTaskOrder(r))
 	} else {
-		taskList, totalTasks, err = tasks.ReturnPaginationForUser(page, pageSize, userPtr, timezone)
+		taskList, totalTasks, err = tasks.ReturnPaginationForUserFiltered(page, pageSize, userPtr, timezone, nil, sessionTaskOrder(r))
 	}
 	if err != nil {
 		http.Error(w, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
@@ -149,24 +149,7 @@ func APIToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	completedCount := pagination.TotalCompletedTasks
 	incompleteCount := pagination.TotalIncompleteTasks
 	if projectFilter != nil {
-		pool, err := storage.OpenDatabase()
-		if err == nil {
-			defer storage.CloseDatabase(pool)
-			projectCond := ""
-			args := []interface{}{uid}
-			if *projectFilter == 0 {
-				projectCond = " AND project_id IS NULL"
-			} else {
-				projectCond = " AND project_id = $2"
-				args = append(args, *projectFilter)
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&completedCount); err != nil {
-				completedCount = 0
-			}
-			if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&incompleteCount); err != nil {
-				incompleteCount = 0
-			}
-		}
+		completedCount, incompleteCount, _ = storage.CountTasksByDone(uid, projectFilter)
 	}
 
 	// Fetch projects for user and mark selected
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"GoTodo/internal/attachments"
+	"GoTodo/internal/config"
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// trashRow is a trashed task with the days left before it is purged.
+type trashRow struct {
+	storage.TrashedTask
+	DaysLeft int
+}
+
+// trashContext builds the template data shared by trash.html and trash_list.html.
+func trashContext(userID int, timezone string) (map[string]interface{}, error) {
+	list, err := storage.GetTrash(userID, timezone)
+	if err != nil {
+		return nil, err
+	}
+	retention := config.Cfg.TrashRetentionDays
+	rows := make([]trashRow, 0, len(list))
+	for _, t := range list {
+		left := time.Until(t.DeletedAt.Add(time.Duration(retention) * 24 * time.Hour))
+		rows = append(rows, trashRow{TrashedTask: t, DaysLeft: max(int(left.Hours()/24), 0)})
+	}
+	return map[string]interface{}{
+		"Trash":         rows,
+		"RetentionDays": retention,
+	}, nil
+}
+
+// renderTrashList re-renders the trash list fragment after a change.
+func renderTrashList(w http.ResponseWriter, r *http.Request, userID int, timezone string) {
+	ctx, err := trashContext(userID, timezone)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching trash: %v", err), http.StatusInternalServerError)
+		return
+	}
+	utils.RenderTemplate(w, r, "trash_list.html", ctx)
+}
+
+// TrashPageHandler shows the user's trashed tasks with restore and permanent delete actions.
+func TrashPageHandler(w http.ResponseWriter, r *http.Request) {
+	_, _, _, timezone, loggedIn, _ := utils.GetSessionUserWithTimezone(r)
+	uidPtr := utils.GetSessionUserID(r)
+	if !loggedIn || uidPtr == nil {
+		utils.SetFlash(w, r, "You don't have permission to access this.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	ctx, err := trashContext(*uidPtr, timezone)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching trash: %v", err), http.StatusInternalServerError)
+		return
+	}
+	ctx["LoggedIn"] = loggedIn
+	utils.RenderTemplate(w, r, "trash.html", ctx)
+}
+
+// APIRestoreTask takes a task out of the trash.
+func APIRestoreTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	if err := storage.RestoreTask(id, userID); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrParentInTrash):
+			triggerToast(w, "Restore its parent task first", true)
+			w.Header().Set("HX-Reswap", "none")
+			w.WriteHeader(http.StatusOK)
+		case errors.Is(err, storage.ErrTrashedTaskNotFound):
+			http.Error(w, "Task not found in trash.", http.StatusNotFound)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to restore task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	triggerToast(w, "Task restored", false)
+	renderTrashList(w, r, userID, timezone)
+}
+
+// APIPurgeTask permanently deletes a task from the trash along with its attachment files.
+func APIPurgeTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := storage.PurgeTrashedTask(id, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTrashedTaskNotFound) {
+			http.Error(w, "Task not found in trash.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete task: %v", err), http.StatusInternalServerError)
+		return
+	}
+	attachments.DeleteFiles(keys)
+	renderTrashList(w, r, userID, timezone)
+}
+
+// APIEmptyTrash permanently deletes everything in the user's trash.
+func APIEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := storage.EmptyTrash(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to empty trash: %v", err), http.StatusInternalServerError)
+		return
+	}
+	attachments.DeleteFiles(keys)
+	renderTrashList(w, r, userID, timezone)
+}
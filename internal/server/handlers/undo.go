@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"GoTodo/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// addTrigger adds an event to the HX-Trigger header, keeping events already set on it.
+func addTrigger(w http.ResponseWriter, name string, detail interface{}) {
+	events := make(map[string]interface{})
+	if cur := w.Header().Get("HX-Trigger"); cur != "" {
+		if err := json.Unmarshal([]byte(cur), &events); err != nil {
+			// A plain list of event names
+			for _, ev := range strings.FieldsFunc(cur, func(r rune) bool { return r == ',' || r == ' ' }) {
+				events[ev] = nil
+			}
+		}
+	}
+	events[name] = detail
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return
+	}
+	w.Header().Set("HX-Trigger", string(payload))
+}
+
+// offerUndo records rec as the user's latest undoable action and shows a toast with an
+// Undo button. page and project tell the client which list page to reload on undo.
+// A failure to record is logged and the toast is shown without the button.
+func offerUndo(w http.ResponseWriter, userID int, message string, rec storage.UndoRecord, page int, project string) {
+	token, err := storage.SaveUndo(userID, rec)
+	if err != nil {
+		fmt.Printf("Undo: %v\n", err)
+		addTrigger(w, "show-toast", map[string]interface{}{"message": message, "error": false})
+		return
+	}
+	addTrigger(w, "show-toast", map[string]interface{}{
+		"message": message,
+		"error":   false,
+		"undo": map[string]interface{}{
+			"token":   token,
+			"page":    max(page, 1),
+			"project": project,
+			"seconds": int(storage.UndoWindow.Seconds()),
+		},
+	})
+}
+
+// APIUndo reverts the user's latest undoable action and reloads the affected list page.
+func APIUndo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	token := strings.TrimSpace(r.FormValue("token"))
+	page, _ := strconv.Atoi(r.FormValue("page"))
+
+	w.Header().Set("HX-Reswap", "none")
+	if _, err := storage.ApplyUndo(userID, token); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUndoExpired):
+			triggerToast(w, "Too late to undo that", true)
+		case errors.Is(err, storage.ErrParentInTrash):
+			triggerToast(w, "Could not undo: the parent task is in the trash", true)
+		default:
+			fmt.Printf("Undo: %v\n", err)
+			triggerToast(w, "Could not undo that", true)
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	triggerToast(w, "Undone", false)
+	addTrigger(w, "reloadPage", map[string]interface{}{"page": max(page, 1), "project": r.FormValue("project")})
+	w.WriteHeader(http.StatusOK)
+}
@@ -11,8 +11,15 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// APIUpdateTaskStatus moves a task to the workflow status given by status, or, without
+// one, toggles it between done and open: a top-level task goes to its workflow's done
+// status or back to the first open status. Completion follows the done status. It
+// re-renders the task row.
 func APIUpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 	if id == "" {
@@ -45,21 +52,6 @@ func APIUpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 
 	defer db.Close()
 
-	var completed bool
-
-	// Ensure task exists and belongs to the current user
-	var ownerID int
-	err = db.QueryRow(context.Background(), "SELECT completed, user_id FROM tasks WHERE id = $1", id).Scan(&completed, &ownerID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Task not found.", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Task not found.", http.StatusInternalServerError)
-		return
-	}
-
-	// Verify ownership
 	var userID int
 	if uid := utils.GetSessionUserID(r); uid != nil {
 		userID = *uid
@@ -70,8 +62,19 @@ func APIUpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	if ownerID != userID {
-		http.Error(w, "Not authorized to update this task.", http.StatusForbidden)
+
+	var completed bool
+	var parentID sql.NullInt64
+	var taskProjectID int
+
+	// Ensure the task exists, belongs to the current user and is not in the trash
+	err = db.QueryRow(context.Background(), "SELECT COALESCE(completed, false), parent_id, COALESCE(project_id, 0) FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL", id, userID).Scan(&completed, &parentID, &taskProjectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Task not found.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load task.", http.StatusInternalServerError)
 		return
 	}
 
@@ -88,79 +91,164 @@ func APIUpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Work out the new status. Subtasks have no workflow and only toggle completion.
 	updatedStatus := !completed
+	var newStatus *storage.TaskStatus
+	statusParam := r.URL.Query().Get("status")
+	if !parentID.Valid {
+		workflow, _, err := storage.GetWorkflow(userID, taskProjectID)
+		if err != nil {
+			http.Error(w, "Failed to load workflow.", http.StatusInternalServerError)
+			return
+		}
+		if statusParam != "" {
+			statusID, err := strconv.Atoi(statusParam)
+			if err != nil {
+				http.Error(w, "Invalid status", http.StatusBadRequest)
+				return
+			}
+			for i := range workflow {
+				if workflow[i].ID == statusID {
+					newStatus = &workflow[i]
+				}
+			}
+			if newStatus == nil {
+				http.Error(w, "Status not found.", http.StatusNotFound)
+				return
+			}
+			updatedStatus = newStatus.IsDone
+		} else {
+			s := storage.ResolveStatus(workflow, 0, updatedStatus)
+			newStatus = &s
+		}
+	} else if statusParam != "" {
+		http.Error(w, "Subtasks have no status", http.StatusBadRequest)
+		return
+	}
+	var newStatusID *int
+	if newStatus != nil && newStatus.ID != 0 {
+		newStatusID = &newStatus.ID
+	}
 
-	_, err = db.Exec(context.Background(), "UPDATE tasks SET completed = $1, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $2", updatedStatus, id)
+	// A task cannot be completed while tasks it is blocked by are still open
+	if updatedStatus && !completed && !parentID.Valid {
+		taskID, _ := strconv.Atoi(id)
+		blockers, err := storage.GetOpenBlockers(taskID, userID)
+		if err != nil {
+			http.Error(w, "Failed to check task dependencies.", http.StatusInternalServerError)
+			return
+		}
+		if len(blockers) > 0 {
+			titles := make([]string, 0, len(blockers))
+			for _, b := range blockers {
+				titles = append(titles, b.Title)
+			}
+			triggerToast(w, fmt.Sprintf("Blocked by %s. Complete those tasks first.", strings.Join(titles, ", ")), true)
+			w.Header().Set("HX-Reswap", "none")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	// Remember the prior state of the task (and of a parent a subtask may reopen) for undo
+	taskIDNum, _ := strconv.Atoi(id)
+	undoIDs := []int{taskIDNum}
+	if parentID.Valid {
+		undoIDs = append(undoIDs, int(parentID.Int64))
+	}
+	undoState, undoErr := storage.SnapshotTasks(userID, undoIDs)
+	// Tracked fields of the same tasks, for their revision history
+	revisionsBefore := make(map[int]storage.RevisionState, len(undoIDs))
+	for _, tid := range undoIDs {
+		if state, err := storage.GetRevisionState(tid, userID); err == nil {
+			revisionsBefore[tid] = state
+		}
+	}
+
+	_, err = db.Exec(context.Background(), `UPDATE tasks SET completed = $1,
+		completed_at = CASE WHEN $1 THEN COALESCE(CASE WHEN completed THEN completed_at END, NOW() AT TIME ZONE 'UTC') END,
+		archived_at = CASE WHEN $1 = completed THEN archived_at END, status_id = COALESCE(CAST($3 AS INTEGER), status_id),
+		date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $2`, updatedStatus, id, newStatusID)
 
 	if err != nil {
 		http.Error(w, "Failed to update task status.", http.StatusInternalServerError)
 		return
 	}
 
-	// Fetch updated task data to render the complete row with updated timestamps
+	// Reopening a subtask reopens its parent as well, since the parent is no longer done
+	if parentID.Valid && !updatedStatus {
+		_, err = db.Exec(context.Background(), "UPDATE tasks SET completed = false, completed_at = NULL, archived_at = NULL, status_id = "+storage.StatusAfterSQL("false", "tasks.project_id")+", date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $1 AND user_id = $2 AND completed = true", parentID.Int64, userID)
+		if err != nil {
+			http.Error(w, "Failed to update parent task.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for tid, state := range revisionsBefore {
+		if err := storage.RecordTaskRevision(tid, userID, state); err != nil {
+			fmt.Printf("Error recording revision for task %d: %v\n", tid, err)
+		}
+	}
+
 	email, _, _, timezone, _, _ := utils.GetSessionUserWithTimezone(r)
-	var task tasks.Task
-	var projectID sql.NullInt64
-	var projectName sql.NullString
-	err = db.QueryRow(context.Background(),
-		`SELECT t.id, t.title, t.description, t.completed, 
-			TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_added,
-			COALESCE(CAST(t.due_date AS TEXT), '') AS due_date,
-			TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_created,
-			COALESCE(TO_CHAR((t.date_modified AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM'), '') AS date_modified,
-			COALESCE(t.is_favorite,false), COALESCE(t.position,0), t.project_id, COALESCE(p.name,'')
-		FROM tasks t LEFT JOIN projects p ON t.project_id = p.id 
-		WHERE t.id = $1`, id, timezone).Scan(
-		&task.ID, &task.Title, &task.Description, &task.Completed,
-		&task.DateAdded, &task.DueDate, &task.DateCreated, &task.DateModified,
-		&task.IsFavorite, &task.Position, &projectID, &projectName)
+	rowID, _ := strconv.Atoi(id)
+
+	// Completing a recurring task schedules its next occurrence
+	nextOccurrenceID := 0
+	if updatedStatus && !completed && !parentID.Valid {
+		nextOccurrenceID, err = tasks.CreateNextOccurrence(rowID, userID, timezone)
+		if err != nil {
+			fmt.Printf("Error creating next occurrence for task %d: %v\n", rowID, err)
+		}
+	}
 
+	// Fetch updated task data to render the complete row with updated timestamps.
+	// Subtasks live inside their parent's row, so a subtask toggle re-renders the parent.
+	if parentID.Valid {
+		rowID = int(parentID.Int64)
+	}
+	taskPtr, err := tasks.ReturnTaskForUser(rowID, userID, timezone)
 	if err != nil {
 		http.Error(w, "Failed to fetch updated task", http.StatusInternalServerError)
 		return
 	}
-
-	if projectID.Valid {
-		task.ProjectID = int(projectID.Int64)
-	}
-	task.ProjectName = projectName.String
+	task := *taskPtr
 	pageNum, _ := strconv.Atoi(page)
 	task.Page = pageNum
 
-	if err := db.QueryRow(context.Background(), "SELECT user_id FROM tasks WHERE id = $1", id).Scan(&ownerID); err == nil {
-		var completedCount int
-		var incompleteCount int
-		if projectFilter == nil {
-			_ = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true", ownerID).Scan(&completedCount)
-			_ = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)", ownerID).Scan(&incompleteCount)
-		} else {
-			projectCond := ""
-			args := []interface{}{ownerID}
-			if *projectFilter == 0 {
-				projectCond = " AND project_id IS NULL"
-			} else {
-				projectCond = " AND project_id = $2"
-				args = append(args, *projectFilter)
+	if completedCount, incompleteCount, err := storage.CountTasksByDone(userID, projectFilter); err == nil {
+		// Emit HTMX trigger with counts payload so client can update badges
+		addTrigger(w, "taskCountsChanged", map[string]interface{}{"completed": completedCount, "incomplete": incompleteCount})
+		if nextOccurrenceID > 0 {
+			// Reload the list so the newly scheduled occurrence shows up
+			reloadProject := ""
+			if projectFilter != nil {
+				reloadProject = strconv.Itoa(*projectFilter)
 			}
-			_ = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND completed = true"+projectCond, args...).Scan(&completedCount)
-			_ = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND (completed IS NULL OR completed = false)"+projectCond, args...).Scan(&incompleteCount)
+			addTrigger(w, "reloadPage", map[string]interface{}{"page": max(pageNum, 1), "project": reloadProject})
 		}
-		// Emit HTMX trigger with counts payload so client can update badges
-		w.Header().Set("HX-Trigger", fmt.Sprintf(`{"taskCountsChanged":{"completed":%d,"incomplete":%d}}`, completedCount, incompleteCount))
+	}
+
+	if undoErr == nil {
+		msg := "Task marked incomplete"
+		switch {
+		case statusParam != "":
+			msg = "Task moved to " + newStatus.Name
+		case updatedStatus:
+			msg = "Task completed"
+		}
+		offerUndo(w, userID, msg, storage.UndoRecord{Kind: "complete", Tasks: undoState, Created: nextOccurrenceID}, pageNum, projectParam)
 	}
 
 	basePath := utils.GetBasePath()
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	// Render the complete task row with updated data
-	data := struct {
-		Task          tasks.Task
-		BasePath      string
-		ProjectFilter string
-	}{
+	data := taskRow{
 		Task:          task,
 		BasePath:      basePath,
 		ProjectFilter: projectParam,
+		SubtasksOpen:  parentID.Valid,
 	}
 
 	if err := utils.Templates.ExecuteTemplate(w, "todo.html", data); err != nil {
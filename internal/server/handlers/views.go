@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// renderViewsSidebar renders the views sidebar of the task list: the smart views (Today,
+// Overdue, Upcoming, No due date) with their task counts in the user's timezone, scoped
+// by the project filter in project, then the user's saved views with their counts and
+// the form to save a new one. The view in view is marked as the one shown.
+func renderViewsSidebar(w http.ResponseWriter, r *http.Request, userID int, timezone, errMsg string) {
+	counts, err := tasks.CountSmartViews(userID, timezone, parseProjectFilter(r.FormValue("project")))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error counting tasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	active := r.FormValue("view")
+	views := make([]map[string]interface{}, 0, len(tasks.SmartViews))
+	for _, v := range tasks.SmartViews {
+		views = append(views, map[string]interface{}{
+			"Key":    v.Key,
+			"Label":  v.Label,
+			"Icon":   v.Icon,
+			"Count":  counts[v.Key],
+			"Active": v.Key == active,
+		})
+	}
+
+	// Saved views are counted through the search query that lists them
+	saved, err := storage.GetSavedViews(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching saved views: %v", err), http.StatusInternalServerError)
+		return
+	}
+	today := tasks.LocalToday(timezone)
+	savedList := make([]map[string]interface{}, 0, len(saved))
+	for _, v := range saved {
+		filter := savedViewFilter(v)
+		filter.Today = today
+		count, err := tasks.CountSearchTasksForUser(v.Query, userID, filter)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error counting tasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+		key := savedViewPrefix + strconv.Itoa(v.ID)
+		savedList = append(savedList, map[string]interface{}{
+			"ID":     v.ID,
+			"Key":    key,
+			"Name":   v.Name,
+			"Count":  count,
+			"Active": key == active,
+		})
+	}
+
+	projectsList := make([]map[string]interface{}, 0)
+	projs, err := storage.GetProjectsForUser(userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching projects: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, p := range projs {
+		projectsList = append(projectsList, map[string]interface{}{"ID": p.ID, "Name": p.Name})
+	}
+
+	utils.RenderTemplate(w, r, "views_sidebar.html", map[string]interface{}{
+		"Views":      views,
+		"SavedViews": savedList,
+		"Projects":   projectsList,
+		"View":       active,
+		"Error":      errMsg,
+	})
+}
+
+// APIViewsSidebar renders the views sidebar for the view and project filter shown.
+func APIViewsSidebar(w http.ResponseWriter, r *http.Request) {
+	userID, timezone, ok := requireActiveUser(w, r)
+	if !ok {
+		return
+	}
+	renderViewsSidebar(w, r, userID, timezone, "")
+}
@@ -0,0 +1,75 @@
+// Bulk selection: the Select button turns on checkboxes in the task list, and
+// the bulk bar applies one action to every checked task via /api/bulk-tasks.
+
+function selectedBoxes() {
+  return Array.from(
+    document.querySelectorAll("#task-container .bulk-select:checked"),
+  );
+}
+
+function updateBulkCount() {
+  const count = selectedBoxes().length;
+  const label = document.querySelector("#bulk-form .bulk-count");
+  if (label) label.textContent = `${count} selected`;
+  const all = document.querySelector("#task-container .bulk-select-all");
+  if (all) {
+    const boxes = document.querySelectorAll("#task-container .bulk-select");
+    all.checked = boxes.length > 0 && count === boxes.length;
+    all.indeterminate = count > 0 && count < boxes.length;
+  }
+}
+
+export function attachBulkSelection() {
+  const toggle = document.getElementById("bulk-toggle");
+  const form = document.getElementById("bulk-form");
+  if (!toggle || !form) return;
+
+  toggle.addEventListener("click", () => {
+    const on = document.body.classList.toggle("bulk-mode");
+    toggle.setAttribute("aria-pressed", on ? "true" : "false");
+    toggle.classList.toggle("active", on);
+    if (!on) {
+      selectedBoxes().forEach((box) => (box.checked = false));
+    }
+    updateBulkCount();
+  });
+
+  document.body.addEventListener("change", (evt) => {
+    if (evt.target.classList.contains("bulk-select-all")) {
+      document
+        .querySelectorAll("#task-container .bulk-select")
+        .forEach((box) => (box.checked = evt.target.checked));
+    }
+    if (
+      evt.target.classList.contains("bulk-select") ||
+      evt.target.classList.contains("bulk-select-all")
+    ) {
+      updateBulkCount();
+    }
+  });
+
+  // The list is re-rendered after an action, which clears the selection
+  document.body.addEventListener("htmx:afterSwap", (evt) => {
+    if (evt.target.id === "task-container") updateBulkCount();
+  });
+
+  // Send the list page being shown so it can be reloaded after the action
+  form.addEventListener("htmx:configRequest", (evt) => {
+    const pageEl = document.querySelector(
+      '#task-container [name="currentPage"]',
+    );
+    if (pageEl && pageEl.value) evt.detail.parameters.page = pageEl.value;
+  });
+
+  // Deleting asks first, like deleting a single task
+  form.addEventListener("htmx:confirm", (evt) => {
+    const action = form.querySelector('[name="action"]');
+    if (!action || action.value !== "delete") return;
+    evt.preventDefault();
+    const count = selectedBoxes().length;
+    const noun = count === 1 ? "task" : "tasks";
+    if (count === 0 || window.confirm(`Move ${count} ${noun} to the trash?`)) {
+      evt.detail.issueRequest(true);
+    }
+  });
+}
@@ -0,0 +1,79 @@
+import { apiPath } from "./utils.js";
+
+// Tasks can be dragged to another day; the server re-renders the calendar after
+// every drop, so a refused move puts the task back.
+export function initCalendarSortable() {
+  try {
+    if (typeof Sortable === "undefined") return;
+
+    document.querySelectorAll(".calendar-task-list").forEach((el) => {
+      if (el._sortable) {
+        try {
+          el._sortable.destroy();
+        } catch (e) {}
+      }
+      el._sortable = Sortable.create(el, {
+        group: "calendar",
+        sort: false,
+        animation: 150,
+        onEnd: function (evt) {
+          if (evt.from === evt.to) return;
+          const controls = document.getElementById("calendar-controls");
+          const values = controls
+            ? Object.fromEntries(new FormData(controls))
+            : {};
+          values.id = evt.item.dataset.taskId;
+          values.due_date = evt.to.dataset.date;
+          htmx.ajax("POST", apiPath("/api/calendar/move"), {
+            target: "#calendar-container",
+            swap: "innerHTML",
+            values: values,
+          });
+        },
+      });
+    });
+  } catch (e) {
+    // ignore
+  }
+}
+
+// Clicking a day opens the quick add box with that day as the due date
+function openQuickAdd(date) {
+  const panel = document.getElementById("calendar-quick-add");
+  const dateInput = document.getElementById("quick-add-date");
+  const input = document.getElementById("quick-add");
+  if (!panel || !dateInput || !input) return;
+  dateInput.value = date;
+  dateInput.dispatchEvent(new Event("change", { bubbles: true }));
+  try {
+    bootstrap.Collapse.getOrCreateInstance(panel, { toggle: false }).show();
+  } catch (e) {}
+  input.focus();
+}
+
+export function attachCalendar() {
+  if (!document.getElementById("calendar-container")) return;
+  initCalendarSortable();
+
+  document.body.addEventListener("htmx:afterSwap", function (evt) {
+    if (evt.target.id === "calendar-container") {
+      initCalendarSortable();
+    }
+  });
+
+  document.body.addEventListener("click", function (evt) {
+    const day = evt.target.closest(".calendar-day");
+    if (!day || evt.target.closest(".calendar-task")) return;
+    openQuickAdd(day.dataset.date);
+  });
+
+  // Show a task added from the quick add box (not on validation errors)
+  document.body.addEventListener("htmx:afterRequest", function (evt) {
+    const form = document.getElementById("quick-add-form");
+    if (!form || evt.detail.elt !== form || !evt.detail.successful) return;
+    const xhr = evt.detail.xhr;
+    if (xhr && xhr.getResponseHeader("X-Validation-Error") === "true") return;
+    const controls = document.getElementById("calendar-controls");
+    if (controls) htmx.trigger(controls, "change");
+  });
+}
@@ -39,8 +39,37 @@ export function attachTaskDeletedListener() {
 
 export function attachReloadPageListener() {
   document.body.addEventListener("reloadPage", function (evt) {
+    // On the board, refresh the columns of the project shown
+    if (document.getElementById("board-container")) {
+      const pf = document.querySelector("select#project-filter");
+      const project = pf ? pf.value : "";
+      htmx.ajax(
+        "GET",
+        apiPath(`/api/board?project=${encodeURIComponent(project)}`),
+        { target: "#board-container", swap: "innerHTML" },
+      );
+      return;
+    }
     const page = evt.detail.page || 1;
     let url = `/api/fetch-tasks?page=${page}`;
+    if (evt.detail.project) {
+      url += `&project=${encodeURIComponent(evt.detail.project)}`;
+    }
+    const deferredFilter = document.getElementById("deferred-filter");
+    if (deferredFilter && deferredFilter.value) {
+      url += `&deferred=${encodeURIComponent(deferredFilter.value)}`;
+    }
+    // Stay in the smart view shown
+    const view = document.querySelector('#task-container [name="view"]');
+    if (view && view.value) {
+      url += `&view=${encodeURIComponent(view.value)}`;
+    }
+    // Keep the custom field filter and sort of the project view
+    document.querySelectorAll("#field-filter-bar [name]").forEach((el) => {
+      if (el.value) {
+        url += `&${el.name}=${encodeURIComponent(el.value)}`;
+      }
+    });
     const searchInput = document.getElementById("search");
     if (searchInput && searchInput.value) {
       url += `&search=${encodeURIComponent(searchInput.value)}`;
@@ -117,3 +117,32 @@ export function handleDescriptionInput(charCountElement) {
     description.classList.add("char-count-listener-added");
   }
 }
+
+export function initializeQuickAddForm() {
+  const form = document.getElementById("quick-add-form");
+  const input = document.getElementById("quick-add");
+  const error = document.getElementById("quick-add-error");
+  const preview = document.getElementById("quick-add-preview");
+  if (!form || !input) return;
+
+  // Clear the error when the user edits the line
+  input.addEventListener("input", function () {
+    if (error) error.innerHTML = "";
+  });
+
+  // Clear the line and its preview once the task is added (not on errors)
+  form.addEventListener("htmx:afterRequest", function (event) {
+    if (event.detail.elt !== form) return;
+    const xhr = event.detail && event.detail.xhr;
+    const header =
+      xhr && xhr.getResponseHeader
+        ? xhr.getResponseHeader("X-Validation-Error")
+        : null;
+    const isValidationError = header && header.toLowerCase() === "true";
+    if (event.detail.successful && !isValidationError) {
+      input.value = "";
+      if (error) error.innerHTML = "";
+      if (preview) preview.innerHTML = "";
+    }
+  });
+}
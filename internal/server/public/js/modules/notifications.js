@@ -1,4 +1,4 @@
-import { ensureToastContainer } from "./utils.js";
+import { apiPath, ensureToastContainer } from "./utils.js";
 
 export function showToast(message, opts) {
   opts = opts || {};
@@ -10,12 +10,37 @@ export function showToast(message, opts) {
   t.textContent = message;
   container.appendChild(t);
 
+  // Undo button for actions the server recorded: {token, page, project, seconds}
+  const undo = opts.undo;
+  if (undo && undo.token) {
+    t.classList.add("app-toast--undo");
+    const btn = document.createElement("button");
+    btn.type = "button";
+    btn.className = "app-toast-action";
+    btn.textContent = "Undo";
+    btn.addEventListener("click", function (e) {
+      e.stopPropagation();
+      btn.disabled = true;
+      htmx.ajax("POST", apiPath("/api/undo"), {
+        swap: "none",
+        values: { token: undo.token, page: undo.page, project: undo.project || "" },
+      });
+      clearTimeout(to);
+      remove();
+    });
+    t.appendChild(btn);
+  }
+
   // ensure next frame for animation
   requestAnimationFrame(() => {
     t.classList.add("show");
   });
 
-  const timeout = typeof opts.duration === "number" ? opts.duration : 3500;
+  let timeout = typeof opts.duration === "number" ? opts.duration : 3500;
+  if (undo && undo.seconds) {
+    // Keep the toast up for the whole undo window
+    timeout = undo.seconds * 1000;
+  }
   const remove = () => {
     t.classList.remove("show");
     setTimeout(() => {
@@ -48,4 +73,12 @@ export function attachNotificationListeners() {
       showToast("You can only favorite up to 5 tasks", { error: true });
     }
   });
+
+  // Generic toast requested by the server via HX-Trigger: {"show-toast": {"message": "...", "error": true}}
+  document.body.addEventListener("show-toast", function (evt) {
+    const detail = (evt && evt.detail) || {};
+    if (detail.message) {
+      showToast(detail.message, { error: !!detail.error, undo: detail.undo });
+    }
+  });
 }
@@ -31,10 +31,26 @@ export function initializeSidebarEventListeners() {
           if (titleEl) titleEl.value = "";
           const descEl = tf.querySelector("#description");
           if (descEl) descEl.value = "";
+          const notesEl = tf.querySelector("#notes");
+          if (notesEl) notesEl.value = "";
           const projEl = tf.querySelector("#project_id");
           if (projEl) projEl.value = "";
           const dueEl = tf.querySelector("#due_date");
           if (dueEl) dueEl.value = "";
+          const dueTimeEl = tf.querySelector("#due_time");
+          if (dueTimeEl) dueTimeEl.value = "";
+          const priorityEl = tf.querySelector("#priority");
+          if (priorityEl) priorityEl.value = "none";
+          const repeatEl = tf.querySelector("#repeat");
+          if (repeatEl) repeatEl.value = "";
+          const intervalEl = tf.querySelector("#repeat_interval");
+          if (intervalEl) intervalEl.value = "1";
+          tf.querySelectorAll(
+            ".repeat-options input[type=checkbox], .tag-options input[type=checkbox], .reminder-options input[type=checkbox]",
+          ).forEach((cb) => (cb.checked = false));
+          tf.querySelectorAll("#repeat_monthday, #repeat_rrule").forEach(
+            (el) => (el.value = ""),
+          );
           const idInput = tf.querySelector('input[name="id"]');
           if (idInput) idInput.remove();
           const submit = tf.querySelector('button[type="submit"]');
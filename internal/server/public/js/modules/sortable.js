@@ -102,9 +102,96 @@ export function initSortable() {
   }
 }
 
+// Board cards move between the status columns; the server re-renders the board
+// after every drop, so a refused move puts the card back.
+export function initBoardSortable() {
+  try {
+    if (typeof Sortable === "undefined") return;
+
+    document.querySelectorAll(".board-column-list").forEach((el) => {
+      if (el._sortable) {
+        try {
+          el._sortable.destroy();
+        } catch (e) {}
+      }
+      el._sortable = Sortable.create(el, {
+        group: "board",
+        animation: 150,
+        onEnd: function (evt) {
+          if (evt.from === evt.to && evt.oldIndex === evt.newIndex) return;
+          const ids = Array.from(evt.to.children)
+            .map((card) => card.dataset.taskId)
+            .filter(Boolean)
+            .join(",");
+          const project = document.querySelector("select#project-filter");
+          htmx.ajax("POST", apiPath("/api/board/move"), {
+            target: "#board-container",
+            swap: "innerHTML",
+            values: {
+              id: evt.item.dataset.taskId,
+              status: evt.to.dataset.statusId,
+              ids: ids,
+              project: project ? project.value : "",
+            },
+          });
+        },
+      });
+    });
+  } catch (e) {
+    // ignore
+  }
+}
+
+// Saved views are reordered by dragging them in the views sidebar
+export function initSavedViewsSortable() {
+  try {
+    if (typeof Sortable === "undefined") return;
+
+    const el = document.querySelector(".saved-views-list");
+    if (!el) return;
+    if (el._sortable) {
+      try {
+        el._sortable.destroy();
+      } catch (e) {}
+    }
+    el._sortable = Sortable.create(el, {
+      handle: ".saved-view-handle",
+      animation: 150,
+      onEnd: function (evt) {
+        if (evt.oldIndex === evt.newIndex) return;
+        const ids = Array.from(el.querySelectorAll("[data-view-id]"))
+          .map((item) => item.dataset.viewId)
+          .join(",");
+        const project = document.querySelector("select#project-filter");
+        const view = document.querySelector('#task-container [name="view"]');
+        htmx.ajax("POST", apiPath("/api/saved-views/reorder"), {
+          target: "#views-sidebar",
+          swap: "innerHTML",
+          values: {
+            ids: ids,
+            project: project ? project.value : "",
+            view: view ? view.value : "",
+          },
+        });
+      },
+    });
+  } catch (e) {
+    // ignore
+  }
+}
+
 export function attachSortableInitializers() {
   // Initialize sortable on initial load and after HTMX swaps
   initSortable();
+  initBoardSortable();
+  document.body.addEventListener("htmx:afterSwap", function (evt) {
+    if (evt.target.id === "board-container") {
+      initBoardSortable();
+    }
+    if (evt.target.id === "views-sidebar") {
+      initSavedViewsSortable();
+    }
+  });
   document.body.addEventListener("htmx:afterSwap", function (evt) {
     if (evt.target.id === "task-container") {
       // Ensure table retains expected Bootstrap classes after HTMX replaces content
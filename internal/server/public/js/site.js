@@ -11,6 +11,7 @@ import { initTheme, toggleTheme, attachThemeToggle } from "./modules/theme.js";
 import {
   initCharacterCounters,
   initializeProjectFormHandlers,
+  initializeQuickAddForm,
   handleDescriptionInput,
 } from "./modules/form-handlers.js";
 import {
@@ -36,6 +37,8 @@ import {
   attachNotificationListeners,
 } from "./modules/notifications.js";
 import { attachAllEventListeners } from "./modules/events.js";
+import { attachBulkSelection } from "./modules/bulk.js";
+import { attachCalendar } from "./modules/calendar.js";
 import {
   initGlobalAnnouncement,
   dismissGlobalAnnouncement,
@@ -57,12 +60,15 @@ document.addEventListener("DOMContentLoaded", () => {
   attachThemeToggle();
   initCharacterCounters();
   initializeProjectFormHandlers();
+  initializeQuickAddForm();
   initializeSidebarEventListeners();
   attachSortableInitializers();
   initializeModalEventListeners();
   attachChangelogListener();
   attachNotificationListeners();
   attachAllEventListeners();
+  attachBulkSelection();
+  attachCalendar();
   initGlobalAnnouncement();
   initAnnouncementCharCounter();
 
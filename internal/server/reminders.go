@@ -0,0 +1,77 @@
+package server
+
+import (
+	"GoTodo/internal/server/utils"
+	"GoTodo/internal/storage"
+	"GoTodo/internal/tasks"
+	"fmt"
+	"html"
+	"time"
+)
+
+// reminderInterval is how often the reminder worker looks for due reminders.
+const reminderInterval = time.Minute
+
+// startReminderWorker runs the reminder loop in the background for the lifetime of the process.
+func startReminderWorker() {
+	if !utils.EmailConfigured() {
+		fmt.Println("Reminders: Mailgun is not configured, email reminders are disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+		for {
+			sendDueReminders(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// sendDueReminders delivers every reminder that is due at now. Each reminder is claimed in
+// the database before sending, so it goes out once even across restarts; a failed send
+// releases the claim so the next run retries it.
+func sendDueReminders(now time.Time) {
+	due, err := tasks.DueReminders(now)
+	if err != nil {
+		fmt.Printf("Reminders: failed to load due reminders: %v\n", err)
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	siteName := "GoTodo"
+	if settings, err := storage.GetSiteSettings(); err == nil && settings != nil && settings.SiteName != "" {
+		siteName = settings.SiteName
+	}
+
+	for _, rem := range due {
+		claimed, err := storage.ClaimReminder(rem.ID, rem.DueAt)
+		if err != nil {
+			fmt.Printf("Reminders: %v\n", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		task := tasks.Task{DueDate: rem.DueDate, DueTime: rem.DueTime}
+		subject := fmt.Sprintf("%s - Reminder: %s", siteName, rem.Title)
+		body := fmt.Sprintf(`Hello,
+
+This is a reminder (%s) for your task:
+
+<strong>%s</strong>
+
+Due: %s
+`, tasks.DescribeReminderOffset(rem.OffsetMinutes), html.EscapeString(rem.Title), task.DueLabel())
+
+		if err := utils.SendEmail(subject, body, rem.Email); err != nil {
+			fmt.Printf("Reminders: failed to send reminder %d: %v\n", rem.ID, err)
+			if err := storage.ReleaseReminder(rem.ID, rem.DueAt); err != nil {
+				fmt.Printf("Reminders: %v\n", err)
+			}
+		}
+	}
+}
@@ -38,6 +38,13 @@ func StartServer() error {
 		fmt.Printf("Warning: migrations completed with errors: %v\n", err)
 	}
 
+	// Send task reminder emails in the background
+	startReminderWorker()
+	// Purge tasks that have been in the trash past the retention period
+	startTrashPurgeWorker()
+	// Archive completed tasks after each user's chosen number of days
+	startArchiveWorker()
+
 	// Preload changelog from GitHub at startup to avoid runtime API calls
 	if err := handlers.PreloadChangelog(); err != nil {
 		fmt.Printf("Warning: Preloading changelog failed: %v\n", err)
@@ -65,6 +72,14 @@ func StartServer() error {
 	http.HandleFunc("/search", handlers.SearchHandler)
 	http.HandleFunc("/profile", handlers.ProfilePage)
 	http.HandleFunc("/projects", utils.RequireAuth(handlers.ProjectsPageHandler))
+	http.HandleFunc("/tags", utils.RequireAuth(handlers.TagsPageHandler))
+	http.HandleFunc("/reports/time", utils.RequireAuth(handlers.TimeReportPageHandler))
+	http.HandleFunc("/reports/time.csv", utils.RequireAuth(handlers.TimeReportCSVHandler))
+	http.HandleFunc("/trash", utils.RequireAuth(handlers.TrashPageHandler))
+	http.HandleFunc("/archive", utils.RequireAuth(handlers.ArchivePageHandler))
+	http.HandleFunc("/templates", utils.RequireAuth(handlers.TemplatesPageHandler))
+	http.HandleFunc("/board", utils.RequireAuth(handlers.BoardPageHandler))
+	http.HandleFunc("/calendar", utils.RequireAuth(handlers.CalendarPageHandler))
 	http.HandleFunc("/createinvite", utils.RequirePermission("createinvites", handlers.CreateInvitePageHandler))
 	http.HandleFunc("/admin", utils.RequirePermission("admin", handlers.AdminPageHandler))
 	http.HandleFunc("/admin/", utils.RequirePermission("admin", handlers.AdminPageHandler))
@@ -82,6 +97,8 @@ func StartServer() error {
 	// Task endpoints
 	http.HandleFunc("/api/fetch-tasks", utils.RequireHTMX(handlers.APIReturnTasks))
 	http.HandleFunc("/api/add-task", utils.RequireHTMX(utils.RateLimitMiddleware(60, 1.0, 60, utils.KeyByUser)(handlers.APIAddTask)))
+	http.HandleFunc("/api/quick-add", utils.RequireHTMX(utils.RateLimitMiddleware(60, 1.0, 60, utils.KeyByUser)(handlers.APIQuickAdd)))
+	http.HandleFunc("/api/quick-add/preview", utils.RequireHTMX(handlers.APIQuickAddPreview))
 	http.HandleFunc("/api/edit", utils.RequireHTMX(handlers.APIEditTaskForm))
 	http.HandleFunc("/api/edit-task", utils.RequireHTMX(utils.RateLimitMiddleware(60, 1.0, 60, utils.KeyByUser)(handlers.APIEditTask)))
 	http.HandleFunc("/api/confirm", utils.RequireHTMX(handlers.APIConfirmDelete))
@@ -90,6 +107,51 @@ func StartServer() error {
 	http.HandleFunc("/api/update-status", utils.RequireHTMX(handlers.APIUpdateTaskStatus))
 	http.HandleFunc("/api/toggle-favorite", utils.RequireHTMX(handlers.APIToggleFavorite))
 	http.HandleFunc("/api/reorder-tasks", utils.RequireHTMX(handlers.APIReorderTasks))
+	http.HandleFunc("/api/bulk-tasks", utils.RequireHTMX(handlers.APIBulkTasks))
+
+	// Subtask endpoints
+	http.HandleFunc("/api/subtasks/add", utils.RequireHTMX(utils.RateLimitMiddleware(60, 1.0, 60, utils.KeyByUser)(handlers.APIAddSubtask)))
+	http.HandleFunc("/api/subtasks/move", utils.RequireHTMX(handlers.APIMoveSubtask))
+	http.HandleFunc("/api/subtasks/complete-all", utils.RequireHTMX(handlers.APICompleteSubtasks))
+
+	// Dependency (blocked-by) endpoints
+	http.HandleFunc("/api/dependencies/add", utils.RequireHTMX(handlers.APIAddDependency))
+	http.HandleFunc("/api/dependencies/remove", utils.RequireHTMX(handlers.APIRemoveDependency))
+	http.HandleFunc("/api/dependencies/options", utils.RequireHTMX(utils.RequireAuth(handlers.APIDependencyOptions)))
+
+	// Task comment endpoints
+	http.HandleFunc("/api/comments", utils.RequireHTMX(handlers.APITaskComments))
+	http.HandleFunc("/api/comments/add", utils.RequireHTMX(utils.RateLimitMiddleware(60, 1.0, 60, utils.KeyByUser)(handlers.APIAddComment)))
+	http.HandleFunc("/api/comments/edit", utils.RequireHTMX(handlers.APIEditCommentForm))
+	http.HandleFunc("/api/comments/update", utils.RequireHTMX(handlers.APIUpdateComment))
+	http.HandleFunc("/api/comments/delete", utils.RequireHTMX(handlers.APIDeleteComment))
+
+	// Task revision history
+	http.HandleFunc("/api/history", utils.RequireHTMX(handlers.APITaskHistory))
+	http.HandleFunc("/api/history/revert", utils.RequireHTMX(handlers.APIRevertTask))
+
+	// Attachment endpoints (downloads are plain links, not HTMX requests)
+	http.HandleFunc("/api/attachments", utils.RequireHTMX(handlers.APITaskAttachments))
+	http.HandleFunc("/api/attachments/upload", utils.RequireHTMX(utils.RateLimitMiddleware(30, 0.5, 30, utils.KeyByUser)(handlers.APIUploadAttachment)))
+	http.HandleFunc("/api/attachments/delete", utils.RequireHTMX(handlers.APIDeleteAttachment))
+	http.HandleFunc("/attachments/download", handlers.AttachmentDownloadHandler)
+	http.HandleFunc("/api/timer/start", utils.RequireHTMX(handlers.APIStartTimer))
+	http.HandleFunc("/api/timer/stop", utils.RequireHTMX(handlers.APIStopTimer))
+	http.HandleFunc("/api/time-entries", utils.RequireHTMX(handlers.APITimeEntries))
+	http.HandleFunc("/api/time-entries/add", utils.RequireHTMX(handlers.APIAddTimeEntry))
+	http.HandleFunc("/api/time-entries/delete", utils.RequireHTMX(handlers.APIDeleteTimeEntry))
+	http.HandleFunc("/api/trash/restore", utils.RequireHTMX(handlers.APIRestoreTask))
+	http.HandleFunc("/api/trash/purge", utils.RequireHTMX(handlers.APIPurgeTask))
+	http.HandleFunc("/api/trash/empty", utils.RequireHTMX(handlers.APIEmptyTrash))
+	http.HandleFunc("/api/archive", utils.RequireHTMX(handlers.APIArchiveList))
+	http.HandleFunc("/api/archive/add", utils.RequireHTMX(handlers.APIArchiveTask))
+	http.HandleFunc("/api/archive/unarchive", utils.RequireHTMX(handlers.APIUnarchiveTask))
+	http.HandleFunc("/api/templates/save-task", utils.RequireHTMX(handlers.APISaveTaskTemplate))
+	http.HandleFunc("/api/templates/save-project", utils.RequireHTMX(handlers.APISaveProjectTemplate))
+	http.HandleFunc("/api/templates/use", utils.RequireHTMX(handlers.APIUseTemplate))
+	http.HandleFunc("/api/templates/delete", utils.RequireHTMX(handlers.APIDeleteTemplate))
+	http.HandleFunc("/api/snooze", utils.RequireHTMX(handlers.APISnoozeTask))
+	http.HandleFunc("/api/undo", utils.RequireHTMX(handlers.APIUndo))
 
 	// Partials
 	http.HandleFunc("/partials/login", utils.RequireHTMX(handlers.APIGetLoginPartial))
@@ -101,6 +163,31 @@ func StartServer() error {
 	http.HandleFunc("/api/projects/create", utils.RequireHTMX(utils.RequireAuth(handlers.APICreateProject)))
 	http.HandleFunc("/api/projects/delete", utils.RequireHTMX(utils.RequireAuth(handlers.APIDeleteProject)))
 	http.HandleFunc("/api/projects/json", utils.RequireHTMX(utils.RequireAuth(handlers.APIProjectsJSON)))
+	http.HandleFunc("/api/fields", utils.RequireHTMX(utils.RequireAuth(handlers.APIProjectFields)))
+	http.HandleFunc("/api/fields/create", utils.RequireHTMX(utils.RequireAuth(handlers.APICreateProjectField)))
+	http.HandleFunc("/api/fields/delete", utils.RequireHTMX(utils.RequireAuth(handlers.APIDeleteProjectField)))
+	http.HandleFunc("/api/fields/inputs", utils.RequireHTMX(utils.RequireAuth(handlers.APITaskFieldInputs)))
+	http.HandleFunc("/api/fields/filter", utils.RequireHTMX(utils.RequireAuth(handlers.APIFieldFilterBar)))
+	http.HandleFunc("/api/statuses", utils.RequireHTMX(utils.RequireAuth(handlers.APIWorkflow)))
+	http.HandleFunc("/api/statuses/create", utils.RequireHTMX(utils.RequireAuth(handlers.APICreateStatus)))
+	http.HandleFunc("/api/statuses/delete", utils.RequireHTMX(utils.RequireAuth(handlers.APIDeleteStatus)))
+	http.HandleFunc("/api/statuses/done", utils.RequireHTMX(utils.RequireAuth(handlers.APISetDoneStatus)))
+	http.HandleFunc("/api/statuses/move", utils.RequireHTMX(utils.RequireAuth(handlers.APIMoveStatus)))
+	http.HandleFunc("/api/statuses/customize", utils.RequireHTMX(utils.RequireAuth(handlers.APICustomizeWorkflow)))
+	http.HandleFunc("/api/statuses/reset", utils.RequireHTMX(utils.RequireAuth(handlers.APIResetWorkflow)))
+	http.HandleFunc("/api/board", utils.RequireHTMX(utils.RequireAuth(handlers.APIBoard)))
+	http.HandleFunc("/api/board/move", utils.RequireHTMX(utils.RequireAuth(handlers.APIMoveBoardCard)))
+	http.HandleFunc("/api/views/sidebar", utils.RequireHTMX(utils.RequireAuth(handlers.APIViewsSidebar)))
+	http.HandleFunc("/api/saved-views/create", utils.RequireHTMX(utils.RequireAuth(handlers.APICreateSavedView)))
+	http.HandleFunc("/api/saved-views/delete", utils.RequireHTMX(utils.RequireAuth(handlers.APIDeleteSavedView)))
+	http.HandleFunc("/api/saved-views/reorder", utils.RequireHTMX(utils.RequireAuth(handlers.APIReorderSavedViews)))
+	http.HandleFunc("/api/calendar", utils.RequireHTMX(utils.RequireAuth(handlers.APICalendar)))
+	http.HandleFunc("/api/calendar/move", utils.RequireHTMX(utils.RequireAuth(handlers.APIMoveCalendarTask)))
+	http.HandleFunc("/api/tags/create", utils.RequireHTMX(utils.RequireAuth(handlers.APICreateTag)))
+	http.HandleFunc("/api/tags/update", utils.RequireHTMX(utils.RequireAuth(handlers.APIUpdateTag)))
+	http.HandleFunc("/api/tags/delete", utils.RequireHTMX(utils.RequireAuth(handlers.APIDeleteTag)))
+	http.HandleFunc("/api/tags/options", utils.RequireHTMX(utils.RequireAuth(handlers.APITagOptions)))
+	http.HandleFunc("/api/reminders/options", utils.RequireHTMX(utils.RequireAuth(handlers.APIReminderOptions)))
 
 	// Profile API endpoints
 	http.HandleFunc("/api/update-timezone", utils.RequireHTMX(handlers.APIUpdateTimezone))
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"GoTodo/internal/attachments"
+	"GoTodo/internal/config"
+	"GoTodo/internal/storage"
+	"fmt"
+	"time"
+)
+
+// trashPurgeInterval is how often expired tasks are purged from the trash.
+const trashPurgeInterval = time.Hour
+
+// startTrashPurgeWorker permanently deletes tasks that have been in the trash longer than
+// the configured retention, for the lifetime of the process.
+func startTrashPurgeWorker() {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			purgeExpiredTrash()
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeExpiredTrash runs one purge and removes the attachment files of the purged tasks.
+func purgeExpiredTrash() {
+	keys, n, err := storage.PurgeExpiredTrash(config.Cfg.TrashRetentionDays)
+	if err != nil {
+		fmt.Printf("Trash: failed to purge expired tasks: %v\n", err)
+		return
+	}
+	attachments.DeleteFiles(keys)
+	if n > 0 {
+		fmt.Printf("Trash: purged %d task(s) older than %d days\n", n, config.Cfg.TrashRetentionDays)
+	}
+}
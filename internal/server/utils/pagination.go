@@ -1,6 +1,9 @@
 package utils
 
-import "GoTodo/internal/tasks"
+import (
+	"GoTodo/internal/storage"
+	"fmt"
+)
 
 type PaginationData struct {
 	PreviousPage         int
@@ -78,24 +81,27 @@ func GetPaginationData(page, pageSize, totalItems, userID int) PaginationData {
 	}
 }
 
+// GetCompletedTasksCount returns how many of the user's listed tasks are in the done
+// status of their workflow.
 func GetCompletedTasksCount(userID *int) int {
-	count := 0
-	var ts = tasks.ReturnTaskListForUser(userID)
-	for _, task := range ts {
-		if task.Completed {
-			count++
-		}
+	if userID == nil {
+		return 0
 	}
-	return count
+	done, _, err := storage.CountTasksByDone(*userID, nil)
+	if err != nil {
+		fmt.Println("Error counting completed tasks:", err)
+	}
+	return done
 }
 
+// GetIncompleteTasksCount returns how many of the user's listed tasks are in an open status.
 func GetIncompleteTasksCount(userID *int) int {
-	count := 0
-	var ts = tasks.ReturnTaskListForUser(userID)
-	for _, task := range ts {
-		if !task.Completed {
-			count++
-		}
+	if userID == nil {
+		return 0
+	}
+	_, open, err := storage.CountTasksByDone(*userID, nil)
+	if err != nil {
+		fmt.Println("Error counting incomplete tasks:", err)
 	}
-	return count
+	return open
 }
@@ -12,6 +12,11 @@ import (
 	"github.com/mailgun/mailgun-go/v5"
 )
 
+// EmailConfigured reports whether Mailgun credentials are present, i.e. whether SendEmail can work.
+func EmailConfigured() bool {
+	return os.Getenv("MAILGUN_API_KEY") != "" && os.Getenv("MAILGUN_DOMAIN") != ""
+}
+
 // SendEmail sends an email using Mailgun. Parameters: subject, message, toEmail
 func SendEmail(subject, message, toEmail string) error {
 	apiKey := os.Getenv("MAILGUN_API_KEY")
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"testing"
 
 	"github.com/gorilla/sessions"
 	"github.com/joho/godotenv"
@@ -19,6 +21,10 @@ func init() {
 	}
 
 	sessionKey := os.Getenv("SESSION_KEY")
+	if sessionKey == "" && testing.Testing() {
+		// Handler tests render pages without a configured key
+		sessionKey = strings.Repeat("t", 32)
+	}
 	if sessionKey == "" {
 		log.Fatal("SESSION_KEY environment variable is not set")
 	}
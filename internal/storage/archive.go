@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ArchivedTask is a task in the archive view.
+type ArchivedTask struct {
+	ID             int
+	Title          string
+	ProjectName    string
+	SubtaskCount   int
+	CompletedLabel string // completion time in the viewer's timezone, empty when unknown
+	ArchivedLabel  string // archive time in the viewer's timezone
+}
+
+// ErrArchiveNotAllowed is returned when a task cannot be archived: it is not one of the
+// user's completed top-level tasks, or it is already archived or in the trash.
+var ErrArchiveNotAllowed = errors.New("only completed tasks can be archived")
+
+// ErrArchivedTaskNotFound is returned when an archived task does not exist or belongs to another user.
+var ErrArchivedTaskNotFound = errors.New("archived task not found")
+
+// ArchiveTask moves a completed top-level task of the user into the archive.
+func ArchiveTask(taskID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `UPDATE tasks SET archived_at = NOW() AT TIME ZONE 'UTC'
+		WHERE id = $1 AND user_id = $2 AND parent_id IS NULL AND completed = true
+		AND archived_at IS NULL AND deleted_at IS NULL`, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to archive task: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrArchiveNotAllowed
+	}
+	return nil
+}
+
+// UnarchiveTask brings an archived task of the user back into the task list.
+func UnarchiveTask(taskID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `UPDATE tasks SET archived_at = NULL
+		WHERE id = $1 AND user_id = $2 AND archived_at IS NOT NULL AND deleted_at IS NULL`, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unarchive task: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrArchivedTaskNotFound
+	}
+	return nil
+}
+
+// ArchiveCompletedTasks archives every completed top-level task that has been complete for
+// longer than its owner's archive_after_days setting, and returns how many were archived.
+// Users with the setting at 0 are skipped.
+func ArchiveCompletedTasks() (int64, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `UPDATE tasks t SET archived_at = NOW() AT TIME ZONE 'UTC'
+		FROM users u
+		WHERE u.id = t.user_id AND u.archive_after_days > 0
+		AND t.parent_id IS NULL AND t.completed = true AND t.archived_at IS NULL AND t.deleted_at IS NULL
+		AND t.completed_at < (NOW() AT TIME ZONE 'UTC') - make_interval(days => u.archive_after_days)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive completed tasks: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetArchive returns one page of the user's archived tasks, most recently archived first,
+// along with the total number of archived tasks.
+func GetArchive(userID int, timezone string, page, pageSize int) ([]ArchivedTask, int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var total int
+	err = pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM tasks
+		WHERE user_id = $1 AND parent_id IS NULL AND archived_at IS NOT NULL AND deleted_at IS NULL`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count archived tasks: %v", err)
+	}
+
+	rows, err := pool.Query(context.Background(), `SELECT t.id, t.title, COALESCE(p.name, ''),
+		(SELECT COUNT(*) FROM tasks s WHERE s.parent_id = t.id AND s.deleted_at IS NULL),
+		COALESCE(TO_CHAR((t.completed_at AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM'), ''),
+		TO_CHAR((t.archived_at AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM')
+		FROM tasks t LEFT JOIN projects p ON p.id = t.project_id
+		WHERE t.user_id = $1 AND t.parent_id IS NULL AND t.archived_at IS NOT NULL AND t.deleted_at IS NULL
+		ORDER BY t.archived_at DESC, t.id DESC
+		LIMIT $3 OFFSET $4`, userID, timezone, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query archive: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]ArchivedTask, 0)
+	for rows.Next() {
+		var t ArchivedTask
+		if err := rows.Scan(&t.ID, &t.Title, &t.ProjectName, &t.SubtaskCount, &t.CompletedLabel, &t.ArchivedLabel); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan archived task: %v", err)
+		}
+		list = append(list, t)
+	}
+	return list, total, rows.Err()
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TaskAttachment is the metadata of a file attached to a task. The contents are kept by
+// the attachments store under StorageKey.
+type TaskAttachment struct {
+	ID          int
+	TaskID      int
+	StorageKey  string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	CreatedAt   string // formatted in the viewer's timezone
+}
+
+var (
+	// ErrAttachmentNotFound is returned when an attachment does not exist or is not the user's.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrAttachmentQuota is returned when a new attachment would exceed the user's quota.
+	ErrAttachmentQuota = errors.New("attachment quota exceeded")
+)
+
+// CreateTaskAttachmentsTable creates the attachment metadata table.
+func CreateTaskAttachmentsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_attachments (
+            id SERIAL PRIMARY KEY,
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+            storage_key VARCHAR(64) NOT NULL UNIQUE,
+            filename VARCHAR(255) NOT NULL,
+            content_type VARCHAR(100) NOT NULL,
+            size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+            created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_attachments table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_attachments_task_id ON task_attachments(task_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_attachments.task_id: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_attachments_user_id ON task_attachments(user_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_attachments.user_id: %v", err)
+	}
+	return nil
+}
+
+// GetTaskAttachments lists the attachments of a task owned by the user, oldest first.
+func GetTaskAttachments(taskID, userID int, timezone string) ([]TaskAttachment, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT a.id, a.task_id, a.storage_key, a.filename, a.content_type, a.size_bytes,
+		TO_CHAR((a.created_at AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM')
+		FROM task_attachments a
+		JOIN tasks t ON t.id = a.task_id
+		WHERE a.task_id = $1 AND t.user_id = $2
+		ORDER BY a.created_at, a.id`, taskID, userID, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TaskAttachment, 0)
+	for rows.Next() {
+		var a TaskAttachment
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.StorageKey, &a.Filename, &a.ContentType, &a.SizeBytes, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %v", err)
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// GetAttachment returns an attachment on one of the user's tasks.
+func GetAttachment(id, userID int) (*TaskAttachment, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	var a TaskAttachment
+	err = pool.QueryRow(context.Background(), `SELECT a.id, a.task_id, a.storage_key, a.filename, a.content_type, a.size_bytes
+		FROM task_attachments a JOIN tasks t ON t.id = a.task_id
+		WHERE a.id = $1 AND t.user_id = $2`, id, userID).Scan(
+		&a.ID, &a.TaskID, &a.StorageKey, &a.Filename, &a.ContentType, &a.SizeBytes)
+	if err != nil {
+		return nil, ErrAttachmentNotFound
+	}
+	return &a, nil
+}
+
+// GetAttachmentUsage returns the total size of the user's attachments in bytes.
+func GetAttachmentUsage(userID int) (int64, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var used int64
+	err = pool.QueryRow(context.Background(), "SELECT COALESCE(SUM(size_bytes), 0) FROM task_attachments WHERE user_id = $1", userID).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum attachment sizes: %v", err)
+	}
+	return used, nil
+}
+
+// AddTaskAttachment records an attachment stored under a.StorageKey on one of the user's
+// tasks. It returns ErrAttachmentQuota when the user's attachments would then exceed
+// quotaBytes, and ErrAttachmentNotFound when the task is not the user's.
+func AddTaskAttachment(a TaskAttachment, userID int, quotaBytes int64) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize uploads per user so parallel requests cannot overshoot the quota together
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('task_attachments'), $1)", userID); err != nil {
+		return fmt.Errorf("failed to lock attachments: %v", err)
+	}
+
+	var used int64
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(SUM(size_bytes), 0) FROM task_attachments WHERE user_id = $1", userID).Scan(&used); err != nil {
+		return fmt.Errorf("failed to sum attachment sizes: %v", err)
+	}
+	if used+a.SizeBytes > quotaBytes {
+		return ErrAttachmentQuota
+	}
+
+	tag, err := tx.Exec(ctx, `INSERT INTO task_attachments (task_id, user_id, storage_key, filename, content_type, size_bytes)
+		SELECT t.id, t.user_id, $3, $4, $5, $6 FROM tasks t WHERE t.id = $1 AND t.user_id = $2`,
+		a.TaskID, userID, a.StorageKey, a.Filename, a.ContentType, a.SizeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to add attachment: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAttachmentNotFound
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit attachment: %v", err)
+	}
+	return nil
+}
+
+// DeleteAttachment removes an attachment record of the user and returns it so the
+// caller can delete the stored contents.
+func DeleteAttachment(id, userID int) (*TaskAttachment, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	var a TaskAttachment
+	err = pool.QueryRow(context.Background(), `DELETE FROM task_attachments a USING tasks t
+		WHERE a.id = $1 AND t.id = a.task_id AND t.user_id = $2
+		RETURNING a.id, a.task_id, a.storage_key`, id, userID).Scan(&a.ID, &a.TaskID, &a.StorageKey)
+	if err != nil {
+		return nil, ErrAttachmentNotFound
+	}
+	return &a, nil
+}
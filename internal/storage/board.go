@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrBoardNotAllowed is returned when a board move names a task that does not exist,
+// belongs to another user or another project, is a subtask, or is archived or in the
+// trash. Nothing is changed.
+var ErrBoardNotAllowed = errors.New("some of the tasks cannot be moved")
+
+// MoveTaskOnBoard moves a task of the user to a status of its workflow and orders the
+// cards of that status column as given in order, which must contain the task and only
+// tasks of the same project. The column's tasks reuse the positions they held, so tasks
+// in other columns keep their place in the list; favorites stay ahead of the others, as
+// in the list. Changing the status completes or reopens the task to match the status,
+// recorded in its history. It reports whether the move completed the task.
+func MoveTaskOnBoard(userID, taskID int, status TaskStatus, order []int) (bool, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return false, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var projectID, statusID int
+	var completed bool
+	err = tx.QueryRow(ctx, `SELECT COALESCE(project_id, 0), COALESCE(status_id, 0), COALESCE(completed, false) FROM tasks
+		WHERE id = $1 AND user_id = $2 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL
+		FOR UPDATE`, taskID, userID).Scan(&projectID, &statusID, &completed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrBoardNotAllowed
+		}
+		return false, fmt.Errorf("failed to find task: %v", err)
+	}
+
+	// Every card in the column must be a listed task of the same project
+	rows, err := tx.Query(ctx, `SELECT id, COALESCE(position, 0), COALESCE(is_favorite, false) FROM tasks
+		WHERE id = ANY($1) AND user_id = $2 AND COALESCE(project_id, 0) = $3
+		AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL
+		FOR UPDATE`, order, userID, projectID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check tasks: %v", err)
+	}
+	slots := make([]int, 0, len(order))
+	favorite := make(map[int]bool, len(order))
+	for rows.Next() {
+		var id, position int
+		var fav bool
+		if err := rows.Scan(&id, &position, &fav); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan task: %v", err)
+		}
+		slots = append(slots, position)
+		favorite[id] = fav
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to check tasks: %v", err)
+	}
+	if _, ok := favorite[taskID]; !ok || len(favorite) != len(order) {
+		return false, ErrBoardNotAllowed
+	}
+
+	if status.ID != statusID || status.IsDone != completed {
+		before, err := readRevisionStateTx(ctx, tx, taskID, userID)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(ctx, `UPDATE tasks SET completed = $1, status_id = $2,
+			completed_at = CASE WHEN $1 THEN COALESCE(CASE WHEN completed THEN completed_at END, NOW() AT TIME ZONE 'UTC') END,
+			date_modified = NOW() AT TIME ZONE 'UTC'
+			WHERE id = $3 AND user_id = $4`, status.IsDone, status.ID, taskID, userID)
+		if err != nil {
+			return false, fmt.Errorf("failed to update task status: %v", err)
+		}
+		if err := recordRevisionTx(ctx, tx, taskID, userID, before); err != nil {
+			return false, err
+		}
+	}
+
+	// Favorites lead the column, then the rest, each in the order given
+	sorted := make([]int, 0, len(order))
+	for _, fav := range []bool{true, false} {
+		for _, id := range order {
+			if favorite[id] == fav {
+				sorted = append(sorted, id)
+			}
+		}
+	}
+	sort.Ints(slots)
+	for i, id := range sorted {
+		if _, err := tx.Exec(ctx, "UPDATE tasks SET position = $1 WHERE id = $2 AND user_id = $3", slots[i], id, userID); err != nil {
+			return false, fmt.Errorf("failed to update positions: %v", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit board move: %v", err)
+	}
+	return status.IsDone && !completed, nil
+}
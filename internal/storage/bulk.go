@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Actions ApplyBulkAction can apply to a selection of tasks.
+const (
+	BulkComplete   = "complete"
+	BulkUncomplete = "uncomplete"
+	BulkDelete     = "delete"
+	BulkMove       = "move"
+	BulkDue        = "due"
+	BulkFavorite   = "favorite"
+	BulkUnfavorite = "unfavorite"
+)
+
+// BulkAction is one action applied to every selected task. ProjectID is the target of a
+// move (nil for no project) and DueDate the new due date of a due action (YYYY-MM-DD,
+// empty to clear it).
+type BulkAction struct {
+	Kind      string
+	ProjectID *int
+	DueDate   string
+}
+
+var (
+	// ErrBulkNotAllowed is returned when a selected task does not exist, belongs to another
+	// user, is a subtask, or is archived or in the trash. Nothing is changed.
+	ErrBulkNotAllowed = errors.New("some of the selected tasks cannot be changed")
+	// ErrBulkBlocked is returned when completing tasks that are blocked by open tasks
+	// outside the selection.
+	ErrBulkBlocked = errors.New("some of the selected tasks are blocked by open tasks")
+	// ErrBulkProjectNotFound is returned when moving tasks to a project the user does not own.
+	ErrBulkProjectNotFound = errors.New("project not found")
+)
+
+// IsValidBulkAction reports whether kind is one of the bulk actions.
+func IsValidBulkAction(kind string) bool {
+	switch kind {
+	case BulkComplete, BulkUncomplete, BulkDelete, BulkMove, BulkDue, BulkFavorite, BulkUnfavorite:
+		return true
+	}
+	return false
+}
+
+// ApplyBulkAction applies an action to the user's top-level tasks in ids in one
+// transaction. Every id is checked first; if any is not one of the user's listed tasks
+// nothing changes. Changes to tracked fields are recorded in each task's history. It
+// returns the ids of the tasks the action changed (for completion, the tasks that were
+// open before), in the order given.
+func ApplyBulkAction(userID int, ids []int, action BulkAction) ([]int, error) {
+	if !IsValidBulkAction(action.Kind) {
+		return nil, fmt.Errorf("unknown bulk action %q", action.Kind)
+	}
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Check ownership of every id, locking the rows for the rest of the transaction
+	rows, err := tx.Query(ctx, `SELECT id, COALESCE(completed,false) FROM tasks
+		WHERE id = ANY($1) AND user_id = $2 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL
+		FOR UPDATE`, ids, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tasks: %v", err)
+	}
+	completed := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		var done bool
+		if err := rows.Scan(&id, &done); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan task: %v", err)
+		}
+		completed[id] = done
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check tasks: %v", err)
+	}
+	for _, id := range ids {
+		if _, ok := completed[id]; !ok {
+			return nil, ErrBulkNotAllowed
+		}
+	}
+
+	changed := make([]int, 0, len(ids))
+	switch action.Kind {
+	case BulkComplete:
+		var blocked bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocker_id
+			WHERE d.task_id = ANY($1) AND NOT (d.blocker_id = ANY($1))
+			AND COALESCE(b.completed, false) = false AND b.deleted_at IS NULL)`, ids).Scan(&blocked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check task dependencies: %v", err)
+		}
+		if blocked {
+			return nil, ErrBulkBlocked
+		}
+		for _, id := range ids {
+			if !completed[id] {
+				changed = append(changed, id)
+			}
+		}
+	case BulkUncomplete:
+		for _, id := range ids {
+			if completed[id] {
+				changed = append(changed, id)
+			}
+		}
+	case BulkMove:
+		if action.ProjectID != nil {
+			var exists bool
+			err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1 AND user_id = $2)", *action.ProjectID, userID).Scan(&exists)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check project: %v", err)
+			}
+			if !exists {
+				return nil, ErrBulkProjectNotFound
+			}
+		}
+		changed = append(changed, ids...)
+	default:
+		changed = append(changed, ids...)
+	}
+
+	for _, id := range changed {
+		if action.Kind == BulkDelete {
+			if err := trashTaskTx(ctx, tx, id, userID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		before, err := readRevisionStateTx(ctx, tx, id, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyBulkActionTx(ctx, tx, id, userID, action); err != nil {
+			return nil, err
+		}
+		if err := recordRevisionTx(ctx, tx, id, userID, before); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit bulk action: %v", err)
+	}
+	return changed, nil
+}
+
+// applyBulkActionTx applies a bulk action other than delete to one task inside tx.
+func applyBulkActionTx(ctx context.Context, tx pgx.Tx, taskID, userID int, action BulkAction) error {
+	var err error
+	switch action.Kind {
+	case BulkComplete, BulkUncomplete:
+		_, err = tx.Exec(ctx, `UPDATE tasks SET completed = $1, completed_at = CASE WHEN $1 THEN NOW() AT TIME ZONE 'UTC' END,
+			status_id = `+StatusAfterSQL("$1", "tasks.project_id")+`,
+			archived_at = NULL, date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $2 AND user_id = $3`,
+			action.Kind == BulkComplete, taskID, userID)
+	case BulkMove:
+		// The task takes the status of the same name in the new project's workflow
+		_, err = tx.Exec(ctx, `UPDATE tasks SET project_id = $1,
+			status_id = `+StatusAfterSQL("COALESCE(tasks.completed, false)", "CAST($1 AS INTEGER)")+`,
+			date_modified = NOW() AT TIME ZONE 'UTC' WHERE id = $2 AND user_id = $3`, action.ProjectID, taskID, userID)
+	case BulkDue:
+		_, err = tx.Exec(ctx, `UPDATE tasks SET due_date = CAST(NULLIF($1, '') AS DATE),
+			due_time = CASE WHEN $1 = '' THEN NULL ELSE due_time END, date_modified = NOW() AT TIME ZONE 'UTC'
+			WHERE id = $2 AND user_id = $3`, action.DueDate, taskID, userID)
+	case BulkFavorite, BulkUnfavorite:
+		_, err = tx.Exec(ctx, `UPDATE tasks SET is_favorite = $1, date_modified = NOW() AT TIME ZONE 'UTC'
+			WHERE id = $2 AND user_id = $3`, action.Kind == BulkFavorite, taskID, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update task %d: %v", taskID, err)
+	}
+	return nil
+}
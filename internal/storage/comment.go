@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TaskComment is one entry in a task's discussion thread. UserName is recorded when the
+// comment is written, so the thread reads the same to anyone the task is shown to.
+type TaskComment struct {
+	ID        int
+	TaskID    int
+	UserID    int
+	UserName  string
+	Body      string
+	CreatedAt string // formatted in the viewer's timezone
+	EditedAt  string // empty when the comment was never edited
+}
+
+// ErrCommentNotFound is returned when a comment does not exist or the user may not change it.
+var ErrCommentNotFound = errors.New("comment not found")
+
+// CreateTaskCommentsTable creates the table holding task comments. Timestamps are stored
+// in UTC like tasks.time_stamp.
+func CreateTaskCommentsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_comments (
+            id SERIAL PRIMARY KEY,
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            user_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+            user_name VARCHAR(100) NOT NULL DEFAULT '',
+            body TEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+            edited_at TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_comments table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_comments_task_id ON task_comments(task_id, created_at)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_comments.task_id: %v", err)
+	}
+	return nil
+}
+
+// GetTaskComments returns the comments on a task owned by the user, oldest first, with
+// timestamps formatted in the given timezone.
+func GetTaskComments(taskID, userID int, timezone string) ([]TaskComment, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT c.id, c.task_id, COALESCE(c.user_id, 0), c.user_name, c.body,
+		TO_CHAR((c.created_at AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM'),
+		COALESCE(TO_CHAR((c.edited_at AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM'), '')
+		FROM task_comments c
+		JOIN tasks t ON t.id = c.task_id
+		WHERE c.task_id = $1 AND t.user_id = $2
+		ORDER BY c.created_at, c.id`, taskID, userID, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task comments: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TaskComment, 0)
+	for rows.Next() {
+		var c TaskComment
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.UserID, &c.UserName, &c.Body, &c.CreatedAt, &c.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task comment: %v", err)
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// GetTaskComment returns a single comment written by the user.
+func GetTaskComment(commentID, userID int) (*TaskComment, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	var c TaskComment
+	err = pool.QueryRow(context.Background(), "SELECT id, task_id, user_id, user_name, body FROM task_comments WHERE id = $1 AND user_id = $2", commentID, userID).Scan(
+		&c.ID, &c.TaskID, &c.UserID, &c.UserName, &c.Body)
+	if err != nil {
+		return nil, ErrCommentNotFound
+	}
+	return &c, nil
+}
+
+// AddTaskComment adds a comment by the user to one of their tasks, recording the author's
+// current user_name (or the local part of their email when no name is set).
+func AddTaskComment(taskID, userID int, body string) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `INSERT INTO task_comments (task_id, user_id, user_name, body)
+		SELECT t.id, u.id, COALESCE(NULLIF(u.user_name, ''), split_part(u.email, '@', 1)), $3
+		FROM tasks t JOIN users u ON u.id = t.user_id
+		WHERE t.id = $1 AND t.user_id = $2`, taskID, userID, body)
+	if err != nil {
+		return fmt.Errorf("failed to add comment: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// UpdateTaskComment changes the body of a comment written by the user and returns its task id.
+func UpdateTaskComment(commentID, userID int, body string) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var taskID int
+	err = pool.QueryRow(context.Background(), `UPDATE task_comments SET body = $3, edited_at = NOW() AT TIME ZONE 'UTC'
+		WHERE id = $1 AND user_id = $2 RETURNING task_id`, commentID, userID, body).Scan(&taskID)
+	if err != nil {
+		return 0, ErrCommentNotFound
+	}
+	return taskID, nil
+}
+
+// DeleteTaskComment removes a comment and returns its task id. The author and the owner
+// of the task may delete it.
+func DeleteTaskComment(commentID, userID int) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var taskID int
+	err = pool.QueryRow(context.Background(), `DELETE FROM task_comments c USING tasks t
+		WHERE c.id = $1 AND t.id = c.task_id AND (c.user_id = $2 OR t.user_id = $2)
+		RETURNING c.task_id`, commentID, userID).Scan(&taskID)
+	if err != nil {
+		return 0, ErrCommentNotFound
+	}
+	return taskID, nil
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Custom field types a project can define.
+const (
+	FieldText     = "text"
+	FieldNumber   = "number"
+	FieldDate     = "date"
+	FieldSelect   = "select"
+	FieldCheckbox = "checkbox"
+)
+
+// FieldTypes lists the custom field types in the order they are offered.
+var FieldTypes = []string{FieldText, FieldNumber, FieldDate, FieldSelect, FieldCheckbox}
+
+// ProjectField is a custom field defined on a project. Every task in the project can
+// carry a value for it. Options are the choices of a select field.
+type ProjectField struct {
+	ID        int
+	ProjectID int
+	Name      string
+	Type      string
+	Options   []string
+	Position  int
+}
+
+// ErrProjectFieldNotFound is returned when a custom field (or the project it is added to)
+// does not exist or belongs to another user.
+var ErrProjectFieldNotFound = errors.New("custom field not found")
+
+// ErrProjectFieldExists is returned when a project already has a field with the same name.
+var ErrProjectFieldExists = errors.New("a field with that name already exists")
+
+// CreateProjectFieldsTables creates the custom field definitions and the per-task values.
+// Values are stored as text in a normalized form (numbers without formatting, dates as
+// YYYY-MM-DD, checkboxes as "true").
+func CreateProjectFieldsTables() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS project_fields (
+            id SERIAL PRIMARY KEY,
+            project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+            name VARCHAR(50) NOT NULL,
+            field_type VARCHAR(20) NOT NULL,
+            options TEXT[] NOT NULL DEFAULT '{}',
+            position INTEGER NOT NULL DEFAULT 0,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE (project_id, name)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create project_fields table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_field_values (
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            field_id INTEGER NOT NULL REFERENCES project_fields (id) ON DELETE CASCADE,
+            value TEXT NOT NULL,
+            PRIMARY KEY (task_id, field_id)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_field_values table: %v", err)
+	}
+
+	// Filtering goes from field to tasks, so index the second key column as well
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_field_values_field_id ON task_field_values(field_id, value)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_field_values.field_id: %v", err)
+	}
+	return nil
+}
+
+// IsValidFieldType reports whether t is one of FieldTypes.
+func IsValidFieldType(t string) bool {
+	for _, ft := range FieldTypes {
+		if t == ft {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProjectFields returns the custom fields of a project owned by the user, in position order.
+func GetProjectFields(projectID, userID int) ([]ProjectField, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT f.id, f.project_id, f.name, f.field_type, f.options, f.position
+		FROM project_fields f JOIN projects p ON p.id = f.project_id
+		WHERE f.project_id = $1 AND p.user_id = $2
+		ORDER BY f.position, f.id`, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project fields: %v", err)
+	}
+	defer rows.Close()
+
+	out := make([]ProjectField, 0)
+	for rows.Next() {
+		var f ProjectField
+		if err := rows.Scan(&f.ID, &f.ProjectID, &f.Name, &f.Type, &f.Options, &f.Position); err != nil {
+			return nil, fmt.Errorf("failed to scan project field: %v", err)
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// CreateProjectField adds a custom field at the end of a project owned by the user.
+func CreateProjectField(projectID, userID int, name, fieldType string, options []string) (*ProjectField, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	if options == nil {
+		options = []string{}
+	}
+	var exists bool
+	err = pool.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM project_fields
+		WHERE project_id = $1 AND LOWER(name) = LOWER($2))`, projectID, name).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check project fields: %v", err)
+	}
+	if exists {
+		return nil, ErrProjectFieldExists
+	}
+
+	var f ProjectField
+	err = pool.QueryRow(context.Background(), `INSERT INTO project_fields (project_id, name, field_type, options, position)
+		SELECT p.id, $3, $4, CAST($5 AS TEXT[]), COALESCE((SELECT MAX(position) + 1 FROM project_fields WHERE project_id = p.id), 0)
+		FROM projects p WHERE p.id = $1 AND p.user_id = $2
+		RETURNING id, project_id, name, field_type, options, position`,
+		projectID, userID, name, fieldType, options).Scan(&f.ID, &f.ProjectID, &f.Name, &f.Type, &f.Options, &f.Position)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProjectFieldNotFound
+		}
+		return nil, fmt.Errorf("failed to create project field: %v", err)
+	}
+	return &f, nil
+}
+
+// DeleteProjectField removes a custom field of one of the user's projects along with its values.
+func DeleteProjectField(fieldID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `DELETE FROM project_fields f USING projects p
+		WHERE f.id = $1 AND p.id = f.project_id AND p.user_id = $2`, fieldID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete project field: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProjectFieldNotFound
+	}
+	return nil
+}
+
+// GetTaskFieldValues returns the custom field values of a task owned by the user, by field id.
+// Only fields of the task's current project are returned.
+func GetTaskFieldValues(taskID, userID int) (map[int]string, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT v.field_id, v.value
+		FROM task_field_values v
+		JOIN tasks t ON t.id = v.task_id
+		JOIN project_fields f ON f.id = v.field_id AND f.project_id = t.project_id
+		WHERE v.task_id = $1 AND t.user_id = $2`, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task field values: %v", err)
+	}
+	defer rows.Close()
+
+	values := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var v string
+		if err := rows.Scan(&id, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan task field value: %v", err)
+		}
+		values[id] = v
+	}
+	return values, rows.Err()
+}
+
+// SetTaskFieldValues stores custom field values on a task owned by the user. Values must
+// already be normalized; an empty value removes it. Fields that do not belong to the
+// task's project are ignored.
+func SetTaskFieldValues(taskID, userID int, values map[int]string) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for fieldID, value := range values {
+		if value == "" {
+			_, err = tx.Exec(ctx, `DELETE FROM task_field_values v USING tasks t
+				WHERE v.task_id = $1 AND v.field_id = $2 AND t.id = v.task_id AND t.user_id = $3`, taskID, fieldID, userID)
+		} else {
+			_, err = tx.Exec(ctx, `INSERT INTO task_field_values (task_id, field_id, value)
+				SELECT t.id, f.id, $4 FROM tasks t JOIN project_fields f ON f.project_id = t.project_id
+				WHERE t.id = $1 AND f.id = $2 AND t.user_id = $3
+				ON CONFLICT (task_id, field_id) DO UPDATE SET value = EXCLUDED.value`, taskID, fieldID, userID, value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set task field value: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit task field values: %v", err)
+	}
+	return nil
+}
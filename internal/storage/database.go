@@ -361,6 +361,180 @@ func MigrateTasksAddDueDate() error {
 	return nil
 }
 
+// MigrateTasksAddParentID adds a nullable parent_id column so tasks can have subtasks
+func MigrateTasksAddParentID() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	// Children are removed together with their parent
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES tasks (id) ON DELETE CASCADE")
+	if err != nil {
+		return fmt.Errorf("failed to add parent_id column to tasks table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on tasks.parent_id: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddRepeatRule adds a nullable repeat_rule column for recurring tasks
+func MigrateTasksAddRepeatRule() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS repeat_rule TEXT")
+	if err != nil {
+		return fmt.Errorf("failed to add repeat_rule column to tasks table: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddPriority adds a priority column (0 = none .. 4 = urgent) and an index
+// for priority-ordered lists
+func MigrateTasksAddPriority() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("failed to add priority column to tasks table: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_tasks_user_priority_position ON tasks(user_id, priority DESC, position)")
+	if err != nil {
+		return fmt.Errorf("failed to create priority index: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddDueTime adds an optional time of day to the due date
+func MigrateTasksAddDueTime() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_time TIME")
+	if err != nil {
+		return fmt.Errorf("failed to add due_time column to tasks table: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddNotes adds the long-form Markdown notes column to tasks
+func MigrateTasksAddNotes() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS notes TEXT")
+	if err != nil {
+		return fmt.Errorf("failed to add notes column to tasks table: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddDeletedAt adds the soft-delete timestamp used by the trash. Tasks with
+// deleted_at set are in the trash and hidden from every list.
+func MigrateTasksAddDeletedAt() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP")
+	if err != nil {
+		return fmt.Errorf("failed to add deleted_at column to tasks table: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks(deleted_at) WHERE deleted_at IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to create index on tasks.deleted_at: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddArchiving adds the completed_at and archived_at columns to tasks and the
+// per-user archive_after_days setting (0 disables automatic archiving). Tasks that are
+// already completed take their last modification as their completion time.
+func MigrateTasksAddArchiving() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP")
+	if err != nil {
+		return fmt.Errorf("failed to add completed_at column to tasks table: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "UPDATE tasks SET completed_at = COALESCE(date_modified, time_stamp) WHERE completed = true AND completed_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to backfill tasks.completed_at: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP")
+	if err != nil {
+		return fmt.Errorf("failed to add archived_at column to tasks table: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_tasks_archived_at ON tasks(user_id, archived_at) WHERE archived_at IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to create index on tasks.archived_at: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "ALTER TABLE users ADD COLUMN IF NOT EXISTS archive_after_days INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("failed to add archive_after_days column to users table: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddHiddenUntil adds the hidden_until date a snoozed task is kept out of the
+// task list until, as a calendar day in its owner's timezone.
+func MigrateTasksAddHiddenUntil() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS hidden_until DATE")
+	if err != nil {
+		return fmt.Errorf("failed to add hidden_until column to tasks table: %v", err)
+	}
+	return nil
+}
+
+// MigrateTasksAddEstimate adds the effort estimate of a task and its unit (hours or points).
+func MigrateTasksAddEstimate() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate NUMERIC(7,2)")
+	if err != nil {
+		return fmt.Errorf("failed to add estimate column to tasks table: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_unit VARCHAR(10)")
+	if err != nil {
+		return fmt.Errorf("failed to add estimate_unit column to tasks table: %v", err)
+	}
+	return nil
+}
+
 // MigrateUsersAddTimezone adds timezone column to users table
 func MigrateUsersAddTimezone() error {
 	pool, err := OpenDatabase()
@@ -409,11 +583,12 @@ func MigrateUsersAddItemsPerPage() error {
 }
 
 type User struct {
-	ID           int
-	Email        string
-	Password     string
-	UserName     string
-	ItemsPerPage int
+	ID               int
+	Email            string
+	Password         string
+	UserName         string
+	ItemsPerPage     int
+	ArchiveAfterDays int // 0 when completed tasks are never archived automatically
 }
 
 func GetUserByEmail(email string) (*User, error) {
@@ -426,7 +601,7 @@ func GetUserByEmail(email string) (*User, error) {
 	var user User
 	// include items_per_page (use COALESCE to ensure default)
 	// Use COALESCE for user_name as well since it can be NULL for newly created users
-	err = pool.QueryRow(context.Background(), "SELECT id, email, password, COALESCE(user_name, ''), COALESCE(items_per_page, 15) FROM users WHERE email=$1", email).Scan(&user.ID, &user.Email, &user.Password, &user.UserName, &user.ItemsPerPage)
+	err = pool.QueryRow(context.Background(), "SELECT id, email, password, COALESCE(user_name, ''), COALESCE(items_per_page, 15), COALESCE(archive_after_days, 0) FROM users WHERE email=$1", email).Scan(&user.ID, &user.Email, &user.Password, &user.UserName, &user.ItemsPerPage, &user.ArchiveAfterDays)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TaskRef is a lightweight reference to a task, used for blocker lists and pickers.
+type TaskRef struct {
+	ID        int
+	Title     string
+	Completed bool
+}
+
+var (
+	// ErrDependencyCycle is returned when a new dependency would make a task (indirectly) block itself.
+	ErrDependencyCycle = errors.New("that dependency would create a cycle")
+	// ErrDependencyNotAllowed is returned when the tasks are not two different top-level tasks of the user.
+	ErrDependencyNotAllowed = errors.New("a task can only be blocked by another of your own top-level tasks")
+)
+
+// CreateTaskDependenciesTable creates the blocked-by table: task_id is blocked by blocker_id.
+func CreateTaskDependenciesTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_dependencies (
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            blocker_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (task_id, blocker_id),
+            CHECK (task_id <> blocker_id)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_dependencies table: %v", err)
+	}
+
+	// Cycle checks walk from blocker to blocker, and deletes cascade from either side
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocker_id ON task_dependencies(blocker_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_dependencies.blocker_id: %v", err)
+	}
+	return nil
+}
+
+// AddTaskDependency records that taskID is blocked by blockerID. Both must be top-level tasks
+// of the user, and the new edge must not close a cycle.
+func AddTaskDependency(taskID, blockerID, userID int) error {
+	if taskID == blockerID {
+		return ErrDependencyCycle
+	}
+
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize dependency changes per user so two concurrent inserts cannot form a cycle together
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('task_dependencies'), $1)", userID); err != nil {
+		return fmt.Errorf("failed to lock dependencies: %v", err)
+	}
+
+	var owned int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM tasks WHERE id IN ($1, $2) AND user_id = $3 AND parent_id IS NULL AND deleted_at IS NULL", taskID, blockerID, userID).Scan(&owned)
+	if err != nil {
+		return fmt.Errorf("failed to verify tasks: %v", err)
+	}
+	if owned != 2 {
+		return ErrDependencyNotAllowed
+	}
+
+	// A cycle exists if the blocker already waits, directly or not, on the task
+	var cycle bool
+	err = tx.QueryRow(ctx, `WITH RECURSIVE chain(id) AS (
+			SELECT blocker_id FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.blocker_id FROM task_dependencies d JOIN chain c ON d.task_id = c.id
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)`, blockerID, taskID).Scan(&cycle)
+	if err != nil {
+		return fmt.Errorf("failed to check for dependency cycles: %v", err)
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO task_dependencies (task_id, blocker_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", taskID, blockerID)
+	if err != nil {
+		return fmt.Errorf("failed to add dependency: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit dependency: %v", err)
+	}
+	return nil
+}
+
+// RemoveTaskDependency deletes the blocked-by link between two of the user's tasks.
+func RemoveTaskDependency(taskID, blockerID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `DELETE FROM task_dependencies
+		WHERE task_id = (SELECT id FROM tasks WHERE id = $1 AND user_id = $3) AND blocker_id = $2`, taskID, blockerID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove dependency: %v", err)
+	}
+	return nil
+}
+
+// GetOpenBlockers returns the incomplete tasks that block the given task.
+func GetOpenBlockers(taskID, userID int) ([]TaskRef, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT b.id, b.title, COALESCE(b.completed, false)
+		FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		JOIN tasks b ON b.id = d.blocker_id
+		WHERE d.task_id = $1 AND t.user_id = $2 AND COALESCE(b.completed, false) = false AND b.deleted_at IS NULL
+		ORDER BY b.title`, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blockers: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TaskRef, 0)
+	for rows.Next() {
+		var ref TaskRef
+		if err := rows.Scan(&ref.ID, &ref.Title, &ref.Completed); err != nil {
+			return nil, fmt.Errorf("failed to scan blocker: %v", err)
+		}
+		list = append(list, ref)
+	}
+	return list, rows.Err()
+}
+
+// GetDependencyCandidates lists the user's incomplete top-level tasks that could block the
+// given task: not the task itself and not already one of its blockers.
+func GetDependencyCandidates(taskID, userID int) ([]TaskRef, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT t.id, t.title, COALESCE(t.completed, false) FROM tasks t
+		WHERE t.user_id = $2 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.id <> $1 AND COALESCE(t.completed, false) = false
+		AND NOT EXISTS (SELECT 1 FROM task_dependencies d WHERE d.task_id = $1 AND d.blocker_id = t.id)
+		ORDER BY t.title LIMIT 200`, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependency candidates: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TaskRef, 0)
+	for rows.Next() {
+		var ref TaskRef
+		if err := rows.Scan(&ref.ID, &ref.Title, &ref.Completed); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency candidate: %v", err)
+		}
+		list = append(list, ref)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// ProjectEffort sums the effort estimates of a project's tasks. Hours and points are
+// kept apart; remaining effort is on open tasks and completed effort on completed ones.
+type ProjectEffort struct {
+	EstimatedHours  float64
+	RemainingHours  float64
+	CompletedHours  float64
+	EstimatedPoints float64
+	RemainingPoints float64
+	CompletedPoints float64
+}
+
+// SetTaskEstimate sets the effort estimate of a task owned by the user. An empty unit
+// clears the estimate.
+func SetTaskEstimate(taskID, userID int, estimate float64, unit string) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `UPDATE tasks SET
+		estimate = CASE WHEN $3 = '' THEN NULL ELSE CAST($4 AS NUMERIC) END, estimate_unit = NULLIF($3, '')
+		WHERE id = $1 AND user_id = $2`, taskID, userID, unit, estimate)
+	if err != nil {
+		return fmt.Errorf("failed to set task estimate: %v", err)
+	}
+	return nil
+}
+
+// GetProjectEffortTotals sums the effort estimates of the user's tasks by project id
+// (0 for tasks without a project). A task whose subtasks carry estimates counts through
+// them: the subtasks' sum replaces the parent's own estimate, so no effort is counted
+// twice. Subtasks count under their parent's project and as completed once either they
+// or their parent are. Archived tasks count; trashed ones do not.
+func GetProjectEffortTotals(userID int) (map[int]ProjectEffort, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT COALESCE(top.project_id, 0), t.estimate_unit,
+		CAST(SUM(t.estimate) AS DOUBLE PRECISION),
+		CAST(COALESCE(SUM(t.estimate) FILTER (WHERE t.completed = true OR top.completed = true), 0) AS DOUBLE PRECISION)
+		FROM tasks t
+		JOIN tasks top ON top.id = COALESCE(t.parent_id, t.id)
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL AND top.deleted_at IS NULL
+			AND t.estimate IS NOT NULL AND t.estimate_unit IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM tasks sub WHERE sub.parent_id = t.id AND sub.deleted_at IS NULL
+				AND sub.estimate IS NOT NULL AND sub.estimate_unit IS NOT NULL)
+		GROUP BY COALESCE(top.project_id, 0), t.estimate_unit`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project effort totals: %v", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[int]ProjectEffort)
+	for rows.Next() {
+		var pid int
+		var unit string
+		var estimated, completed float64
+		if err := rows.Scan(&pid, &unit, &estimated, &completed); err != nil {
+			return nil, fmt.Errorf("failed to scan project effort total: %v", err)
+		}
+		e := totals[pid]
+		switch unit {
+		case "hours":
+			e.EstimatedHours, e.CompletedHours, e.RemainingHours = estimated, completed, estimated-completed
+		case "points":
+			e.EstimatedPoints, e.CompletedPoints, e.RemainingPoints = estimated, completed, estimated-completed
+		}
+		totals[pid] = e
+	}
+	return totals, rows.Err()
+}
@@ -31,6 +31,15 @@ func RunMigrations() error {
 		fmt.Printf("migration: CreateProjectsTable failed: %v\n", err)
 		errCount++
 	}
+	// Ensure tags and the task_tags join table exist
+	if err := CreateTagsTable(); err != nil {
+		fmt.Printf("migration: CreateTagsTable failed: %v\n", err)
+		errCount++
+	}
+	if err := CreateTaskTagsTable(); err != nil {
+		fmt.Printf("migration: CreateTaskTagsTable failed: %v\n", err)
+		errCount++
+	}
 
 	// Non-breaking column migrations
 	if err := MigrateUsersAddTimezone(); err != nil {
@@ -72,6 +81,110 @@ func RunMigrations() error {
 		fmt.Printf("migration: MigrateTasksAddDueDate failed: %v\n", err)
 		errCount++
 	}
+	// Add parent_id column to tasks for subtasks
+	if err := MigrateTasksAddParentID(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddParentID failed: %v\n", err)
+		errCount++
+	}
+	// Add repeat_rule column to tasks for recurring tasks
+	if err := MigrateTasksAddRepeatRule(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddRepeatRule failed: %v\n", err)
+		errCount++
+	}
+	// Add priority column to tasks
+	if err := MigrateTasksAddPriority(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddPriority failed: %v\n", err)
+		errCount++
+	}
+	// Add due_time column and the reminders table
+	if err := MigrateTasksAddDueTime(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddDueTime failed: %v\n", err)
+		errCount++
+	}
+	if err := CreateTaskRemindersTable(); err != nil {
+		fmt.Printf("migration: CreateTaskRemindersTable failed: %v\n", err)
+		errCount++
+	}
+	// Blocked-by relationships between tasks
+	if err := CreateTaskDependenciesTable(); err != nil {
+		fmt.Printf("migration: CreateTaskDependenciesTable failed: %v\n", err)
+		errCount++
+	}
+	// Markdown notes column on tasks
+	if err := MigrateTasksAddNotes(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddNotes failed: %v\n", err)
+		errCount++
+	}
+	// Per-task comment threads
+	if err := CreateTaskCommentsTable(); err != nil {
+		fmt.Printf("migration: CreateTaskCommentsTable failed: %v\n", err)
+		errCount++
+	}
+	// File attachment metadata (contents live in the attachments store)
+	if err := CreateTaskAttachmentsTable(); err != nil {
+		fmt.Printf("migration: CreateTaskAttachmentsTable failed: %v\n", err)
+		errCount++
+	}
+	// Time tracking entries (timers and manual entries)
+	if err := CreateTimeEntriesTable(); err != nil {
+		fmt.Printf("migration: CreateTimeEntriesTable failed: %v\n", err)
+		errCount++
+	}
+	// Soft-delete timestamp for the trash
+	if err := MigrateTasksAddDeletedAt(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddDeletedAt failed: %v\n", err)
+		errCount++
+	}
+	// Per-user undo records for recent task actions
+	if err := CreateUndoActionsTable(); err != nil {
+		fmt.Printf("migration: CreateUndoActionsTable failed: %v\n", err)
+		errCount++
+	}
+	// Field-level history of task changes
+	if err := CreateTaskRevisionsTable(); err != nil {
+		fmt.Printf("migration: CreateTaskRevisionsTable failed: %v\n", err)
+		errCount++
+	}
+	// Completion and archive timestamps, and the per-user archive setting
+	if err := MigrateTasksAddArchiving(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddArchiving failed: %v\n", err)
+		errCount++
+	}
+	// Snooze dates that hide tasks from the list
+	if err := MigrateTasksAddHiddenUntil(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddHiddenUntil failed: %v\n", err)
+		errCount++
+	}
+	// Effort estimates in hours or points
+	if err := MigrateTasksAddEstimate(); err != nil {
+		fmt.Printf("migration: MigrateTasksAddEstimate failed: %v\n", err)
+		errCount++
+	}
+	// Per-project custom fields and their values on tasks
+	if err := CreateProjectFieldsTables(); err != nil {
+		fmt.Printf("migration: CreateProjectFieldsTables failed: %v\n", err)
+		errCount++
+	}
+	// Per-user task and project templates
+	if err := CreateTaskTemplatesTables(); err != nil {
+		fmt.Printf("migration: CreateTaskTemplatesTables failed: %v\n", err)
+		errCount++
+	}
+	// Workflow statuses per user or project, and the status of each task
+	if err := CreateTaskStatusesTables(); err != nil {
+		fmt.Printf("migration: CreateTaskStatusesTables failed: %v\n", err)
+		errCount++
+	}
+	// Every user has a default workflow and every task a status that matches it
+	if err := SeedDefaultStatuses(); err != nil {
+		fmt.Printf("migration: SeedDefaultStatuses failed: %v\n", err)
+		errCount++
+	}
+	// Saved task list filters per user
+	if err := CreateSavedViewsTable(); err != nil {
+		fmt.Printf("migration: CreateSavedViewsTable failed: %v\n", err)
+		errCount++
+	}
 
 	// Ensure site_settings table exists
 	if err := CreateSiteSettingsTable(); err != nil {
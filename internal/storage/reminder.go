@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReminderCandidate is a reminder on an open task with a due date, joined with what is
+// needed to decide whether it is due and to deliver it.
+type ReminderCandidate struct {
+	ID            int
+	OffsetMinutes int
+	TaskID        int
+	Title         string
+	DueDate       string // YYYY-MM-DD
+	DueTime       string // HH:MM, empty for date-only tasks
+	Email         string
+	Timezone      string
+	SentFor       *time.Time // due instant the reminder was last delivered for
+}
+
+// CreateTaskRemindersTable creates the table holding reminder offsets per task. sent_for
+// records the due instant a reminder was delivered for, so a restart never sends it twice
+// while moving the due date re-arms it.
+func CreateTaskRemindersTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_reminders (
+            id SERIAL PRIMARY KEY,
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            offset_minutes INTEGER NOT NULL CHECK (offset_minutes >= 0),
+            sent_for TIMESTAMPTZ,
+            sent_at TIMESTAMPTZ,
+            UNIQUE (task_id, offset_minutes)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_reminders table: %v", err)
+	}
+	return nil
+}
+
+// GetReminderOffsets returns the reminder offsets (minutes before due) of a task owned by the user.
+func GetReminderOffsets(taskID int, userID int) ([]int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT r.offset_minutes FROM task_reminders r
+		JOIN tasks t ON t.id = r.task_id
+		WHERE r.task_id = $1 AND t.user_id = $2 ORDER BY r.offset_minutes`, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task reminders: %v", err)
+	}
+	defer rows.Close()
+
+	offsets := make([]int, 0)
+	for rows.Next() {
+		var m int
+		if err := rows.Scan(&m); err != nil {
+			return nil, fmt.Errorf("failed to scan task reminder: %v", err)
+		}
+		offsets = append(offsets, m)
+	}
+	return offsets, rows.Err()
+}
+
+// SetTaskReminders replaces the reminder offsets of a task owned by the user. Offsets that
+// are kept retain their delivery record.
+func SetTaskReminders(taskID int, userID int, offsets []int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM task_reminders
+		WHERE task_id = (SELECT id FROM tasks WHERE id = $1 AND user_id = $2) AND NOT (offset_minutes = ANY($3))`,
+		taskID, userID, offsets)
+	if err != nil {
+		return fmt.Errorf("failed to clear task reminders: %v", err)
+	}
+	if len(offsets) > 0 {
+		_, err = tx.Exec(ctx, `INSERT INTO task_reminders (task_id, offset_minutes)
+			SELECT t.id, o FROM tasks t, UNNEST($3::int[]) AS o
+			WHERE t.id = $1 AND t.user_id = $2
+			ON CONFLICT (task_id, offset_minutes) DO NOTHING`, taskID, userID, offsets)
+		if err != nil {
+			return fmt.Errorf("failed to add task reminders: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit task reminders: %v", err)
+	}
+	return nil
+}
+
+// ListReminderCandidates returns reminders on incomplete tasks of active users whose due
+// date lies near enough to today for a reminder to fire. The exact send time depends on
+// the user's timezone and is decided by the caller.
+func ListReminderCandidates() ([]ReminderCandidate, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	// Dates are widened by a day on both sides to cover every timezone
+	rows, err := pool.Query(context.Background(), `SELECT r.id, r.offset_minutes, t.id, t.title,
+		CAST(t.due_date AS TEXT), COALESCE(TO_CHAR(t.due_time, 'HH24:MI'), ''),
+		u.email, COALESCE(u.timezone, ''), r.sent_for
+		FROM task_reminders r
+		JOIN tasks t ON t.id = r.task_id
+		JOIN users u ON u.id = t.user_id
+		WHERE t.due_date IS NOT NULL
+		AND COALESCE(t.completed, false) = false
+		AND t.deleted_at IS NULL
+		AND COALESCE(u.is_banned, false) = false
+		AND t.due_date >= CURRENT_DATE - 1
+		AND t.due_date <= CURRENT_DATE + 1 + (r.offset_minutes / 1440)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reminders: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]ReminderCandidate, 0)
+	for rows.Next() {
+		var c ReminderCandidate
+		if err := rows.Scan(&c.ID, &c.OffsetMinutes, &c.TaskID, &c.Title, &c.DueDate, &c.DueTime, &c.Email, &c.Timezone, &c.SentFor); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %v", err)
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// ClaimReminder records that the reminder is being delivered for the given due instant.
+// It returns false when it was already delivered for that instant, e.g. by an earlier run.
+func ClaimReminder(id int, dueAt time.Time) (bool, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return false, err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `UPDATE task_reminders SET sent_for = $2, sent_at = NOW()
+		WHERE id = $1 AND sent_for IS DISTINCT FROM $2`, id, dueAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %v", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseReminder undoes ClaimReminder after a failed delivery so it is retried.
+func ReleaseReminder(id int, dueAt time.Time) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "UPDATE task_reminders SET sent_for = NULL, sent_at = NULL WHERE id = $1 AND sent_for = $2", id, dueAt)
+	if err != nil {
+		return fmt.Errorf("failed to release reminder: %v", err)
+	}
+	return nil
+}
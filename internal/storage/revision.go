@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RevisionState is the part of a task whose changes are kept in its history.
+type RevisionState struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	DueDate     string `json:"due_date"` // YYYY-MM-DD, empty when unset
+	ProjectID   *int   `json:"project_id"`
+	ProjectName string `json:"project_name"` // recorded so the history survives renaming or deleting the project
+	Completed   bool   `json:"completed"`
+}
+
+// TaskRevision is one recorded change to a task: its tracked fields before and after,
+// who made it and when. UserName is recorded with the change like comment authors.
+type TaskRevision struct {
+	ID        int
+	TaskID    int
+	UserName  string
+	Before    RevisionState
+	After     RevisionState
+	CreatedAt string // formatted in the viewer's timezone
+}
+
+// ErrRevisionNotFound is returned when a revision does not exist or belongs to another user's task.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// CreateTaskRevisionsTable creates the table holding task revisions. Timestamps are stored
+// in UTC like tasks.time_stamp.
+func CreateTaskRevisionsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_revisions (
+            id SERIAL PRIMARY KEY,
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            user_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+            user_name VARCHAR(100) NOT NULL DEFAULT '',
+            before_state JSONB NOT NULL,
+            after_state JSONB NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_revisions table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_revisions_task_id ON task_revisions(task_id, created_at)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_revisions.task_id: %v", err)
+	}
+	return nil
+}
+
+// readRevisionStateTx reads the tracked fields of a task of the user, locking its row.
+func readRevisionStateTx(ctx context.Context, tx pgx.Tx, taskID, userID int) (RevisionState, error) {
+	var s RevisionState
+	err := tx.QueryRow(ctx, `SELECT t.title, COALESCE(t.description,''), COALESCE(CAST(t.due_date AS TEXT),''),
+		t.project_id, COALESCE(p.name,''), COALESCE(t.completed,false)
+		FROM tasks t LEFT JOIN projects p ON p.id = t.project_id
+		WHERE t.id = $1 AND t.user_id = $2 FOR UPDATE OF t`, taskID, userID).Scan(
+		&s.Title, &s.Description, &s.DueDate, &s.ProjectID, &s.ProjectName, &s.Completed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s, ErrRevisionNotFound
+		}
+		return s, fmt.Errorf("failed to read task state: %v", err)
+	}
+	return s, nil
+}
+
+// recordRevisionTx stores a revision of the task if its tracked fields differ from before.
+func recordRevisionTx(ctx context.Context, tx pgx.Tx, taskID, userID int, before RevisionState) error {
+	after, err := readRevisionStateTx(ctx, tx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	if before.Equal(after) {
+		return nil
+	}
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision: %v", err)
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision: %v", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO task_revisions (task_id, user_id, user_name, before_state, after_state)
+		SELECT $1, u.id, COALESCE(NULLIF(u.user_name, ''), split_part(u.email, '@', 1)), $3, $4
+		FROM users u WHERE u.id = $2`, taskID, userID, string(beforeJSON), string(afterJSON))
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %v", err)
+	}
+	return nil
+}
+
+// Equal reports whether two states have the same tracked values. The project name is
+// only a label and is not compared.
+func (s RevisionState) Equal(o RevisionState) bool {
+	sameProject := (s.ProjectID == nil && o.ProjectID == nil) ||
+		(s.ProjectID != nil && o.ProjectID != nil && *s.ProjectID == *o.ProjectID)
+	return sameProject && s.Title == o.Title && s.Description == o.Description &&
+		s.DueDate == o.DueDate && s.Completed == o.Completed
+}
+
+// GetRevisionState reads the tracked fields of a task of the user, to be passed to
+// RecordTaskRevision once the task has been changed.
+func GetRevisionState(taskID, userID int) (RevisionState, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return RevisionState{}, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return RevisionState{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	return readRevisionStateTx(ctx, tx, taskID, userID)
+}
+
+// RecordTaskRevision records the change made to a task by the user since before was read.
+// Nothing is recorded when the tracked fields are unchanged.
+func RecordTaskRevision(taskID, userID int, before RevisionState) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := recordRevisionTx(ctx, tx, taskID, userID, before); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit revision: %v", err)
+	}
+	return nil
+}
+
+// GetTaskRevisions returns the revisions of a task owned by the user, newest first, with
+// timestamps formatted in the given timezone.
+func GetTaskRevisions(taskID, userID int, timezone string) ([]TaskRevision, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT r.id, r.task_id, r.user_name, r.before_state, r.after_state,
+		TO_CHAR((r.created_at AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM')
+		FROM task_revisions r
+		JOIN tasks t ON t.id = r.task_id
+		WHERE r.task_id = $1 AND t.user_id = $2
+		ORDER BY r.created_at DESC, r.id DESC`, taskID, userID, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task revisions: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TaskRevision, 0)
+	for rows.Next() {
+		var rev TaskRevision
+		var before, after []byte
+		if err := rows.Scan(&rev.ID, &rev.TaskID, &rev.UserName, &before, &after, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task revision: %v", err)
+		}
+		if err := json.Unmarshal(before, &rev.Before); err != nil {
+			return nil, fmt.Errorf("failed to decode task revision: %v", err)
+		}
+		if err := json.Unmarshal(after, &rev.After); err != nil {
+			return nil, fmt.Errorf("failed to decode task revision: %v", err)
+		}
+		list = append(list, rev)
+	}
+	return list, rows.Err()
+}
+
+// RevertTaskToRevision puts a task of the user back into the state it had after the given
+// revision, or before it when original is set (used to go back past the oldest revision).
+// A project that has since been deleted is left unset. The revert is itself recorded as a
+// revision. It returns the task and its tracked fields before and after the revert, which
+// are equal when the task already matched.
+func RevertTaskToRevision(revisionID, userID int, original bool) (int, RevisionState, RevisionState, error) {
+	var from, to RevisionState
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, from, to, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, from, to, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var taskID int
+	var payload []byte
+	column := "after_state"
+	if original {
+		column = "before_state"
+	}
+	err = tx.QueryRow(ctx, `SELECT r.task_id, r.`+column+` FROM task_revisions r
+		JOIN tasks t ON t.id = r.task_id
+		WHERE r.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL`, revisionID, userID).Scan(&taskID, &payload)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, from, to, ErrRevisionNotFound
+		}
+		return 0, from, to, fmt.Errorf("failed to load revision: %v", err)
+	}
+	var target RevisionState
+	if err := json.Unmarshal(payload, &target); err != nil {
+		return 0, from, to, fmt.Errorf("failed to decode revision: %v", err)
+	}
+
+	from, err = readRevisionStateTx(ctx, tx, taskID, userID)
+	if err != nil {
+		return 0, from, to, err
+	}
+	if from.Equal(target) {
+		return taskID, from, from, nil
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE tasks SET title = $1, description = $2, due_date = CAST(NULLIF($3, '') AS DATE),
+		project_id = (SELECT id FROM projects WHERE id = $4 AND user_id = $6),
+		due_time = CASE WHEN $3 = '' THEN NULL ELSE due_time END,
+		completed = $5, completed_at = CASE WHEN $5 THEN COALESCE(completed_at, NOW() AT TIME ZONE 'UTC') END,
+		status_id = `+StatusAfterSQL("CAST($5 AS BOOLEAN)", "(SELECT id FROM projects WHERE id = $4 AND user_id = $6)")+`,
+		archived_at = CASE WHEN $5 THEN archived_at END, date_modified = NOW() AT TIME ZONE 'UTC'
+		WHERE id = $7 AND user_id = $6`,
+		target.Title, target.Description, target.DueDate, target.ProjectID, target.Completed, userID, taskID)
+	if err != nil {
+		return 0, from, to, fmt.Errorf("failed to revert task: %v", err)
+	}
+	if err := recordRevisionTx(ctx, tx, taskID, userID, from); err != nil {
+		return 0, from, to, err
+	}
+	if to, err = readRevisionStateTx(ctx, tx, taskID, userID); err != nil {
+		return 0, from, to, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, from, to, fmt.Errorf("failed to commit revert: %v", err)
+	}
+	return taskID, from, to, nil
+}
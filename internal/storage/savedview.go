@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Completion filters of a saved view.
+const (
+	CompletionOpen = "open"
+	CompletionDone = "done"
+)
+
+// SavedView is a named combination of task list filters, shown in the views sidebar.
+// The filters are kept as entered so a view follows the tasks as they change.
+type SavedView struct {
+	ID         int
+	Name       string
+	Position   int
+	Project    string // "" for all projects, "0" for tasks without a project, or a project id
+	Completion string // "", CompletionOpen or CompletionDone
+	DueFrom    string // YYYY-MM-DD, empty for no lower bound
+	DueTo      string // YYYY-MM-DD, empty for no upper bound
+	Query      string // text matched against task titles and descriptions
+}
+
+// ErrSavedViewNotFound is returned when a saved view does not exist or belongs to another user.
+var ErrSavedViewNotFound = errors.New("saved view not found")
+
+// ErrSavedViewExists is returned when the user already has a saved view with the same name.
+var ErrSavedViewExists = errors.New("a saved view with that name already exists")
+
+// CreateSavedViewsTable creates the per-user saved views.
+func CreateSavedViewsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS saved_views (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+            name VARCHAR(50) NOT NULL,
+            position INTEGER NOT NULL DEFAULT 0,
+            project VARCHAR(20) NOT NULL DEFAULT '',
+            completion VARCHAR(10) NOT NULL DEFAULT '',
+            due_from DATE,
+            due_to DATE,
+            query TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+            UNIQUE (user_id, name)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create saved_views table: %v", err)
+	}
+	return nil
+}
+
+const savedViewColumns = `SELECT id, name, position, project, completion,
+	COALESCE(CAST(due_from AS TEXT), ''), COALESCE(CAST(due_to AS TEXT), ''), query FROM saved_views `
+
+// GetSavedViews returns the user's saved views in sidebar order.
+func GetSavedViews(userID int) ([]SavedView, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), savedViewColumns+"WHERE user_id = $1 ORDER BY position, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved views: %v", err)
+	}
+	defer rows.Close()
+
+	views := make([]SavedView, 0)
+	for rows.Next() {
+		var v SavedView
+		if err := rows.Scan(&v.ID, &v.Name, &v.Position, &v.Project, &v.Completion, &v.DueFrom, &v.DueTo, &v.Query); err != nil {
+			return nil, fmt.Errorf("failed to scan saved view: %v", err)
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// GetSavedView returns one of the user's saved views.
+func GetSavedView(id, userID int) (SavedView, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return SavedView{}, err
+	}
+	defer CloseDatabase(pool)
+
+	var v SavedView
+	err = pool.QueryRow(context.Background(), savedViewColumns+"WHERE id = $1 AND user_id = $2", id, userID).
+		Scan(&v.ID, &v.Name, &v.Position, &v.Project, &v.Completion, &v.DueFrom, &v.DueTo, &v.Query)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SavedView{}, ErrSavedViewNotFound
+		}
+		return SavedView{}, fmt.Errorf("failed to find saved view: %v", err)
+	}
+	return v, nil
+}
+
+// CreateSavedView adds a saved view at the end of the user's views and returns its id.
+func CreateSavedView(userID int, v SavedView) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var id int
+	err = pool.QueryRow(context.Background(), `INSERT INTO saved_views (user_id, name, position, project, completion, due_from, due_to, query)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM saved_views WHERE user_id = $1), $3, $4,
+			CAST(NULLIF($5, '') AS DATE), CAST(NULLIF($6, '') AS DATE), $7)
+		RETURNING id`, userID, v.Name, v.Project, v.Completion, v.DueFrom, v.DueTo, v.Query).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, ErrSavedViewExists
+		}
+		return 0, fmt.Errorf("failed to create saved view: %v", err)
+	}
+	return id, nil
+}
+
+// DeleteSavedView removes one of the user's saved views.
+func DeleteSavedView(id, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), "DELETE FROM saved_views WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved view: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSavedViewNotFound
+	}
+	return nil
+}
+
+// ReorderSavedViews puts the user's saved views in the order of ids. Views of other
+// users are not touched; views missing from ids keep their place after the others.
+func ReorderSavedViews(userID int, ids []int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for i, id := range ids {
+		if _, err := tx.Exec(ctx, "UPDATE saved_views SET position = $1 WHERE id = $2 AND user_id = $3", i+1, id, userID); err != nil {
+			return fmt.Errorf("failed to update saved view order: %v", err)
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE saved_views SET position = $1 + position WHERE user_id = $2 AND NOT (id = ANY($3))`, len(ids), userID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to update saved view order: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit saved view order: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSnoozeNotAllowed is returned when a task cannot be snoozed: it does not belong to the
+// user, is completed, or is in the trash or the archive.
+var ErrSnoozeNotAllowed = errors.New("only open tasks can be snoozed")
+
+// SnoozeTask hides an open task of the user from the task list until the given date
+// (YYYY-MM-DD). An empty date brings the task back right away.
+func SnoozeTask(taskID, userID int, until string) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `UPDATE tasks SET hidden_until = CAST(NULLIF($3, '') AS DATE)
+		WHERE id = $1 AND user_id = $2 AND parent_id IS NULL AND COALESCE(completed, false) = false
+		AND archived_at IS NULL AND deleted_at IS NULL`, taskID, userID, until)
+	if err != nil {
+		return fmt.Errorf("failed to snooze task: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSnoozeNotAllowed
+	}
+	return nil
+}
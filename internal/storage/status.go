@@ -0,0 +1,569 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TaskStatus is one step of a workflow. A user has a default workflow (ProjectID 0) and a
+// project can replace it with its own. Exactly one status of each workflow is the done
+// status; a task's completed flag mirrors whether it is in it, so filters and archiving
+// keep reading completed. Every update that completes, reopens or moves a task writes
+// the matching status in the same statement (see StatusAfterSQL).
+type TaskStatus struct {
+	ID        int
+	ProjectID int // 0 for the user's default workflow
+	Name      string
+	Position  int
+	IsDone    bool
+}
+
+// DefaultStatuses is the workflow every user starts with.
+var DefaultStatuses = []TaskStatus{
+	{Name: "To do"},
+	{Name: "In progress"},
+	{Name: "Blocked"},
+	{Name: "Done", IsDone: true},
+}
+
+var (
+	// ErrStatusNotFound is returned when a status (or the project of a workflow) does not
+	// exist or belongs to another user.
+	ErrStatusNotFound = errors.New("status not found")
+	// ErrStatusExists is returned when a workflow already has a status with the same name.
+	ErrStatusExists = errors.New("a status with that name already exists")
+	// ErrStatusRequired is returned when deleting the done status or the last open status
+	// of a workflow.
+	ErrStatusRequired = errors.New("a workflow needs a done status and at least one open status")
+)
+
+// CreateTaskStatusesTables creates the workflow statuses and the status column on tasks.
+// A task without a status, or whose status is not in its project's workflow, is shown in
+// the workflow's first open status, or its done status once completed.
+func CreateTaskStatusesTables() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_statuses (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+            project_id INTEGER REFERENCES projects (id) ON DELETE CASCADE,
+            name VARCHAR(50) NOT NULL,
+            position INTEGER NOT NULL DEFAULT 0,
+            is_done BOOLEAN NOT NULL DEFAULT false,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_statuses table: %v", err)
+	}
+
+	// Names are unique within a workflow, and a workflow has a single done status
+	_, err = pool.Exec(context.Background(), "CREATE UNIQUE INDEX IF NOT EXISTS idx_task_statuses_name ON task_statuses(user_id, COALESCE(project_id, 0), LOWER(name))")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_statuses.name: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "CREATE UNIQUE INDEX IF NOT EXISTS idx_task_statuses_done ON task_statuses(user_id, COALESCE(project_id, 0)) WHERE is_done")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_statuses.is_done: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), "ALTER TABLE tasks ADD COLUMN IF NOT EXISTS status_id INTEGER REFERENCES task_statuses (id) ON DELETE SET NULL")
+	if err != nil {
+		return fmt.Errorf("failed to add status_id column to tasks: %v", err)
+	}
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_tasks_status_id ON tasks(status_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on tasks.status_id: %v", err)
+	}
+	return nil
+}
+
+// SeedDefaultStatusesTx gives the user in userID, or every user when userID is 0, the
+// default workflow from DefaultStatuses unless they already have one. New accounts are
+// seeded at signup and existing ones by SeedDefaultStatuses as a migration.
+func SeedDefaultStatusesTx(ctx context.Context, tx pgx.Tx, userID int) error {
+	names := make([]string, len(DefaultStatuses))
+	positions := make([]int, len(DefaultStatuses))
+	done := make([]bool, len(DefaultStatuses))
+	for i, s := range DefaultStatuses {
+		names[i], positions[i], done[i] = s.Name, i, s.IsDone
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO task_statuses (user_id, name, position, is_done)
+		SELECT u.id, d.name, d.position, d.is_done
+		FROM users u, unnest(CAST($2 AS TEXT[]), CAST($3 AS INTEGER[]), CAST($4 AS BOOLEAN[])) AS d(name, position, is_done)
+		WHERE ($1 = 0 OR u.id = $1)
+		AND NOT EXISTS (SELECT 1 FROM task_statuses s WHERE s.user_id = u.id AND s.project_id IS NULL)
+		ON CONFLICT DO NOTHING`, userID, names, positions, done)
+	if err != nil {
+		return fmt.Errorf("failed to create default statuses: %v", err)
+	}
+	return nil
+}
+
+// SeedDefaultStatuses gives every user without a default workflow one, then points each
+// top-level task at the status of its workflow that matches whether it is completed.
+// Tasks written before every completion path kept the status in step are corrected here.
+func SeedDefaultStatuses() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := SeedDefaultStatusesTx(ctx, tx, 0); err != nil {
+		return err
+	}
+	status := StatusAfterSQL("COALESCE(tasks.completed, false)", "tasks.project_id")
+	_, err = tx.Exec(ctx, "UPDATE tasks SET status_id = "+status+" WHERE parent_id IS NULL AND status_id IS DISTINCT FROM "+status)
+	if err != nil {
+		return fmt.Errorf("failed to update task statuses: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit default statuses: %v", err)
+	}
+	return nil
+}
+
+// GetStatuses returns every status of the user: the default workflow first, then each
+// project's own, in position order.
+func GetStatuses(userID int) ([]TaskStatus, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+	return QueryStatuses(pool, userID)
+}
+
+// QueryStatuses is GetStatuses on an open pool, for callers that already hold one.
+func QueryStatuses(pool *pgxpool.Pool, userID int) ([]TaskStatus, error) {
+	rows, err := pool.Query(context.Background(), `SELECT id, COALESCE(project_id, 0), name, position, is_done
+		FROM task_statuses WHERE user_id = $1
+		ORDER BY project_id NULLS FIRST, position, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statuses: %v", err)
+	}
+	defer rows.Close()
+
+	out := make([]TaskStatus, 0)
+	for rows.Next() {
+		var s TaskStatus
+		if err := rows.Scan(&s.ID, &s.ProjectID, &s.Name, &s.Position, &s.IsDone); err != nil {
+			return nil, fmt.Errorf("failed to scan status: %v", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// WorkflowFor picks the workflow of a project out of the statuses returned by
+// GetStatuses: the project's own statuses, or the default workflow when it has none.
+// It reports whether the project has its own.
+func WorkflowFor(statuses []TaskStatus, projectID int) ([]TaskStatus, bool) {
+	own := make([]TaskStatus, 0)
+	defaults := make([]TaskStatus, 0)
+	for _, s := range statuses {
+		switch {
+		case projectID != 0 && s.ProjectID == projectID:
+			own = append(own, s)
+		case s.ProjectID == 0:
+			defaults = append(defaults, s)
+		}
+	}
+	if len(own) > 0 {
+		return own, true
+	}
+	return defaults, false
+}
+
+// GetWorkflow returns the workflow of one of the user's projects (0 for the default
+// workflow) and whether the project has its own.
+func GetWorkflow(userID, projectID int) ([]TaskStatus, bool, error) {
+	statuses, err := GetStatuses(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	workflow, own := WorkflowFor(statuses, projectID)
+	return workflow, own, nil
+}
+
+// ResolveStatus returns the status a task is in: its stored status when that belongs to
+// the workflow and agrees with completed, otherwise the done status for a completed task
+// or the first open status. Tasks get no status when they are created or when theirs is
+// deleted, and show in the first open status until they are moved.
+func ResolveStatus(workflow []TaskStatus, statusID int, completed bool) TaskStatus {
+	var fallback TaskStatus
+	found := false
+	for _, s := range workflow {
+		if s.ID == statusID && s.IsDone == completed {
+			return s
+		}
+		if !found && s.IsDone == completed {
+			fallback, found = s, true
+		}
+	}
+	return fallback
+}
+
+// StatusAfterSQL returns a subquery for the status a task should be in once an UPDATE of
+// tasks sets its completed flag to the SQL expression done and its project to project.
+// Inside the UPDATE, column references still read the row as it was; qualify them with
+// tasks, since the subquery has task_statuses columns of the same names. The status comes
+// from the project's workflow, or the default one when the project has none.
+func StatusAfterSQL(done, project string) string {
+	return statusInWorkflowSQL(done, `CASE WHEN EXISTS (SELECT 1 FROM task_statuses w
+		WHERE w.user_id = tasks.user_id AND w.project_id = `+project+`) THEN `+project+` ELSE 0 END`)
+}
+
+// statusInWorkflowSQL returns a subquery for the status of the workflow whose project is
+// the SQL expression workflow (0 for the default one) that agrees with done: the task's
+// current status if it qualifies, else the status of the same name, else the workflow's
+// done status or its first open status.
+func statusInWorkflowSQL(done, workflow string) string {
+	return `(SELECT s.id FROM task_statuses s
+		WHERE s.user_id = tasks.user_id AND COALESCE(s.project_id, 0) = ` + workflow + ` AND s.is_done = ` + done + `
+		ORDER BY CASE WHEN s.id = tasks.status_id THEN 0
+			WHEN LOWER(s.name) = (SELECT LOWER(c.name) FROM task_statuses c WHERE c.id = tasks.status_id) THEN 1
+			ELSE 2 END, s.position, s.id
+		LIMIT 1)`
+}
+
+// workflowCond is the condition selecting the statuses of one workflow of the user bound to
+// $1, with the project bound to $2 (0 for the default workflow).
+const workflowCond = "user_id = $1 AND COALESCE(project_id, 0) = $2"
+
+// CreateStatus adds an open status at the end of a workflow of the user. Adding a status
+// to a project that uses the default workflow gives it its own, starting as a copy.
+func CreateStatus(userID, projectID int, name string) error {
+	if projectID != 0 {
+		if err := CustomizeProjectWorkflow(projectID, userID); err != nil {
+			return err
+		}
+	}
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `INSERT INTO task_statuses (user_id, project_id, name, position)
+		SELECT $1, NULLIF($2, 0), $3, COALESCE((SELECT MAX(position) + 1 FROM task_statuses WHERE `+workflowCond+`), 0)`,
+		userID, projectID, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrStatusExists
+		}
+		return fmt.Errorf("failed to create status: %v", err)
+	}
+	return nil
+}
+
+// lockWorkflowTx reads the workflow a status of the user belongs to, locking its rows.
+func lockWorkflowTx(ctx context.Context, tx pgx.Tx, statusID, userID int) ([]TaskStatus, int, error) {
+	var projectID int
+	err := tx.QueryRow(ctx, "SELECT COALESCE(project_id, 0) FROM task_statuses WHERE id = $1 AND user_id = $2", statusID, userID).Scan(&projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, ErrStatusNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to find status: %v", err)
+	}
+	rows, err := tx.Query(ctx, `SELECT id, COALESCE(project_id, 0), name, position, is_done
+		FROM task_statuses WHERE `+workflowCond+` ORDER BY position, id FOR UPDATE`, userID, projectID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query statuses: %v", err)
+	}
+	defer rows.Close()
+
+	workflow := make([]TaskStatus, 0)
+	for rows.Next() {
+		var s TaskStatus
+		if err := rows.Scan(&s.ID, &s.ProjectID, &s.Name, &s.Position, &s.IsDone); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan status: %v", err)
+		}
+		workflow = append(workflow, s)
+	}
+	return workflow, projectID, rows.Err()
+}
+
+// DeleteStatus removes a status of the user. Its tasks fall back to the first open status.
+// The done status and the last open status of a workflow cannot be deleted.
+func DeleteStatus(statusID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	workflow, _, err := lockWorkflowTx(ctx, tx, statusID, userID)
+	if err != nil {
+		return err
+	}
+	open := 0
+	for _, s := range workflow {
+		if s.ID == statusID && s.IsDone {
+			return ErrStatusRequired
+		}
+		if !s.IsDone {
+			open++
+		}
+	}
+	if open <= 1 {
+		return ErrStatusRequired
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM task_statuses WHERE id = $1 AND user_id = $2", statusID, userID); err != nil {
+		return fmt.Errorf("failed to delete status: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit status deletion: %v", err)
+	}
+	return nil
+}
+
+// SetDoneStatus makes a status the done status of its workflow. Tasks in the workflow's
+// statuses are completed or reopened to match, so counts follow the new done state.
+func SetDoneStatus(statusID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	workflow, projectID, err := lockWorkflowTx(ctx, tx, statusID, userID)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(workflow))
+	for _, s := range workflow {
+		ids = append(ids, s.ID)
+	}
+
+	// The partial index allows one done status per workflow, so clear the old one first
+	if _, err := tx.Exec(ctx, "UPDATE task_statuses SET is_done = false WHERE "+workflowCond, userID, projectID); err != nil {
+		return fmt.Errorf("failed to update statuses: %v", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE task_statuses SET is_done = true WHERE id = $1", statusID); err != nil {
+		return fmt.Errorf("failed to update status: %v", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE tasks t SET completed = s.is_done,
+		completed_at = CASE WHEN s.is_done THEN NOW() AT TIME ZONE 'UTC' END, archived_at = NULL,
+		date_modified = NOW() AT TIME ZONE 'UTC'
+		FROM task_statuses s
+		WHERE t.status_id = s.id AND s.id = ANY($1) AND COALESCE(t.completed, false) <> s.is_done`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to update tasks: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit done status: %v", err)
+	}
+	return nil
+}
+
+// MoveStatus swaps a status with its neighbour in the workflow: delta -1 moves it one
+// place earlier, 1 one place later.
+func MoveStatus(statusID, userID, delta int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	workflow, _, err := lockWorkflowTx(ctx, tx, statusID, userID)
+	if err != nil {
+		return err
+	}
+	for i, s := range workflow {
+		if s.ID != statusID {
+			continue
+		}
+		j := i + delta
+		if j < 0 || j >= len(workflow) {
+			return nil
+		}
+		workflow[i], workflow[j] = workflow[j], workflow[i]
+		break
+	}
+	for i, s := range workflow {
+		if _, err := tx.Exec(ctx, "UPDATE task_statuses SET position = $1 WHERE id = $2", i, s.ID); err != nil {
+			return fmt.Errorf("failed to reorder statuses: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit status order: %v", err)
+	}
+	return nil
+}
+
+// CustomizeProjectWorkflow gives one of the user's projects its own workflow, copied from
+// the default one. Tasks of the project keep their status. It does nothing when the
+// project already has its own.
+func CustomizeProjectWorkflow(projectID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var owned bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND user_id = $2)", projectID, userID).Scan(&owned)
+	if err != nil {
+		return fmt.Errorf("failed to check project: %v", err)
+	}
+	if !owned {
+		return ErrStatusNotFound
+	}
+	tag, err := tx.Exec(ctx, `INSERT INTO task_statuses (user_id, project_id, name, position, is_done)
+		SELECT user_id, $2, name, position, is_done FROM task_statuses
+		WHERE user_id = $1 AND project_id IS NULL
+		AND NOT EXISTS (SELECT 1 FROM task_statuses WHERE user_id = $1 AND project_id = $2)`, userID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to copy statuses: %v", err)
+	}
+	if tag.RowsAffected() > 0 {
+		if err := remapProjectStatusesTx(ctx, tx, projectID, userID, true); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit workflow: %v", err)
+	}
+	return nil
+}
+
+// ResetProjectWorkflow drops the own workflow of one of the user's projects so it uses
+// the default one again. Tasks move to the default status of the same name, if any.
+func ResetProjectWorkflow(projectID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := remapProjectStatusesTx(ctx, tx, projectID, userID, false); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM task_statuses WHERE user_id = $1 AND project_id = $2", userID, projectID); err != nil {
+		return fmt.Errorf("failed to delete statuses: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit workflow: %v", err)
+	}
+	return nil
+}
+
+// remapProjectStatusesTx points the project's tasks at the status of the same name in
+// the project's own workflow (toProject) or in the default workflow. Tasks whose status
+// has no namesake go to the done status or the first open status.
+func remapProjectStatusesTx(ctx context.Context, tx pgx.Tx, projectID, userID int, toProject bool) error {
+	workflow := "0"
+	if toProject {
+		workflow = "$2"
+	}
+	_, err := tx.Exec(ctx, `UPDATE tasks SET status_id = `+statusInWorkflowSQL("COALESCE(tasks.completed, false)", workflow)+`
+		WHERE user_id = $1 AND project_id = $2 AND parent_id IS NULL`, userID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to move tasks between workflows: %v", err)
+	}
+	return nil
+}
+
+// listedTaskSnoozeSQL hides tasks snoozed past today in the owner's timezone, like the
+// task list does. It expects tasks aliased t and the owner's id as $1.
+const listedTaskSnoozeSQL = ` AND (t.hidden_until IS NULL OR t.hidden_until <= CAST(NOW() AT TIME ZONE
+	(SELECT COALESCE(NULLIF(u.timezone, ''), 'UTC') FROM users u WHERE u.id = $1) AS DATE))`
+
+// CountTasksByDone counts the user's listed top-level tasks in the done status and in an
+// open one; a task without a status counts by its completed flag. projectFilter works as
+// in the task list: nil for every project, 0 for tasks without one. Snoozed tasks are
+// left out until they reappear in the list.
+func CountTasksByDone(userID int, projectFilter *int) (int, int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer CloseDatabase(pool)
+
+	query := `SELECT COUNT(*) FILTER (WHERE COALESCE(s.is_done, t.completed, false)),
+		COUNT(*) FILTER (WHERE NOT COALESCE(s.is_done, t.completed, false))
+		FROM tasks t LEFT JOIN task_statuses s ON s.id = t.status_id
+		WHERE t.user_id = $1 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.archived_at IS NULL` + listedTaskSnoozeSQL
+	args := []interface{}{userID}
+	if projectFilter != nil {
+		if *projectFilter == 0 {
+			query += " AND t.project_id IS NULL"
+		} else {
+			query += " AND t.project_id = $2"
+			args = append(args, *projectFilter)
+		}
+	}
+	var done, open int
+	if err := pool.QueryRow(context.Background(), query, args...).Scan(&done, &open); err != nil {
+		return 0, 0, fmt.Errorf("failed to count tasks: %v", err)
+	}
+	return done, open, nil
+}
+
+// CountTopLevelTasks returns how many top-level tasks the list shows for the user,
+// optionally scoped to a project (0 for tasks without a project), leaving out snoozed
+// ones as the list does. Handlers use it to work out the last page after adding or
+// removing a task.
+func CountTopLevelTasks(userID int, projectFilter *int) (int, error) {
+	done, open, err := CountTasksByDone(userID, projectFilter)
+	if err != nil {
+		return 0, err
+	}
+	return done + open, nil
+}
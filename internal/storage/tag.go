@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Tag is a user-owned colored label. A task can carry any number of tags.
+type Tag struct {
+	ID        int
+	UserID    int
+	Name      string
+	Color     string // hex color, e.g. #0d6efd
+	CreatedAt time.Time
+}
+
+// DefaultTagColor is used when a tag is created without a valid color.
+const DefaultTagColor = "#6c757d"
+
+// CreateTagsTable creates the tags table
+func CreateTagsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS tags (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+            name TEXT NOT NULL,
+            color VARCHAR(7) NOT NULL DEFAULT '#6c757d',
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE (user_id, name)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create tags table: %v", err)
+	}
+	return nil
+}
+
+// CreateTaskTagsTable creates the join table between tasks and tags
+func CreateTaskTagsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_tags (
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+            PRIMARY KEY (task_id, tag_id)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_tags table: %v", err)
+	}
+
+	// Filtering goes from tag to tasks, so index the second key column as well
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags(tag_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_tags.tag_id: %v", err)
+	}
+	return nil
+}
+
+// IsValidTagColor reports whether s is a #RRGGBB hex color.
+func IsValidTagColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(s[1:], 16, 32)
+	return err == nil
+}
+
+// TextClass returns the Bootstrap text color class that stays readable on the tag color.
+func (t Tag) TextClass() string {
+	if !IsValidTagColor(t.Color) {
+		return "text-white"
+	}
+	v, _ := strconv.ParseUint(t.Color[1:], 16, 32)
+	r, g, b := float64(v>>16&0xff), float64(v>>8&0xff), float64(v&0xff)
+	// Perceived brightness (ITU-R BT.601)
+	if 0.299*r+0.587*g+0.114*b > 150 {
+		return "text-dark"
+	}
+	return "text-white"
+}
+
+// CreateTag inserts a new tag for the given user and returns it.
+func CreateTag(userID int, name, color string) (*Tag, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	if !IsValidTagColor(color) {
+		color = DefaultTagColor
+	}
+
+	var t Tag
+	err = pool.QueryRow(context.Background(), "INSERT INTO tags (user_id, name, color) VALUES ($1, $2, $3) RETURNING id, user_id, name, color, created_at", userID, name, color).Scan(&t.ID, &t.UserID, &t.Name, &t.Color, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag: %v", err)
+	}
+	return &t, nil
+}
+
+// UpdateTag renames and recolors a tag owned by the user.
+func UpdateTag(id int, userID int, name, color string) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	if !IsValidTagColor(color) {
+		color = DefaultTagColor
+	}
+
+	_, err = pool.Exec(context.Background(), "UPDATE tags SET name = $1, color = $2 WHERE id = $3 AND user_id = $4", name, color, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update tag: %v", err)
+	}
+	return nil
+}
+
+// DeleteTag removes a tag owned by the user. Task assignments are removed with it.
+func DeleteTag(id int, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), "DELETE FROM tags WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %v", err)
+	}
+	return nil
+}
+
+// GetTagsForUser returns all tags owned by a user.
+func GetTagsForUser(userID int) ([]Tag, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), "SELECT id, user_id, name, color, created_at FROM tags WHERE user_id = $1 ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %v", err)
+	}
+	defer rows.Close()
+
+	var out []Tag
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Color, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %v", err)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// GetTagIDsForTask returns the ids of the tags assigned to a task owned by the user.
+func GetTagIDsForTask(taskID int, userID int) ([]int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), "SELECT tt.tag_id FROM task_tags tt JOIN tasks t ON t.id = tt.task_id WHERE tt.task_id = $1 AND t.user_id = $2", taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task tags: %v", err)
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan task tag row: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SetTaskTags replaces the tags assigned to a task. Only tags owned by the task's
+// owner are assigned; other ids are ignored.
+func SetTaskTags(taskID int, userID int, tagIDs []int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM task_tags WHERE task_id = (SELECT id FROM tasks WHERE id = $1 AND user_id = $2)", taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear task tags: %v", err)
+	}
+	if len(tagIDs) > 0 {
+		_, err = tx.Exec(ctx, `INSERT INTO task_tags (task_id, tag_id)
+			SELECT t.id, g.id FROM tasks t JOIN tags g ON g.user_id = t.user_id
+			WHERE t.id = $1 AND t.user_id = $2 AND g.id = ANY($3)
+			ON CONFLICT DO NOTHING`, taskID, userID, tagIDs)
+		if err != nil {
+			return fmt.Errorf("failed to assign task tags: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit task tags: %v", err)
+	}
+	return nil
+}
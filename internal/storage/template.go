@@ -0,0 +1,430 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Template kinds: a single task with its subtasks, or a project with all of its tasks.
+const (
+	TemplateKindTask    = "task"
+	TemplateKindProject = "project"
+)
+
+// TaskTemplate is a saved task or project that can be created again from scratch.
+type TaskTemplate struct {
+	ID          int
+	Name        string
+	Kind        string
+	ProjectName string // name of the saved project, for project templates
+	ItemCount   int
+	CreatedAt   string // formatted in the viewer's timezone
+}
+
+// TemplateItem is one task of a template. Due dates are kept as a number of days after
+// the template's earliest due date, so instantiating shifts them to a new start date.
+type TemplateItem struct {
+	ID           int
+	ParentID     int // item id of the parent task, 0 for top-level tasks
+	Title        string
+	Description  string
+	Notes        string
+	Priority     int
+	Estimate     float64
+	EstimateUnit string
+	RepeatRule   string
+	DueOffset    *int // days after the start date, nil without a due date
+	DueTime      string
+}
+
+// ErrTemplateNotFound is returned when a template does not exist or belongs to another user.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrTemplateExists is returned when the user already has a template with the same name.
+var ErrTemplateExists = errors.New("a template with that name already exists")
+
+// ErrTemplateSourceNotFound is returned when the task or project to save does not exist,
+// belongs to another user, or has no tasks.
+var ErrTemplateSourceNotFound = errors.New("nothing to save as a template")
+
+// ErrTemplateProjectNotFound is returned when the project to create a task template in
+// does not exist or belongs to another user.
+var ErrTemplateProjectNotFound = errors.New("project not found")
+
+// CreateTaskTemplatesTables creates the per-user templates and their tasks.
+func CreateTaskTemplatesTables() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_templates (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+            name VARCHAR(100) NOT NULL,
+            kind VARCHAR(10) NOT NULL,
+            project_name VARCHAR(50) NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+            UNIQUE (user_id, name)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_templates table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS task_template_items (
+            id SERIAL PRIMARY KEY,
+            template_id INTEGER NOT NULL REFERENCES task_templates (id) ON DELETE CASCADE,
+            parent_item_id INTEGER REFERENCES task_template_items (id) ON DELETE CASCADE,
+            position INTEGER NOT NULL DEFAULT 0,
+            title TEXT NOT NULL,
+            description TEXT NOT NULL DEFAULT '',
+            notes TEXT NOT NULL DEFAULT '',
+            priority INTEGER NOT NULL DEFAULT 0,
+            estimate NUMERIC(7,2),
+            estimate_unit VARCHAR(10),
+            repeat_rule TEXT,
+            due_offset_days INTEGER,
+            due_time TIME
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create task_template_items table: %v", err)
+	}
+
+	_, err = pool.Exec(context.Background(), "CREATE INDEX IF NOT EXISTS idx_task_template_items_template_id ON task_template_items(template_id, position)")
+	if err != nil {
+		return fmt.Errorf("failed to create index on task_template_items.template_id: %v", err)
+	}
+	return nil
+}
+
+// saveTemplateTx stores a template made of the user's tasks matched by cond (a clause on
+// tasks aliased as t, with the user bound to $1 and arg to $2) and their subtasks.
+func saveTemplateTx(ctx context.Context, tx pgx.Tx, userID int, name, kind, projectName, cond string, arg int) (int, error) {
+	rows, err := tx.Query(ctx, `WITH RECURSIVE tree AS (
+			SELECT t.id, t.parent_id, 0 AS depth FROM tasks t
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.parent_id IS NULL AND `+cond+`
+			UNION ALL
+			SELECT s.id, s.parent_id, tree.depth + 1 FROM tasks s JOIN tree ON s.parent_id = tree.id
+			WHERE s.deleted_at IS NULL
+		)
+		SELECT t.id, COALESCE(t.parent_id, 0), t.title, COALESCE(t.description, ''), COALESCE(t.notes, ''),
+			COALESCE(t.priority, 0), CAST(COALESCE(t.estimate, 0) AS DOUBLE PRECISION), COALESCE(t.estimate_unit, ''),
+			COALESCE(t.repeat_rule, ''), COALESCE(CAST(t.due_date AS TEXT), ''), COALESCE(TO_CHAR(t.due_time, 'HH24:MI'), '')
+		FROM tree JOIN tasks t ON t.id = tree.id
+		ORDER BY tree.depth, t.position, t.id`, userID, arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read tasks for template: %v", err)
+	}
+	type source struct {
+		TemplateItem
+		TaskID  int
+		Parent  int
+		DueDate string
+	}
+	list := make([]source, 0)
+	for rows.Next() {
+		var s source
+		if err := rows.Scan(&s.TaskID, &s.Parent, &s.Title, &s.Description, &s.Notes, &s.Priority,
+			&s.Estimate, &s.EstimateUnit, &s.RepeatRule, &s.DueDate, &s.DueTime); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan task for template: %v", err)
+		}
+		list = append(list, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read tasks for template: %v", err)
+	}
+	if len(list) == 0 {
+		return 0, ErrTemplateSourceNotFound
+	}
+
+	// Due dates are stored relative to the earliest one
+	var start time.Time
+	for _, s := range list {
+		if d, err := time.Parse("2006-01-02", s.DueDate); err == nil && (start.IsZero() || d.Before(start)) {
+			start = d
+		}
+	}
+
+	var templateID int
+	err = tx.QueryRow(ctx, `INSERT INTO task_templates (user_id, name, kind, project_name) VALUES ($1, $2, $3, $4) RETURNING id`,
+		userID, name, kind, projectName).Scan(&templateID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, ErrTemplateExists
+		}
+		return 0, fmt.Errorf("failed to create template: %v", err)
+	}
+
+	// Parents come before their subtasks, so their item ids are known when needed
+	itemIDs := make(map[int]int, len(list))
+	for i, s := range list {
+		var offset *int
+		if d, err := time.Parse("2006-01-02", s.DueDate); err == nil {
+			days := int(d.Sub(start).Hours() / 24)
+			offset = &days
+		}
+		var parentItem *int
+		if id, ok := itemIDs[s.Parent]; ok {
+			parentItem = &id
+		}
+		var itemID int
+		err = tx.QueryRow(ctx, `INSERT INTO task_template_items (template_id, parent_item_id, position, title, description, notes,
+			priority, estimate, estimate_unit, repeat_rule, due_offset_days, due_time)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $9 = '' THEN NULL ELSE CAST($8 AS NUMERIC) END, NULLIF($9, ''), NULLIF($10, ''), $11, CAST(NULLIF($12, '') AS TIME))
+			RETURNING id`,
+			templateID, parentItem, i, s.Title, s.Description, s.Notes, s.Priority, s.Estimate, s.EstimateUnit,
+			s.RepeatRule, offset, s.DueTime).Scan(&itemID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save template task: %v", err)
+		}
+		itemIDs[s.TaskID] = itemID
+	}
+	return templateID, nil
+}
+
+// SaveTaskAsTemplate saves a top-level task of the user and its subtasks as a template.
+func SaveTaskAsTemplate(taskID, userID int, name string) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := saveTemplateTx(ctx, tx, userID, name, TemplateKindTask, "", "t.id = $2", taskID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit template: %v", err)
+	}
+	return id, nil
+}
+
+// SaveProjectAsTemplate saves a project of the user with all of its tasks (completed and
+// archived ones included, trashed ones not) as a template.
+func SaveProjectAsTemplate(projectID, userID int, name string) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var projectName string
+	err = tx.QueryRow(ctx, "SELECT name FROM projects WHERE id = $1 AND user_id = $2", projectID, userID).Scan(&projectName)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrTemplateSourceNotFound
+		}
+		return 0, fmt.Errorf("failed to read project: %v", err)
+	}
+	id, err := saveTemplateTx(ctx, tx, userID, name, TemplateKindProject, projectName, "t.project_id = $2", projectID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit template: %v", err)
+	}
+	return id, nil
+}
+
+// GetTemplates returns the user's templates by name, with timestamps formatted in the given timezone.
+func GetTemplates(userID int, timezone string) ([]TaskTemplate, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT tt.id, tt.name, tt.kind, tt.project_name,
+		(SELECT COUNT(*) FROM task_template_items i WHERE i.template_id = tt.id),
+		TO_CHAR((tt.created_at AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD')
+		FROM task_templates tt WHERE tt.user_id = $1 ORDER BY LOWER(tt.name), tt.id`, userID, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TaskTemplate, 0)
+	for rows.Next() {
+		var t TaskTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Kind, &t.ProjectName, &t.ItemCount, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan template: %v", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// DeleteTemplate removes a template of the user.
+func DeleteTemplate(templateID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), "DELETE FROM task_templates WHERE id = $1 AND user_id = $2", templateID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// InstantiateTemplate creates the tasks of a template for the user in one transaction,
+// with due dates shifted so the earliest falls on start (YYYY-MM-DD). A project template
+// creates a new project named projectName (the saved name when empty); a task template
+// goes into projectID when it is one of the user's projects, or no project when nil.
+// It returns the project the tasks went into (0 for none) and how many were created.
+func InstantiateTemplate(templateID, userID int, start, projectName string, projectID *int) (int, int, error) {
+	startDate, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start date: %v", err)
+	}
+
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var kind, savedProject string
+	err = tx.QueryRow(ctx, "SELECT kind, project_name FROM task_templates WHERE id = $1 AND user_id = $2", templateID, userID).Scan(&kind, &savedProject)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, ErrTemplateNotFound
+		}
+		return 0, 0, fmt.Errorf("failed to load template: %v", err)
+	}
+
+	var project *int
+	switch {
+	case kind == TemplateKindProject:
+		if projectName == "" {
+			projectName = savedProject
+		}
+		var id int
+		if err := tx.QueryRow(ctx, "INSERT INTO projects (user_id, name) VALUES ($1, $2) RETURNING id", userID, projectName).Scan(&id); err != nil {
+			return 0, 0, fmt.Errorf("failed to create project: %v", err)
+		}
+		project = &id
+	case projectID != nil:
+		var id int
+		if err := tx.QueryRow(ctx, "SELECT id FROM projects WHERE id = $1 AND user_id = $2", *projectID, userID).Scan(&id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return 0, 0, ErrTemplateProjectNotFound
+			}
+			return 0, 0, fmt.Errorf("failed to read project: %v", err)
+		}
+		project = &id
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, COALESCE(parent_item_id, 0), title, description, notes, priority,
+		CAST(COALESCE(estimate, 0) AS DOUBLE PRECISION), COALESCE(estimate_unit, ''), COALESCE(repeat_rule, ''),
+		due_offset_days, COALESCE(TO_CHAR(due_time, 'HH24:MI'), '')
+		FROM task_template_items WHERE template_id = $1 ORDER BY position, id`, templateID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load template tasks: %v", err)
+	}
+	items := make([]TemplateItem, 0)
+	for rows.Next() {
+		var it TemplateItem
+		if err := rows.Scan(&it.ID, &it.ParentID, &it.Title, &it.Description, &it.Notes, &it.Priority,
+			&it.Estimate, &it.EstimateUnit, &it.RepeatRule, &it.DueOffset, &it.DueTime); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan template task: %v", err)
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to load template tasks: %v", err)
+	}
+
+	// New top-level tasks go after the user's non-favorite tasks, in template order
+	var nextPos int
+	err = tx.QueryRow(ctx, "SELECT COALESCE(MAX(position),0) + 1 FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND (is_favorite IS NULL OR is_favorite = false)", userID).Scan(&nextPos)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read task positions: %v", err)
+	}
+
+	taskIDs := make(map[int]int, len(items))
+	subtaskCount := make(map[int]int)
+	for _, it := range items {
+		due, dueTime := "", ""
+		if it.DueOffset != nil {
+			due = startDate.AddDate(0, 0, *it.DueOffset).Format("2006-01-02")
+			dueTime = it.DueTime
+		}
+		var parent *int
+		position := nextPos
+		if it.ParentID != 0 {
+			id, ok := taskIDs[it.ParentID]
+			if !ok {
+				continue
+			}
+			parent = &id
+			subtaskCount[id]++
+			position = subtaskCount[id]
+		} else {
+			nextPos++
+		}
+		var taskID int
+		err = tx.QueryRow(ctx, `INSERT INTO tasks (title, description, notes, completed, user_id, time_stamp, position, project_id, parent_id,
+			priority, estimate, estimate_unit, repeat_rule, due_date, due_time)
+			VALUES ($1, $2, $3, false, $4, NOW() AT TIME ZONE 'UTC', $5, $6, $7, $8, CASE WHEN $10 = '' THEN NULL ELSE CAST($9 AS NUMERIC) END,
+				NULLIF($10, ''), NULLIF($11, ''), CAST(NULLIF($12, '') AS DATE), CAST(NULLIF($13, '') AS TIME))
+			RETURNING id`,
+			it.Title, it.Description, it.Notes, userID, position, project, parent, it.Priority,
+			it.Estimate, it.EstimateUnit, it.RepeatRule, due, dueTime).Scan(&taskID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to create task from template: %v", err)
+		}
+		taskIDs[it.ID] = taskID
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit template tasks: %v", err)
+	}
+	pid := 0
+	if project != nil {
+		pid = *project
+	}
+	return pid, len(taskIDs), nil
+}
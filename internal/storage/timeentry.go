@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TimeEntry is a span of time logged on a task, either by a timer or entered by hand.
+type TimeEntry struct {
+	ID        int
+	TaskID    int
+	StartedAt string // formatted in the viewer's timezone
+	Seconds   int64  // elapsed so far for a running timer
+	Running   bool
+	Manual    bool
+	Note      string
+}
+
+// RunningTimer is the timer a user currently has running.
+type RunningTimer struct {
+	TaskID    int
+	Title     string
+	StartedAt time.Time
+}
+
+// TimeReportRow is the time logged on one day (in the user's timezone) for one project.
+type TimeReportRow struct {
+	Day         string // YYYY-MM-DD
+	ProjectID   int    // 0 for tasks without a project
+	ProjectName string
+	Seconds     int64
+}
+
+var (
+	// ErrNoRunningTimer is returned by StopTimer when the user has no timer running.
+	ErrNoRunningTimer = errors.New("no timer is running")
+	// ErrTimeEntryNotFound is returned when a task or entry does not exist or is not the user's.
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+)
+
+// entrySeconds is the duration of a time entry; running timers count up to now.
+const entrySeconds = "CAST(EXTRACT(EPOCH FROM (COALESCE(e.ended_at, NOW()) - e.started_at)) AS BIGINT)"
+
+// CreateTimeEntriesTable creates the time tracking table. A partial unique index allows
+// only one running timer (ended_at IS NULL) per user.
+func CreateTimeEntriesTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS time_entries (
+            id SERIAL PRIMARY KEY,
+            user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+            task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+            started_at TIMESTAMPTZ NOT NULL,
+            ended_at TIMESTAMPTZ,
+            manual BOOLEAN NOT NULL DEFAULT false,
+            note VARCHAR(200) NOT NULL DEFAULT '',
+            CHECK (ended_at IS NULL OR ended_at >= started_at)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create time_entries table: %v", err)
+	}
+
+	stmts := []struct{ sql, what string }{
+		{"CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_one_running ON time_entries(user_id) WHERE ended_at IS NULL", "running timer index"},
+		{"CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id)", "index on time_entries.task_id"},
+		{"CREATE INDEX IF NOT EXISTS idx_time_entries_user_started ON time_entries(user_id, started_at)", "index on time_entries.started_at"},
+	}
+	for _, s := range stmts {
+		if _, err := pool.Exec(context.Background(), s.sql); err != nil {
+			return fmt.Errorf("failed to create %s: %v", s.what, err)
+		}
+	}
+	return nil
+}
+
+// StartTimer starts a timer on one of the user's tasks. A timer already running on
+// another task is stopped first; its task id is returned as stoppedTaskID (0 if none).
+// Starting the timer that is already running leaves it untouched.
+func StartTimer(taskID, userID int) (stoppedTaskID int, err error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var owned bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND user_id = $2)", taskID, userID).Scan(&owned); err != nil {
+		return 0, fmt.Errorf("failed to verify task: %v", err)
+	}
+	if !owned {
+		return 0, ErrTimeEntryNotFound
+	}
+
+	var runningTaskID int
+	err = tx.QueryRow(ctx, "SELECT task_id FROM time_entries WHERE user_id = $1 AND ended_at IS NULL FOR UPDATE", userID).Scan(&runningTaskID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to check running timer: %v", err)
+	}
+	if runningTaskID == taskID {
+		return 0, tx.Commit(ctx)
+	}
+	if runningTaskID != 0 {
+		if _, err := tx.Exec(ctx, "UPDATE time_entries SET ended_at = NOW() WHERE user_id = $1 AND ended_at IS NULL", userID); err != nil {
+			return 0, fmt.Errorf("failed to stop running timer: %v", err)
+		}
+	}
+
+	// The partial unique index rejects a second running timer should two starts race
+	if _, err := tx.Exec(ctx, "INSERT INTO time_entries (user_id, task_id, started_at) VALUES ($1, $2, NOW())", userID, taskID); err != nil {
+		return 0, fmt.Errorf("failed to start timer: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit timer: %v", err)
+	}
+	return runningTaskID, nil
+}
+
+// StopTimer stops the user's running timer and returns the task it was running on.
+func StopTimer(userID int) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var taskID int
+	err = pool.QueryRow(context.Background(), "UPDATE time_entries SET ended_at = NOW() WHERE user_id = $1 AND ended_at IS NULL RETURNING task_id", userID).Scan(&taskID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNoRunningTimer
+		}
+		return 0, fmt.Errorf("failed to stop timer: %v", err)
+	}
+	return taskID, nil
+}
+
+// GetRunningTimer returns the user's running timer, or nil when none is running.
+func GetRunningTimer(userID int) (*RunningTimer, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	var rt RunningTimer
+	err = pool.QueryRow(context.Background(), `SELECT e.task_id, t.title, e.started_at FROM time_entries e
+		JOIN tasks t ON t.id = e.task_id
+		WHERE e.user_id = $1 AND e.ended_at IS NULL`, userID).Scan(&rt.TaskID, &rt.Title, &rt.StartedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query running timer: %v", err)
+	}
+	return &rt, nil
+}
+
+// AddManualTimeEntry logs time on one of the user's tasks without a timer.
+func AddManualTimeEntry(taskID, userID int, startedAt time.Time, duration time.Duration, note string) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	tag, err := pool.Exec(context.Background(), `INSERT INTO time_entries (user_id, task_id, started_at, ended_at, manual, note)
+		SELECT t.user_id, t.id, $3, $4, true, $5 FROM tasks t WHERE t.id = $1 AND t.user_id = $2`,
+		taskID, userID, startedAt, startedAt.Add(duration), note)
+	if err != nil {
+		return fmt.Errorf("failed to add time entry: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTimeEntryNotFound
+	}
+	return nil
+}
+
+// DeleteTimeEntry removes one of the user's time entries and returns its task id.
+func DeleteTimeEntry(id, userID int) (int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer CloseDatabase(pool)
+
+	var taskID int
+	err = pool.QueryRow(context.Background(), "DELETE FROM time_entries WHERE id = $1 AND user_id = $2 RETURNING task_id", id, userID).Scan(&taskID)
+	if err != nil {
+		return 0, ErrTimeEntryNotFound
+	}
+	return taskID, nil
+}
+
+// GetTimeEntries lists the time logged on one of the user's tasks, newest first.
+func GetTimeEntries(taskID, userID int, timezone string) ([]TimeEntry, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT e.id, e.task_id,
+		TO_CHAR(e.started_at AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM'), `+entrySeconds+`,
+		e.ended_at IS NULL, e.manual, e.note
+		FROM time_entries e
+		WHERE e.task_id = $1 AND e.user_id = $2
+		ORDER BY e.started_at DESC, e.id DESC`, taskID, userID, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time entries: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TimeEntry, 0)
+	for rows.Next() {
+		var e TimeEntry
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.StartedAt, &e.Seconds, &e.Running, &e.Manual, &e.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %v", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// GetProjectTimeTotals returns the seconds logged per project of the user, including
+// subtasks. Tasks without a project are reported under key 0.
+func GetProjectTimeTotals(userID int) (map[int]int64, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT COALESCE(t.project_id, 0), SUM(`+entrySeconds+`)
+		FROM time_entries e JOIN tasks t ON t.id = e.task_id
+		WHERE e.user_id = $1
+		GROUP BY COALESCE(t.project_id, 0)`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project time totals: %v", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[int]int64)
+	for rows.Next() {
+		var pid int
+		var secs int64
+		if err := rows.Scan(&pid, &secs); err != nil {
+			return nil, fmt.Errorf("failed to scan project time total: %v", err)
+		}
+		totals[pid] = secs
+	}
+	return totals, rows.Err()
+}
+
+// GetTimeReport sums the user's logged time by day and project for the days from..to
+// (inclusive, YYYY-MM-DD) in the given timezone. Entries count towards the day they
+// started on.
+func GetTimeReport(userID int, from, to, timezone string) ([]TimeReportRow, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT CAST(CAST(e.started_at AT TIME ZONE $4 AS DATE) AS TEXT) AS day,
+		COALESCE(t.project_id, 0), COALESCE(p.name, ''), SUM(`+entrySeconds+`)
+		FROM time_entries e
+		JOIN tasks t ON t.id = e.task_id
+		LEFT JOIN projects p ON p.id = t.project_id
+		WHERE e.user_id = $1
+		AND e.started_at >= (CAST($2 AS DATE)::timestamp AT TIME ZONE $4)
+		AND e.started_at < ((CAST($3 AS DATE) + 1)::timestamp AT TIME ZONE $4)
+		GROUP BY day, COALESCE(t.project_id, 0), COALESCE(p.name, '')
+		ORDER BY day, COALESCE(p.name, '')`, userID, from, to, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time report: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TimeReportRow, 0)
+	for rows.Next() {
+		var row TimeReportRow
+		if err := rows.Scan(&row.Day, &row.ProjectID, &row.ProjectName, &row.Seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan time report row: %v", err)
+		}
+		list = append(list, row)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TrashedTask is a soft-deleted task as listed in the trash.
+type TrashedTask struct {
+	ID           int
+	Title        string
+	ProjectName  string
+	ParentTitle  string // set for a subtask deleted on its own
+	SubtaskCount int    // subtasks deleted along with the task
+	DeletedAt    time.Time
+	DeletedLabel string // DeletedAt formatted in the viewer's timezone
+}
+
+var (
+	// ErrTrashedTaskNotFound is returned when a task is not in the user's trash (or, for
+	// TrashTask, does not exist or is already in the trash).
+	ErrTrashedTaskNotFound = errors.New("task not found in trash")
+	// ErrParentInTrash is returned when restoring a subtask whose parent is itself trashed.
+	ErrParentInTrash = errors.New("the parent task is in the trash")
+)
+
+// TrashTask moves one of the user's tasks to the trash together with its subtasks, which
+// share its deleted_at so that restoring the task brings them back. A timer running on
+// any of them is stopped.
+func TrashTask(taskID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := trashTaskTx(ctx, tx, taskID, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit trash: %v", err)
+	}
+	return nil
+}
+
+// trashTaskTx moves a task of the user and its subtasks to the trash inside tx and stops
+// any timer running on them.
+func trashTaskTx(ctx context.Context, tx pgx.Tx, taskID, userID int) error {
+	var deletedAt time.Time
+	err := tx.QueryRow(ctx, `UPDATE tasks SET deleted_at = NOW() AT TIME ZONE 'UTC'
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL RETURNING deleted_at`, taskID, userID).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTrashedTaskNotFound
+		}
+		return fmt.Errorf("failed to trash task: %v", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE tasks SET deleted_at = $1 WHERE parent_id = $2 AND deleted_at IS NULL", deletedAt, taskID); err != nil {
+		return fmt.Errorf("failed to trash subtasks: %v", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE time_entries SET ended_at = NOW()
+		WHERE user_id = $1 AND ended_at IS NULL
+		AND task_id IN (SELECT id FROM tasks WHERE id = $2 OR parent_id = $2)`, userID, taskID); err != nil {
+		return fmt.Errorf("failed to stop timer: %v", err)
+	}
+	return nil
+}
+
+// RestoreTask takes a task out of the user's trash along with the subtasks trashed with it.
+func RestoreTask(taskID, userID int) error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := restoreTaskTx(ctx, tx, taskID, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit restore: %v", err)
+	}
+	return nil
+}
+
+// restoreTaskTx restores a trashed task and the subtasks trashed with it inside tx.
+func restoreTaskTx(ctx context.Context, tx pgx.Tx, taskID, userID int) error {
+	var deletedAt time.Time
+	var parentTrashed bool
+	err := tx.QueryRow(ctx, `SELECT t.deleted_at, COALESCE(p.deleted_at IS NOT NULL, false)
+		FROM tasks t LEFT JOIN tasks p ON p.id = t.parent_id
+		WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NOT NULL
+		FOR UPDATE OF t`, taskID, userID).Scan(&deletedAt, &parentTrashed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTrashedTaskNotFound
+		}
+		return fmt.Errorf("failed to find trashed task: %v", err)
+	}
+	if parentTrashed {
+		return ErrParentInTrash
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET deleted_at = NULL
+		WHERE id = $1 OR (parent_id = $1 AND deleted_at = $2)`, taskID, deletedAt); err != nil {
+		return fmt.Errorf("failed to restore task: %v", err)
+	}
+	return nil
+}
+
+// GetTrash lists the user's trashed tasks, most recently deleted first. Subtasks trashed
+// with their parent are not listed separately.
+func GetTrash(userID int, timezone string) ([]TrashedTask, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT t.id, t.title, COALESCE(pr.name, ''), COALESCE(p.title, ''),
+		(SELECT COUNT(*) FROM tasks s WHERE s.parent_id = t.id AND s.deleted_at = t.deleted_at),
+		t.deleted_at,
+		TO_CHAR((t.deleted_at AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM')
+		FROM tasks t
+		LEFT JOIN tasks p ON p.id = t.parent_id
+		LEFT JOIN projects pr ON pr.id = COALESCE(t.project_id, p.project_id)
+		WHERE t.user_id = $1 AND t.deleted_at IS NOT NULL
+		AND (t.parent_id IS NULL OR p.deleted_at IS NULL)
+		ORDER BY t.deleted_at DESC, t.id DESC`, userID, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]TrashedTask, 0)
+	for rows.Next() {
+		var t TrashedTask
+		if err := rows.Scan(&t.ID, &t.Title, &t.ProjectName, &t.ParentTitle, &t.SubtaskCount, &t.DeletedAt, &t.DeletedLabel); err != nil {
+			return nil, fmt.Errorf("failed to scan trashed task: %v", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// purgeTrashed permanently deletes the trashed tasks matched by cond (with args) and
+// returns the storage keys of their attachments, whose files the caller removes once the
+// rows are gone. Subtasks go with their parent via ON DELETE CASCADE.
+func purgeTrashed(cond string, args ...interface{}) ([]string, int, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT a.storage_key FROM task_attachments a
+		JOIN tasks t ON t.id = a.task_id
+		WHERE t.deleted_at IS NOT NULL AND (`+cond+` OR t.parent_id IN (SELECT t.id FROM tasks t WHERE t.deleted_at IS NOT NULL AND `+cond+`))`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query attachment keys: %v", err)
+	}
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan attachment key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to query attachment keys: %v", err)
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM tasks t WHERE t.deleted_at IS NOT NULL AND "+cond, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to purge tasks: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit purge: %v", err)
+	}
+	return keys, int(tag.RowsAffected()), nil
+}
+
+// PurgeTrashedTask permanently deletes one task from the user's trash and returns the
+// storage keys of the attachments that went with it.
+func PurgeTrashedTask(taskID, userID int) ([]string, error) {
+	keys, n, err := purgeTrashed("t.id = $1 AND t.user_id = $2", taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrTrashedTaskNotFound
+	}
+	return keys, nil
+}
+
+// EmptyTrash permanently deletes everything in the user's trash and returns the storage
+// keys of the attachments that went with it.
+func EmptyTrash(userID int) ([]string, error) {
+	keys, _, err := purgeTrashed("t.user_id = $1", userID)
+	return keys, err
+}
+
+// PurgeExpiredTrash permanently deletes tasks of every user that have been in the trash
+// for more than retentionDays, returning the attachment keys and the number of tasks purged.
+func PurgeExpiredTrash(retentionDays int) ([]string, int, error) {
+	return purgeTrashed("t.deleted_at < (NOW() AT TIME ZONE 'UTC') - make_interval(days => $1)", retentionDays)
+}
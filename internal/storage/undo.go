@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// UndoWindow is how long an action can be undone after it was made.
+const UndoWindow = 15 * time.Second
+
+// UndoTaskState is the state of a task before an undoable action.
+type UndoTaskState struct {
+	ID         int    `json:"id"`
+	Completed  bool   `json:"completed"`
+	Position   int    `json:"position"`
+	IsFavorite bool   `json:"is_favorite"`
+	ProjectID  *int   `json:"project_id"`
+	RepeatRule string `json:"repeat_rule"`
+	StatusID   *int   `json:"status_id"`
+}
+
+// UndoRecord describes how to revert a user's most recent undoable action.
+type UndoRecord struct {
+	Kind       string          `json:"kind"` // "delete", "complete", "reorder", "bulk" or "board"
+	Tasks      []UndoTaskState `json:"tasks"`
+	Trashed    int             `json:"trashed,omitempty"`     // task moved to the trash
+	Created    int             `json:"created,omitempty"`     // task created by the action (a next occurrence)
+	TrashedIDs []int           `json:"trashed_ids,omitempty"` // tasks moved to the trash by a bulk action
+	CreatedIDs []int           `json:"created_ids,omitempty"` // next occurrences created by a bulk action
+}
+
+// ErrUndoExpired is returned when there is no undo record for the token, either because
+// the window has passed or because a newer action replaced it.
+var ErrUndoExpired = errors.New("nothing to undo")
+
+// CreateUndoActionsTable creates the table holding each user's latest undo record.
+func CreateUndoActionsTable() error {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer CloseDatabase(pool)
+
+	_, err = pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS undo_actions (
+            user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+            token VARCHAR(36) NOT NULL,
+            record JSONB NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create undo_actions table: %v", err)
+	}
+	return nil
+}
+
+// SnapshotTasks reads the undoable state of the given tasks of the user.
+func SnapshotTasks(userID int, ids []int) ([]UndoTaskState, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	rows, err := pool.Query(context.Background(), `SELECT id, COALESCE(completed,false), COALESCE(position,0),
+		COALESCE(is_favorite,false), project_id, COALESCE(repeat_rule,''), status_id
+		FROM tasks WHERE id = ANY($1) AND user_id = $2`, ids, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot tasks: %v", err)
+	}
+	defer rows.Close()
+
+	list := make([]UndoTaskState, 0, len(ids))
+	for rows.Next() {
+		var s UndoTaskState
+		if err := rows.Scan(&s.ID, &s.Completed, &s.Position, &s.IsFavorite, &s.ProjectID, &s.RepeatRule, &s.StatusID); err != nil {
+			return nil, fmt.Errorf("failed to scan task snapshot: %v", err)
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// SaveUndo stores rec as the user's undo record, replacing any earlier one, and returns
+// the token the client sends back to undo it.
+func SaveUndo(userID int, rec UndoRecord) (string, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return "", err
+	}
+	defer CloseDatabase(pool)
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode undo record: %v", err)
+	}
+	token := uuid.NewString()
+	_, err = pool.Exec(context.Background(), `INSERT INTO undo_actions (user_id, token, record, created_at)
+		VALUES ($1, $2, $3, NOW() AT TIME ZONE 'UTC')
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, record = EXCLUDED.record, created_at = EXCLUDED.created_at`,
+		userID, token, string(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to save undo record: %v", err)
+	}
+	return token, nil
+}
+
+// ApplyUndo reverts the action recorded under token if it is still within UndoWindow.
+// The record is consumed, so an action can only be undone once.
+func ApplyUndo(userID int, token string) (*UndoRecord, error) {
+	pool, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var payload []byte
+	err = tx.QueryRow(ctx, `DELETE FROM undo_actions
+		WHERE user_id = $1 AND token = $2 AND created_at > (NOW() AT TIME ZONE 'UTC') - make_interval(secs => $3)
+		RETURNING record`, userID, token, UndoWindow.Seconds()).Scan(&payload)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUndoExpired
+		}
+		return nil, fmt.Errorf("failed to load undo record: %v", err)
+	}
+	var rec UndoRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode undo record: %v", err)
+	}
+
+	trashed := rec.TrashedIDs
+	if rec.Trashed != 0 {
+		trashed = append(trashed, rec.Trashed)
+	}
+	for _, id := range trashed {
+		if err := restoreTaskTx(ctx, tx, id, userID); err != nil {
+			return nil, err
+		}
+	}
+	created := rec.CreatedIDs
+	if rec.Created != 0 {
+		created = append(created, rec.Created)
+	}
+	if len(created) > 0 {
+		// The occurrences were generated by the action and have no history of their own
+		if _, err := tx.Exec(ctx, "DELETE FROM tasks WHERE id = ANY($1) AND user_id = $2", created, userID); err != nil {
+			return nil, fmt.Errorf("failed to remove created task: %v", err)
+		}
+	}
+	for _, s := range rec.Tasks {
+		before, err := readRevisionStateTx(ctx, tx, s.ID, userID)
+		if err != nil {
+			if errors.Is(err, ErrRevisionNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		// A project deleted since the snapshot, and its statuses with it, leaves the task
+		// without a project, in the matching status of the default workflow
+		_, err = tx.Exec(ctx, `UPDATE tasks SET completed = $1, position = $2,
+			completed_at = CASE WHEN $1 THEN COALESCE(completed_at, NOW() AT TIME ZONE 'UTC') END,
+			archived_at = CASE WHEN $1 THEN archived_at END, is_favorite = $3,
+			project_id = (SELECT id FROM projects WHERE id = $4 AND user_id = $7),
+			repeat_rule = NULLIF($5, ''),
+			status_id = COALESCE((SELECT id FROM task_statuses WHERE id = $8),
+				`+StatusAfterSQL("CAST($1 AS BOOLEAN)", "(SELECT id FROM projects WHERE id = $4 AND user_id = $7)")+`)
+			WHERE id = $6 AND user_id = $7`, s.Completed, s.Position, s.IsFavorite, s.ProjectID, s.RepeatRule, s.ID, userID, s.StatusID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore task state: %v", err)
+		}
+		if err := recordRevisionTx(ctx, tx, s.ID, userID, before); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit undo: %v", err)
+	}
+	return &rec, nil
+}
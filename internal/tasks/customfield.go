@@ -0,0 +1,159 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxFieldTextLength is the longest value accepted for a text custom field.
+const MaxFieldTextLength = 200
+
+// FieldValue is the value a task carries for one of its project's custom fields.
+type FieldValue struct {
+	FieldID int
+	Name    string
+	Type    string
+	Value   string // normalized, see NormalizeFieldValue
+}
+
+// Label formats the value for display on the task.
+func (v FieldValue) Label() string {
+	switch v.Type {
+	case storage.FieldCheckbox:
+		return "Yes"
+	case storage.FieldDate:
+		if d, err := time.Parse("2006-01-02", v.Value); err == nil {
+			return d.Format("Jan 2, 2006")
+		}
+	}
+	return v.Value
+}
+
+// NormalizeFieldValue validates a value submitted for a custom field and returns it in
+// the form it is stored and compared in. An empty value means no value.
+func NormalizeFieldValue(f storage.ProjectField, raw string) (string, error) {
+	raw = strings.TrimSpace(strings.ReplaceAll(raw, "\x00", ""))
+	if raw == "" {
+		return "", nil
+	}
+	switch f.Type {
+	case storage.FieldText:
+		if len(raw) > MaxFieldTextLength {
+			return "", fmt.Errorf("%s must be %d characters or less", f.Name, MaxFieldTextLength)
+		}
+		return raw, nil
+	case storage.FieldNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", fmt.Errorf("%s must be a number", f.Name)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case storage.FieldDate:
+		d, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a date", f.Name)
+		}
+		return d.Format("2006-01-02"), nil
+	case storage.FieldSelect:
+		for _, o := range f.Options {
+			if raw == o {
+				return raw, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of its options", f.Name)
+	case storage.FieldCheckbox:
+		switch strings.ToLower(raw) {
+		case "true", "on", "1", "yes":
+			return "true", nil
+		case "false", "off", "0", "no":
+			return "", nil
+		}
+		return "", fmt.Errorf("%s must be checked or unchecked", f.Name)
+	}
+	return "", fmt.Errorf("unknown field type %q", f.Type)
+}
+
+// fieldCondition returns the clause keeping only tasks whose FilterField value matches
+// FilterValue: text fields match on a case-insensitive substring, other types on equality.
+// An unchecked checkbox matches tasks without the box checked. The value is bound to a
+// placeholder added to args.
+func (f TaskFilter) fieldCondition(args *sqlArgs) string {
+	if f.FilterField == nil {
+		return ""
+	}
+	id := f.FilterField.ID
+	switch f.FilterField.Type {
+	case storage.FieldCheckbox:
+		if f.FilterValue == "" {
+			return fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM task_field_values fv WHERE fv.task_id = t.id AND fv.field_id = %d)", id)
+		}
+		return fmt.Sprintf(" AND EXISTS (SELECT 1 FROM task_field_values fv WHERE fv.task_id = t.id AND fv.field_id = %d)", id)
+	case storage.FieldText:
+		return fmt.Sprintf(" AND EXISTS (SELECT 1 FROM task_field_values fv WHERE fv.task_id = t.id AND fv.field_id = %d AND POSITION(LOWER(%s) IN LOWER(fv.value)) > 0)", id, args.bind(f.FilterValue))
+	}
+	return fmt.Sprintf(" AND EXISTS (SELECT 1 FROM task_field_values fv WHERE fv.task_id = t.id AND fv.field_id = %d AND fv.value = %s)", id, args.bind(f.FilterValue))
+}
+
+// fieldSortKey returns the expression ordering tasks by their SortField value: numbers
+// and dates by value, select fields by option order, the rest alphabetically. Values are
+// normalized when saved; one that is not in the normalized form sorts as missing rather
+// than failing the whole list query.
+func (f TaskFilter) fieldSortKey() string {
+	value := fmt.Sprintf("(SELECT fv.value FROM task_field_values fv WHERE fv.task_id = t.id AND fv.field_id = %d)", f.SortField.ID)
+	switch f.SortField.Type {
+	case storage.FieldNumber:
+		return "CASE WHEN " + value + ` ~ '^-?[0-9]+(\.[0-9]+)?$' THEN CAST(` + value + " AS NUMERIC) END"
+	case storage.FieldDate:
+		// YYYY-MM-DD sorts by date as text, so no cast is needed
+		return "CASE WHEN " + value + ` ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}$' THEN ` + value + " END"
+	case storage.FieldSelect:
+		return fmt.Sprintf("(SELECT array_position(pf.options, %s) FROM project_fields pf WHERE pf.id = %d)", value, f.SortField.ID)
+	case storage.FieldCheckbox:
+		return "(" + value + " IS NOT NULL)"
+	}
+	return "LOWER(" + value + ")"
+}
+
+// attachFieldValues loads the custom field values of every task in the slice, in the
+// order the fields are defined on the task's project.
+func attachFieldValues(pool *pgxpool.Pool, list []Task) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(list))
+	index := make(map[int]int, len(list))
+	for i, t := range list {
+		ids = append(ids, t.ID)
+		index[t.ID] = i
+	}
+
+	rows, err := pool.Query(context.Background(), `SELECT v.task_id, f.id, f.name, f.field_type, v.value
+		FROM task_field_values v
+		JOIN tasks t ON t.id = v.task_id
+		JOIN project_fields f ON f.id = v.field_id AND f.project_id = t.project_id
+		WHERE v.task_id = ANY($1) ORDER BY f.position, f.id`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var v FieldValue
+		if err := rows.Scan(&taskID, &v.FieldID, &v.Name, &v.Type, &v.Value); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			list[i].Fields = append(list[i].Fields, v)
+		}
+	}
+	return rows.Err()
+}
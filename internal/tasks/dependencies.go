@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// attachBlockers loads the tasks blocking every task in the slice, open ones first.
+func attachBlockers(pool *pgxpool.Pool, list []Task) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(list))
+	index := make(map[int]int, len(list))
+	for i, t := range list {
+		ids = append(ids, t.ID)
+		index[t.ID] = i
+	}
+
+	rows, err := pool.Query(context.Background(), `SELECT d.task_id, b.id, b.title, COALESCE(b.completed, false)
+		FROM task_dependencies d JOIN tasks b ON b.id = d.blocker_id
+		WHERE d.task_id = ANY($1) AND b.deleted_at IS NULL ORDER BY COALESCE(b.completed, false), b.title`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var ref storage.TaskRef
+		if err := rows.Scan(&taskID, &ref.ID, &ref.Title, &ref.Completed); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			list[i].Blockers = append(list[i].Blockers, ref)
+		}
+	}
+	return rows.Err()
+}
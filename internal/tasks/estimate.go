@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Effort estimate units. Hours and points are never added together.
+const (
+	EstimateHours  = "hours"
+	EstimatePoints = "points"
+)
+
+// MaxEstimate is the largest effort estimate accepted for a single task.
+const MaxEstimate = 9999
+
+// ParseEstimate reads an effort estimate and its unit from the task form. An empty or zero
+// value means no estimate and returns 0 with an empty unit. Estimates are kept to two decimals.
+func ParseEstimate(value, unit string) (float64, string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, "", nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) || n < 0 || n > MaxEstimate {
+		return 0, "", fmt.Errorf("estimate must be a number between 0 and %d", MaxEstimate)
+	}
+	n = math.Round(n*100) / 100
+	if n == 0 {
+		return 0, "", nil
+	}
+	switch unit {
+	case EstimateHours, EstimatePoints:
+		return n, unit, nil
+	case "":
+		return n, EstimateHours, nil
+	}
+	return 0, "", fmt.Errorf("unknown estimate unit %q", unit)
+}
+
+// FormatEffort formats an amount of effort in the given unit, e.g. "2.5h" or "3 pts".
+func FormatEffort(amount float64, unit string) string {
+	n := strconv.FormatFloat(amount, 'f', -1, 64)
+	if unit == EstimatePoints {
+		if amount == 1 {
+			return n + " pt"
+		}
+		return n + " pts"
+	}
+	return n + "h"
+}
+
+// EstimateLabel returns the task's effort estimate for display, or "" when it has none.
+func (t Task) EstimateLabel() string {
+	if t.Estimate <= 0 || t.EstimateUnit == "" {
+		return ""
+	}
+	return FormatEffort(t.Estimate, t.EstimateUnit)
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
@@ -30,7 +32,7 @@ func ReturnTaskListForUser(userID *int) []Task {
 		return tasks
 	}
 
-	rows, err := pool.Query(context.Background(), "SELECT id, title, description, completed FROM tasks WHERE user_id = $1 ORDER BY id", *userID)
+	rows, err := pool.Query(context.Background(), "SELECT id, title, description, completed FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL AND archived_at IS NULL ORDER BY id", *userID)
 	if err != nil {
 		fmt.Println("Error in ListTasks (query):", err)
 		return tasks
@@ -49,137 +51,86 @@ func ReturnTaskListForUser(userID *int) []Task {
 	return tasks
 }
 
-func ReturnPagination(page, pageSize int) ([]Task, int, error) {
-	return ReturnPaginationForUser(page, pageSize, nil, "America/New_York")
-}
-
-func ReturnPaginationForUser(page, pageSize int, userID *int, timezone string) ([]Task, int, error) {
-	pool, err := storage.OpenDatabase()
-	if err != nil {
-		return nil, 0, err
-	}
-	defer storage.CloseDatabase(pool)
-
-	var tasks []Task
-	offset := (page - 1) * pageSize
-
-	// We'll fetch favorites separately so we can always show up to 5 favorites first on page 1
-	query := `SELECT t.id, t.title, t.description, t.completed, 
-		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM') AS date_added,
+// taskColumns is the select list shared by the paginated task queries. The user's
+// timezone is always bound to $1 so that timestamps are formatted in local time.
+const taskColumns = `SELECT t.id, t.title, COALESCE(t.description,''), COALESCE(t.completed,false),
+		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $1, 'YYYY/MM/DD HH:MI AM') AS date_added,
 		COALESCE(CAST(t.due_date AS TEXT), '') AS due_date,
-		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM') AS date_created,
-		COALESCE(TO_CHAR((t.date_modified AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM'), '') AS date_modified,
-		COALESCE(t.position,0), t.project_id, COALESCE(p.name,'')
+		COALESCE(TO_CHAR(t.due_time, 'HH24:MI'), '') AS due_time,
+		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $1, 'YYYY/MM/DD HH:MI AM') AS date_created,
+		COALESCE(TO_CHAR((t.date_modified AT TIME ZONE 'UTC') AT TIME ZONE $1, 'YYYY/MM/DD HH:MI AM'), '') AS date_modified,
+		COALESCE(t.is_favorite,false), COALESCE(t.position,0), t.project_id, COALESCE(p.name,''),
+		t.parent_id, COALESCE(t.repeat_rule,''), COALESCE(t.priority,0), COALESCE(t.notes,''),
+		(SELECT COUNT(*) FROM task_comments c WHERE c.task_id = t.id) AS comment_count,
+		(SELECT COUNT(*) FROM task_attachments a WHERE a.task_id = t.id) AS attachment_count,
+		(SELECT COALESCE(SUM(CAST(EXTRACT(EPOCH FROM (COALESCE(e.ended_at, NOW()) - e.started_at)) AS BIGINT)), 0)
+			FROM time_entries e WHERE e.task_id = t.id) AS tracked_seconds,
+		EXISTS (SELECT 1 FROM time_entries e WHERE e.task_id = t.id AND e.ended_at IS NULL) AS timer_running,
+		t.archived_at IS NOT NULL AS archived,
+		CASE WHEN t.hidden_until > CAST(NOW() AT TIME ZONE $1 AS DATE) THEN CAST(t.hidden_until AS TEXT) ELSE '' END AS hidden_until,
+		CAST(COALESCE(t.estimate, 0) AS DOUBLE PRECISION), COALESCE(t.estimate_unit, ''),
+		COALESCE(t.status_id, 0)
 		FROM tasks t LEFT JOIN projects p ON t.project_id = p.id `
 
-	var countQuery string
-	var rows interface {
-		Next() bool
-		Scan(...interface{}) error
-		Close()
-	}
-
-	if userID == nil {
-		// Not logged in - don't show any tasks
-		return tasks, 0, nil
-	}
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	// Logged in - show favorites first (up to 5) on page 1
-	// Fetch favorite tasks
-	favRows, err := pool.Query(context.Background(), `SELECT t.id, t.title, t.description, t.completed, TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_added, COALESCE(CAST(t.due_date AS TEXT), '') AS due_date, TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_created, COALESCE(TO_CHAR((t.date_modified AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM'), '') AS date_modified, COALESCE(t.is_favorite,false), COALESCE(t.position,0), t.project_id, COALESCE(p.name,'') FROM tasks t LEFT JOIN projects p ON t.project_id = p.id WHERE t.user_id = $1 AND t.is_favorite = true ORDER BY t.position`, *userID, timezone)
+// scanTask reads one row selected with taskColumns.
+func scanTask(row rowScanner) (Task, error) {
+	var t Task
+	var pid sql.NullInt64
+	var parentID sql.NullInt64
+	err := row.Scan(&t.ID, &t.Title, &t.Description, &t.Completed,
+		&t.DateAdded, &t.DueDate, &t.DueTime, &t.DateCreated, &t.DateModified,
+		&t.IsFavorite, &t.Position, &pid, &t.ProjectName,
+		&parentID, &t.RepeatRule, &t.Priority, &t.Notes, &t.CommentCount, &t.AttachmentCount,
+		&t.TrackedSeconds, &t.TimerRunning, &t.Archived, &t.HiddenUntil,
+		&t.Estimate, &t.EstimateUnit, &t.StatusID)
 	if err != nil {
-		return nil, 0, err
+		return t, err
 	}
-	defer favRows.Close()
-	favs := make([]Task, 0)
-	for favRows.Next() {
-		var t Task
-		var pid sql.NullInt64
-		var pname sql.NullString
-		if err := favRows.Scan(&t.ID, &t.Title, &t.Description, &t.Completed, &t.DateAdded, &t.DueDate, &t.DateCreated, &t.DateModified, &t.IsFavorite, &t.Position, &pid, &pname); err != nil {
-			return nil, 0, err
-		}
-		if pid.Valid {
-			t.ProjectID = int(pid.Int64)
-		} else {
-			t.ProjectID = 0
-		}
-		t.ProjectName = pname.String
-		favs = append(favs, t)
+	if pid.Valid {
+		t.ProjectID = int(pid.Int64)
+	}
+	if parentID.Valid {
+		t.ParentID = int(parentID.Int64)
 	}
+	return t, nil
+}
 
-	// Count total tasks
-	var totalTasks int
-	countQuery = "SELECT COUNT(*) FROM tasks WHERE user_id = $1"
-	err = pool.QueryRow(context.Background(), countQuery, *userID).Scan(&totalTasks)
+// queryTasks runs a query built on taskColumns and scans every row.
+func queryTasks(pool *pgxpool.Pool, query string, args ...interface{}) ([]Task, error) {
+	rows, err := pool.Query(context.Background(), query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Calculate how many favorites to show on this page
-	favCount := len(favs)
-	if page == 1 && favCount > 0 {
-		// Need to fetch non-favorites to fill remaining slots on page 1
-		remaining := pageSize - favCount
-		if remaining < 0 {
-			remaining = 0
-		}
-		rows, err = pool.Query(context.Background(), query+`WHERE t.user_id = $4 AND (t.is_favorite IS NULL OR t.is_favorite = false) ORDER BY t.position LIMIT $1 OFFSET $2`, remaining, 0, timezone, *userID)
-		if err != nil {
-			return nil, 0, err
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var task Task
-			var pid sql.NullInt64
-			var pname sql.NullString
-			if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.DateAdded, &task.DueDate, &task.DateCreated, &task.DateModified, &task.Position, &pid, &pname); err != nil {
-				return nil, 0, err
-			}
-			if pid.Valid {
-				task.ProjectID = int(pid.Int64)
-			} else {
-				task.ProjectID = 0
-			}
-			task.ProjectName = pname.String
-			tasks = append(tasks, task)
-		}
-		// prepend favorites
-		tasks = append(favs, tasks...)
-	} else {
-		// For pages >1, skip favorites in the offset calculation
-		// Compute offset among non-favorite items
-		offsetNonFav := offset - favCount
-		if offsetNonFav < 0 {
-			offsetNonFav = 0
-		}
-		rows, err = pool.Query(context.Background(), query+`WHERE t.user_id = $4 AND (t.is_favorite IS NULL OR t.is_favorite = false) ORDER BY t.position LIMIT $1 OFFSET $2`, pageSize, offsetNonFav, timezone, *userID)
+	list := make([]Task, 0)
+	for rows.Next() {
+		t, err := scanTask(rows)
 		if err != nil {
-			return nil, 0, err
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var task Task
-			var pid sql.NullInt64
-			var pname sql.NullString
-			if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.DateAdded, &task.DueDate, &task.DateCreated, &task.DateModified, &task.Position, &pid, &pname); err != nil {
-				return nil, 0, err
-			}
-			if pid.Valid {
-				task.ProjectID = int(pid.Int64)
-			} else {
-				task.ProjectID = 0
-			}
-			task.ProjectName = pname.String
-			tasks = append(tasks, task)
+			return nil, err
 		}
+		list = append(list, t)
 	}
-	return tasks, totalTasks, nil
+	return list, rows.Err()
+}
+
+func ReturnPaginationForUser(page, pageSize int, userID *int, timezone string) ([]Task, int, error) {
+	return ReturnPaginationForUserWithProject(page, pageSize, userID, timezone, nil)
 }
 
 // ReturnPaginationForUserWithProject behaves like ReturnPaginationForUser but filters tasks by project.
 // If projectFilter is nil, all projects are returned. If *projectFilter == 0, only tasks with no project are returned.
 func ReturnPaginationForUserWithProject(page, pageSize int, userID *int, timezone string, projectFilter *int) ([]Task, int, error) {
+	return ReturnPaginationForUserFiltered(page, pageSize, userID, timezone, projectFilter, TaskFilter{})
+}
+
+// ReturnPaginationForUserFiltered behaves like ReturnPaginationForUserWithProject and additionally
+// applies the tag filter and sort order.
+func ReturnPaginationForUserFiltered(page, pageSize int, userID *int, timezone string, projectFilter *int, filter TaskFilter) ([]Task, int, error) {
 	pool, err := storage.OpenDatabase()
 	if err != nil {
 		return nil, 0, err
@@ -189,115 +140,69 @@ func ReturnPaginationForUserWithProject(page, pageSize int, userID *int, timezon
 	var tasks []Task
 	offset := (page - 1) * pageSize
 
+	if userID == nil {
+		// Not logged in - don't show any tasks
+		return tasks, 0, nil
+	}
+
 	projectCond := ""
 	if projectFilter != nil {
 		if *projectFilter == 0 {
-			projectCond = " AND (project_id IS NULL)"
+			projectCond = " AND (t.project_id IS NULL)"
 		} else {
-			projectCond = fmt.Sprintf(" AND (project_id = %d)", *projectFilter)
+			projectCond = fmt.Sprintf(" AND (t.project_id = %d)", *projectFilter)
 		}
 	}
-
-	query := `SELECT t.id, t.title, t.description, t.completed, 
-		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM') AS date_added,
-		COALESCE(CAST(t.due_date AS TEXT), '') AS due_date,
-		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM') AS date_created,
-		TO_CHAR((COALESCE(t.date_modified, t.time_stamp) AT TIME ZONE 'UTC') AT TIME ZONE $3, 'YYYY/MM/DD HH:MI AM') AS date_modified,
-		COALESCE(t.position,0), t.project_id, COALESCE(p.name,'')
-		FROM tasks t LEFT JOIN projects p ON t.project_id = p.id `
-
-	var countQuery string
-	var rows interface {
-		Next() bool
-		Scan(...interface{}) error
-		Close()
-	}
-
-	if userID == nil {
-		return tasks, 0, nil
+	if filter.Today == "" {
+		filter.Today = LocalToday(timezone)
 	}
-
-	favRows, err := pool.Query(context.Background(), `SELECT t.id, t.title, t.description, t.completed, TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_added, COALESCE(CAST(t.due_date AS TEXT), '') AS due_date, TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_created, COALESCE(TO_CHAR((t.date_modified AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM'), '') AS date_modified, COALESCE(t.is_favorite,false), COALESCE(t.position,0), t.project_id, COALESCE(p.name,'') FROM tasks t LEFT JOIN projects p ON t.project_id = p.id WHERE t.user_id = $1 AND t.is_favorite = true`+projectCond+` ORDER BY t.position`, *userID, timezone)
+	// The list queries bind the timezone and user, the count only the user
+	args := sqlArgs{timezone, *userID}
+	cond := projectCond + filter.sqlCondition(&args) + filter.deferCondition()
+	countArgs := sqlArgs{*userID}
+	countCond := projectCond + filter.sqlCondition(&countArgs) + filter.deferCondition()
+
+	// Favorites are fetched separately so they always lead page 1
+	favs, err := queryTasks(pool, taskColumns+`WHERE t.user_id = $2 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.archived_at IS NULL AND t.is_favorite = true`+cond+filter.orderBy(), args...)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer favRows.Close()
-	favs := make([]Task, 0)
-	for favRows.Next() {
-		var t Task
-		var pid sql.NullInt64
-		var pname sql.NullString
-		if err := favRows.Scan(&t.ID, &t.Title, &t.Description, &t.Completed, &t.DateAdded, &t.DueDate, &t.DateCreated, &t.DateModified, &t.IsFavorite, &t.Position, &pid, &pname); err != nil {
-			return nil, 0, err
-		}
-		if pid.Valid {
-			t.ProjectID = int(pid.Int64)
-		} else {
-			t.ProjectID = 0
-		}
-		t.ProjectName = pname.String
-		favs = append(favs, t)
-	}
 
 	var totalTasks int
-	countQuery = "SELECT COUNT(*) FROM tasks WHERE user_id = $1" + projectCond
-	err = pool.QueryRow(context.Background(), countQuery, *userID).Scan(&totalTasks)
+	countQuery := "SELECT COUNT(*) FROM tasks t WHERE t.user_id = $1 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.archived_at IS NULL" + countCond
+	err = pool.QueryRow(context.Background(), countQuery, countArgs...).Scan(&totalTasks)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	nonFavQuery := taskColumns + `WHERE t.user_id = $2 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.archived_at IS NULL AND (t.is_favorite IS NULL OR t.is_favorite = false)` + cond + filter.orderBy() +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
 	favCount := len(favs)
 	if page == 1 && favCount > 0 {
+		// Fill the remaining slots on page 1 with non-favorites
 		remaining := pageSize - favCount
 		if remaining < 0 {
 			remaining = 0
 		}
-		rows, err = pool.Query(context.Background(), query+`WHERE t.user_id = $4 AND (t.is_favorite IS NULL OR t.is_favorite = false)`+projectCond+` ORDER BY t.position LIMIT $1 OFFSET $2`, remaining, 0, timezone, *userID)
+		tasks, err = queryTasks(pool, nonFavQuery, append(args, remaining, 0)...)
 		if err != nil {
 			return nil, 0, err
 		}
-		defer rows.Close()
-		for rows.Next() {
-			var task Task
-			var pid sql.NullInt64
-			var pname sql.NullString
-			if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.DateAdded, &task.DueDate, &task.DateCreated, &task.DateModified, &task.Position, &pid, &pname); err != nil {
-				return nil, 0, err
-			}
-			if pid.Valid {
-				task.ProjectID = int(pid.Int64)
-			} else {
-				task.ProjectID = 0
-			}
-			task.ProjectName = pname.String
-			tasks = append(tasks, task)
-		}
 		tasks = append(favs, tasks...)
 	} else {
+		// For pages >1, skip favorites in the offset calculation
 		offsetNonFav := offset - favCount
 		if offsetNonFav < 0 {
 			offsetNonFav = 0
 		}
-		rows, err = pool.Query(context.Background(), query+`WHERE t.user_id = $4 AND (t.is_favorite IS NULL OR t.is_favorite = false)`+projectCond+` ORDER BY t.position LIMIT $1 OFFSET $2`, pageSize, offsetNonFav, timezone, *userID)
+		tasks, err = queryTasks(pool, nonFavQuery, append(args, pageSize, offsetNonFav)...)
 		if err != nil {
 			return nil, 0, err
 		}
-		defer rows.Close()
-		for rows.Next() {
-			var task Task
-			var pid sql.NullInt64
-			var pname sql.NullString
-			if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.DateAdded, &task.DueDate, &task.DateCreated, &task.DateModified, &task.Position, &pid, &pname); err != nil {
-				return nil, 0, err
-			}
-			if pid.Valid {
-				task.ProjectID = int(pid.Int64)
-			} else {
-				task.ProjectID = 0
-			}
-			task.ProjectName = pname.String
-			tasks = append(tasks, task)
-		}
+	}
+	if err := attachRelated(pool, tasks, *userID, timezone); err != nil {
+		return nil, 0, err
 	}
 	return tasks, totalTasks, nil
 }
@@ -307,6 +212,12 @@ func SearchTasks(page, pageSize int, searchQuery string) ([]Task, int, error) {
 }
 
 func SearchTasksForUser(page, pageSize int, searchQuery string, userID *int, timezone string) ([]Task, int, error) {
+	return SearchTasksForUserFiltered(page, pageSize, searchQuery, userID, timezone, TaskFilter{})
+}
+
+// SearchTasksForUserFiltered behaves like SearchTasksForUser and additionally applies the tag filter
+// and sort order.
+func SearchTasksForUserFiltered(page, pageSize int, searchQuery string, userID *int, timezone string, filter TaskFilter) ([]Task, int, error) {
 	pool, err := storage.OpenDatabase()
 	if err != nil {
 		return nil, 0, err
@@ -323,38 +234,49 @@ func SearchTasksForUser(page, pageSize int, searchQuery string, userID *int, tim
 		return tasks, 0, nil
 	}
 
-	rows, err := pool.Query(context.Background(),
-		`SELECT id,
-		    title, 
-		    description,
-		    completed, 
-		    TO_CHAR((time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MM AM') as date_added,
-		    COALESCE(CAST(due_date AS TEXT), '') AS due_date,
-		    TO_CHAR((time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MM AM') AS date_created,
-		    COALESCE(TO_CHAR((date_modified AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MM AM'), '') AS date_modified,
-		    COALESCE(position,0)
-		 FROM tasks 
-		 WHERE (title ILIKE $1 OR description ILIKE $1) AND user_id = $4
-		 ORDER BY position 
-		 LIMIT $3 OFFSET $5`,
-		searchPattern, timezone, pageSize, *userID, offset)
-
+	args := sqlArgs{timezone, searchPattern, *userID}
+	cond := filter.sqlCondition(&args)
+	tasks, err = queryTasks(pool, taskColumns+`WHERE `+searchCondition(2, 3)+cond+filter.orderBy()+
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2),
+		append(args, pageSize, offset)...)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	defer rows.Close()
-	var totalTasks int = 0
-
-	for rows.Next() {
-		var task Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Completed, &task.DateAdded, &task.DueDate, &task.DateCreated, &task.DateModified, &task.Position); err != nil {
-			return nil, 0, err
-		}
-		totalTasks++
-		tasks = append(tasks, task)
+	var totalTasks int
+	countArgs := sqlArgs{searchPattern, *userID}
+	countCond := filter.sqlCondition(&countArgs)
+	err = pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks t WHERE "+searchCondition(1, 2)+countCond, countArgs...).Scan(&totalTasks)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := attachRelated(pool, tasks, *userID, timezone); err != nil {
+		return nil, 0, err
 	}
 
 	return tasks, totalTasks, nil
 
 }
+
+// searchCondition matches the user's top-level tasks whose title or description contains
+// the search pattern bound to $pattern, with the user bound to $user. Archived tasks
+// stay searchable.
+func searchCondition(pattern, user int) string {
+	return fmt.Sprintf("(t.title ILIKE $%d OR t.description ILIKE $%d) AND t.user_id = $%d AND t.parent_id IS NULL AND t.deleted_at IS NULL", pattern, pattern, user)
+}
+
+// CountSearchTasksForUser returns the number of tasks SearchTasksForUserFiltered finds
+// for searchQuery and the filter across all pages.
+func CountSearchTasksForUser(searchQuery string, userID int, filter TaskFilter) (int, error) {
+	pool, err := storage.OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer storage.CloseDatabase(pool)
+
+	var count int
+	args := sqlArgs{"%" + searchQuery + "%", userID}
+	cond := filter.sqlCondition(&args)
+	err = pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM tasks t WHERE "+searchCondition(1, 2)+cond, args...).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,102 @@
+package tasks
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// notesMarkdown renders task notes. Raw HTML is never passed through (goldmark drops it
+// unless WithUnsafe is set), and notesLinkSanitizer restricts link and image targets.
+var notesMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(notesLinkSanitizer{}, 100)),
+	),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// notesLinkRel is set on every rendered link so notes cannot pass on referrers or ranking.
+var notesLinkRel = []byte("nofollow noopener noreferrer")
+
+// notesLinkSanitizer clears link and image destinations with a scheme other than http,
+// https or mailto, and turns such autolinks back into plain text. goldmark's own check
+// is case-sensitive and skips autolinks entirely.
+type notesLinkSanitizer struct{}
+
+func (notesLinkSanitizer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var unsafeAutoLinks []*ast.AutoLink
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			if !isSafeNotesURL(node.Destination) {
+				node.Destination = nil
+			}
+			node.SetAttributeString("rel", notesLinkRel)
+		case *ast.Image:
+			if !isSafeNotesURL(node.Destination) {
+				node.Destination = nil
+			}
+		case *ast.AutoLink:
+			if node.AutoLinkType == ast.AutoLinkURL && !isSafeNotesURL(node.URL(source)) {
+				unsafeAutoLinks = append(unsafeAutoLinks, node)
+			} else {
+				node.SetAttributeString("rel", notesLinkRel)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, link := range unsafeAutoLinks {
+		label := ast.NewString(link.Label(source))
+		label.SetCode(true) // written verbatim (escaped) by the renderer
+		link.Parent().ReplaceChild(link.Parent(), link, label)
+	}
+}
+
+// isSafeNotesURL allows relative URLs, fragments and the http, https and mailto schemes.
+func isSafeNotesURL(dest []byte) bool {
+	u := strings.ToLower(strings.TrimSpace(string(dest)))
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.ContainsAny(u[:colon], "/?#") {
+		return true
+	}
+	switch u[:colon] {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// RenderNotes converts Markdown notes to sanitized HTML for display.
+func RenderNotes(md string) template.HTML {
+	if strings.TrimSpace(md) == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := notesMarkdown.Convert([]byte(md), &buf); err != nil {
+		return template.HTML("<pre>" + template.HTMLEscapeString(md) + "</pre>")
+	}
+	return template.HTML(buf.String())
+}
+
+// HasNotes reports whether the task has any notes.
+func (t Task) HasNotes() bool {
+	return strings.TrimSpace(t.Notes) != ""
+}
+
+// NotesHTML returns the task notes rendered as sanitized HTML.
+func (t Task) NotesHTML() template.HTML {
+	return RenderNotes(t.Notes)
+}
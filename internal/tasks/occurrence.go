@@ -0,0 +1,114 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateNextOccurrence schedules the next occurrence of a recurring task that has just
+// been completed. The new task copies the title, description, notes, project, favorite
+// flag, priority, estimate, due time, reminders, tags, custom field values and subtasks,
+// and starts in the first open status of its workflow. It takes over the repeat rule so
+// that re-completing the old task never creates a duplicate. It returns the id of the new
+// task, or 0 when the task does not repeat or its rule has run out.
+func CreateNextOccurrence(taskID, userID int, timezone string) (int, error) {
+	pool, err := storage.OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer storage.CloseDatabase(pool)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var title, description, notes, dueDate, repeatRule string
+	var projectID sql.NullInt64
+	var isFavorite bool
+	var priority int
+	var dueTime, estimate, estimateUnit sql.NullString
+	err = tx.QueryRow(ctx, `SELECT title, COALESCE(description,''), project_id, COALESCE(is_favorite,false),
+		COALESCE(CAST(due_date AS TEXT), ''), COALESCE(repeat_rule,''), COALESCE(priority,0), CAST(due_time AS TEXT), COALESCE(notes,''),
+		CAST(estimate AS TEXT), estimate_unit
+		FROM tasks WHERE id = $1 AND user_id = $2 AND parent_id IS NULL AND deleted_at IS NULL FOR UPDATE`, taskID, userID).Scan(
+		&title, &description, &projectID, &isFavorite, &dueDate, &repeatRule, &priority, &dueTime, &notes, &estimate, &estimateUnit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	rule, err := ParseRepeatRule(repeatRule)
+	if err != nil || rule == nil {
+		return 0, err
+	}
+
+	next, nextRule, ok := rule.NextOccurrence(dueDate, time.Now().In(UserLocation(timezone)))
+
+	// The rule always leaves the completed task, whether or not it continues
+	if _, err := tx.Exec(ctx, "UPDATE tasks SET repeat_rule = NULL WHERE id = $1", taskID); err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, tx.Commit(ctx)
+	}
+
+	// Append to the end of the same group (favorites or regular) the original belonged to
+	var nextPos int
+	err = tx.QueryRow(ctx, "SELECT COALESCE(MAX(position),0) + 1 FROM tasks WHERE user_id = $1 AND parent_id IS NULL AND COALESCE(is_favorite,false) = $2", userID, isFavorite).Scan(&nextPos)
+	if err != nil {
+		return 0, err
+	}
+
+	var newID int
+	err = tx.QueryRow(ctx, `INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id, due_date, is_favorite, repeat_rule, priority, due_time, notes,
+		estimate, estimate_unit)
+		VALUES ($1, $2, false, $3, NOW() AT TIME ZONE 'UTC', $4, $5, $6, $7, $8, $9, CAST($10 AS TIME), $11, CAST($12 AS NUMERIC), $13) RETURNING id`,
+		title, description, userID, nextPos, projectID, next.Format("2006-01-02"), isFavorite, nextRule.String(), priority, dueTime, notes,
+		estimate, estimateUnit).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE tasks SET status_id = "+storage.StatusAfterSQL("false", "tasks.project_id")+" WHERE id = $1", newID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Checklists repeat with the task, starting out unchecked
+	_, err = tx.Exec(ctx, `INSERT INTO tasks (title, description, completed, user_id, time_stamp, position, project_id, parent_id)
+		SELECT title, description, false, user_id, NOW() AT TIME ZONE 'UTC', position, project_id, $1
+		FROM tasks WHERE parent_id = $2 AND user_id = $3 AND deleted_at IS NULL ORDER BY position`, newID, taskID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO task_tags (task_id, tag_id) SELECT $1, tag_id FROM task_tags WHERE task_id = $2", newID, taskID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO task_reminders (task_id, offset_minutes) SELECT $1, offset_minutes FROM task_reminders WHERE task_id = $2", newID, taskID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO task_field_values (task_id, field_id, value) SELECT $1, field_id, value FROM task_field_values WHERE task_id = $2", newID, taskID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
@@ -0,0 +1,68 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Priority levels, stored as small integers so that higher means more important.
+const (
+	PriorityNone = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+// PriorityNames lists the priority levels from PriorityNone to PriorityUrgent.
+var PriorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority accepts a level name ("high") or its number ("3"). Empty input is PriorityNone.
+func ParsePriority(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return PriorityNone, nil
+	}
+	for i, name := range PriorityNames {
+		if s == name {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= PriorityNone && n <= PriorityUrgent {
+		return n, nil
+	}
+	return PriorityNone, fmt.Errorf("unknown priority %q", s)
+}
+
+// PriorityName returns the lower-case level name, e.g. "high".
+func (t Task) PriorityName() string {
+	if t.Priority < PriorityNone || t.Priority > PriorityUrgent {
+		return PriorityNames[PriorityNone]
+	}
+	return PriorityNames[t.Priority]
+}
+
+// PriorityLabel returns the level name for display, or "" when the task has no priority.
+func (t Task) PriorityLabel() string {
+	if t.Priority <= PriorityNone || t.Priority > PriorityUrgent {
+		return ""
+	}
+	name := t.PriorityName()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// PriorityBadgeClass returns the Bootstrap classes for the priority badge.
+func (t Task) PriorityBadgeClass() string {
+	switch t.Priority {
+	case PriorityUrgent:
+		return "bg-danger"
+	case PriorityHigh:
+		return "bg-warning text-dark"
+	case PriorityMedium:
+		return "bg-info text-dark"
+	case PriorityLow:
+		return "bg-secondary"
+	}
+	return ""
+}
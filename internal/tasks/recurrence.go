@@ -0,0 +1,319 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported repeat frequencies. Rules are stored on the task as a subset of the
+// iCalendar RRULE syntax, e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR".
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// maxRepeatInterval bounds INTERVAL so a typo can't push a task centuries ahead.
+const maxRepeatInterval = 365
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var weekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// RepeatRule describes how a recurring task repeats.
+type RepeatRule struct {
+	Freq     string
+	Interval int
+	Weekdays []time.Weekday // WEEKLY only; empty means the weekday of the current occurrence
+	MonthDay int            // MONTHLY and YEARLY; 1-31, -1 for the last day, 0 for the day of the current occurrence
+	Until    time.Time      // zero when the rule has no end date
+	Count    int            // remaining occurrences including the current one; 0 when unlimited
+}
+
+// ParseRepeatRule parses a stored repeat rule. An empty string yields a nil rule.
+// Only FREQ, INTERVAL, BYDAY (weekly), BYMONTHDAY (monthly and yearly), UNTIL and COUNT
+// are understood. A yearly BYMONTHDAY is a day of the month the task is due in.
+func ParseRepeatRule(s string) (*RepeatRule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	if s == "" {
+		return nil, nil
+	}
+
+	rule := &RepeatRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid repeat rule part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported repeat frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxRepeatInterval {
+				return nil, fmt.Errorf("repeat interval must be between 1 and %d", maxRepeatInterval)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, ok := parseWeekdayCode(code)
+				if !ok {
+					return nil, fmt.Errorf("unsupported weekday %q", code)
+				}
+				if !containsWeekday(rule.Weekdays, wd) {
+					rule.Weekdays = append(rule.Weekdays, wd)
+				}
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return nil, fmt.Errorf("repeat day of month must be between 1 and 31, or -1 for the last day")
+			}
+			rule.MonthDay = n
+		case "UNTIL":
+			// Accept both the date form and the UTC date-time form of UNTIL
+			if len(value) > 8 {
+				value = value[:8]
+			}
+			until, err := time.Parse("20060102", value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid repeat end date %q", value)
+			}
+			rule.Until = until
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("repeat count must be a positive number")
+			}
+			rule.Count = n
+		default:
+			return nil, fmt.Errorf("unsupported repeat rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("repeat rule is missing FREQ")
+	}
+	if len(rule.Weekdays) > 0 && rule.Freq != FreqWeekly {
+		return nil, fmt.Errorf("BYDAY is only supported for weekly rules")
+	}
+	if rule.MonthDay != 0 && rule.Freq != FreqMonthly && rule.Freq != FreqYearly {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported for monthly and yearly rules")
+	}
+	return rule, nil
+}
+
+func parseWeekdayCode(code string) (time.Weekday, bool) {
+	code = strings.TrimSpace(code)
+	for i, c := range weekdayCodes {
+		if c == code {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+func containsWeekday(list []time.Weekday, wd time.Weekday) bool {
+	for _, d := range list {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the canonical RRULE form of the rule, suitable for storage.
+func (r RepeatRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		codes := make([]string, 0, len(r.Weekdays))
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if containsWeekday(r.Weekdays, wd) {
+				codes = append(codes, weekdayCodes[wd])
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.MonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe returns a short human readable summary such as "Every 2 weeks on Mon, Fri".
+func (r RepeatRule) Describe() string {
+	var desc string
+	switch r.Freq {
+	case FreqDaily:
+		desc = plural(r.Interval, "Daily", "day")
+	case FreqWeekly:
+		desc = plural(r.Interval, "Weekly", "week")
+		if len(r.Weekdays) > 0 {
+			names := make([]string, 0, len(r.Weekdays))
+			for wd := time.Sunday; wd <= time.Saturday; wd++ {
+				if containsWeekday(r.Weekdays, wd) {
+					names = append(names, weekdayNames[wd])
+				}
+			}
+			desc += " on " + strings.Join(names, ", ")
+		}
+	case FreqMonthly:
+		desc = plural(r.Interval, "Monthly", "month")
+		if r.MonthDay == -1 {
+			desc += " on the last day"
+		} else if r.MonthDay > 0 {
+			desc += fmt.Sprintf(" on day %d", r.MonthDay)
+		}
+	case FreqYearly:
+		desc = plural(r.Interval, "Yearly", "year")
+	}
+	if !r.Until.IsZero() {
+		desc += " until " + r.Until.Format("2006-01-02")
+	}
+	if r.Count > 0 {
+		desc += fmt.Sprintf(" (%d left)", r.Count)
+	}
+	return desc
+}
+
+func plural(n int, single, unit string) string {
+	if n <= 1 {
+		return single
+	}
+	return fmt.Sprintf("Every %d %ss", n, unit)
+}
+
+// Next returns the first occurrence strictly after the given date. Only the
+// calendar date of after is used; the result is midnight UTC on that date. Without
+// MonthDay, monthly and yearly rules keep the day of after, which drifts once a date
+// was clamped to a shorter month; NextOccurrence sets MonthDay to avoid that.
+func (r RepeatRule) Next(after time.Time) time.Time {
+	d := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC)
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Freq {
+	case FreqWeekly:
+		if len(r.Weekdays) == 0 {
+			return d.AddDate(0, 0, 7*interval)
+		}
+		// Walk forward day by day; only weeks that are a multiple of the interval
+		// away from the current occurrence's week qualify.
+		start := weekStart(d)
+		for i := 1; i <= 7*interval+7; i++ {
+			c := d.AddDate(0, 0, i)
+			weeks := int(weekStart(c).Sub(start).Hours()/24) / 7
+			if weeks%interval == 0 && containsWeekday(r.Weekdays, c.Weekday()) {
+				return c
+			}
+		}
+		return d.AddDate(0, 0, 7*interval)
+	case FreqMonthly:
+		day := r.MonthDay
+		if day == 0 {
+			day = d.Day()
+		}
+		// A fixed day later in the current month comes first
+		if r.MonthDay != 0 {
+			if c := dateInMonth(d.Year(), d.Month(), day); c.After(d) {
+				return c
+			}
+		}
+		return dateInMonth(d.Year(), d.Month()+time.Month(interval), day)
+	case FreqYearly:
+		day := r.MonthDay
+		if day == 0 {
+			day = d.Day()
+		}
+		return dateInMonth(d.Year()+interval, d.Month(), day)
+	default:
+		return d.AddDate(0, 0, interval)
+	}
+}
+
+// weekStart returns the Monday that starts the week containing d.
+func weekStart(d time.Time) time.Time {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// dateInMonth returns the given day of a month, clamped to the month's length.
+// A day of -1 means the last day of the month.
+func dateInMonth(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if day == -1 || day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// NextOccurrence works out when a recurring task comes up again after being completed.
+// The schedule continues from the task's due date, or from today when it has none, and
+// skips occurrences that are already in the past. It returns the new due date and the
+// rule to store on the next occurrence; ok is false when the rule has run out. Monthly
+// and yearly rules are pinned to the day they start from, so a task due on Jan 31 comes
+// back on Feb 28 and then Mar 31, and one due on Feb 29 returns on Feb 29 in leap years.
+func (r RepeatRule) NextOccurrence(dueDate string, today time.Time) (next time.Time, rule RepeatRule, ok bool) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	base := today
+	if dueDate != "" {
+		if d, err := time.Parse("2006-01-02", dueDate); err == nil {
+			base = d
+		}
+	}
+
+	rule = r
+	if (rule.Freq == FreqMonthly || rule.Freq == FreqYearly) && rule.MonthDay == 0 {
+		rule.MonthDay = base.Day()
+	}
+	if rule.Count == 1 {
+		return time.Time{}, rule, false
+	}
+
+	next = rule.Next(base)
+	for i := 0; next.Before(today) && i < 1000; i++ {
+		next = rule.Next(next)
+	}
+	if !rule.Until.IsZero() && next.After(rule.Until) {
+		return time.Time{}, rule, false
+	}
+	if rule.Count > 1 {
+		rule.Count--
+	}
+	return next, rule, true
+}
+
+// RepeatSummary returns a human readable description of the task's repeat rule,
+// or an empty string when the task does not repeat.
+func (t Task) RepeatSummary() string {
+	rule, err := ParseRepeatRule(t.RepeatRule)
+	if err != nil || rule == nil {
+		return ""
+	}
+	return rule.Describe()
+}
@@ -0,0 +1,187 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParseRepeatRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{in: "rrule:freq=weekly;interval=2;byday=fr,mo", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=31", want: "FREQ=MONTHLY;BYMONTHDAY=31"},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=-1", want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{in: "FREQ=YEARLY;BYMONTHDAY=29", want: "FREQ=YEARLY;BYMONTHDAY=29"},
+		{in: "FREQ=DAILY;UNTIL=20261231T000000Z", want: "FREQ=DAILY;UNTIL=20261231"},
+		{in: "FREQ=DAILY;COUNT=3", want: "FREQ=DAILY;COUNT=3"},
+		{in: "INTERVAL=2", wantErr: true},
+		{in: "FREQ=HOURLY", wantErr: true},
+		{in: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{in: "FREQ=DAILY;INTERVAL=366", wantErr: true},
+		{in: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+		{in: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{in: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=0", wantErr: true},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=-2", wantErr: true},
+		{in: "FREQ=DAILY;COUNT=0", wantErr: true},
+		{in: "FREQ=DAILY;UNTIL=tomorrow", wantErr: true},
+		{in: "FREQ=DAILY;BYSETPOS=1", wantErr: true},
+		{in: "FREQ", wantErr: true},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRepeatRule(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRepeatRule(%q) = %v, want an error", tt.in, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRepeatRule(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		got := ""
+		if rule != nil {
+			got = rule.String()
+		}
+		if got != tt.want {
+			t.Errorf("ParseRepeatRule(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRepeatRuleNext(t *testing.T) {
+	tests := []struct {
+		rule  string
+		after string
+		want  string
+	}{
+		{"FREQ=DAILY", "2026-12-31", "2027-01-01"},
+		{"FREQ=DAILY;INTERVAL=3", "2026-02-27", "2026-03-02"},
+		{"FREQ=WEEKLY", "2026-10-16", "2026-10-23"},
+		// 2026-10-16 is a Friday
+		{"FREQ=WEEKLY;BYDAY=MO,FR", "2026-10-16", "2026-10-19"},
+		{"FREQ=WEEKLY;BYDAY=MO,FR", "2026-10-19", "2026-10-23"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2026-10-16", "2026-10-26"},
+		{"FREQ=MONTHLY", "2026-01-15", "2026-02-15"},
+		{"FREQ=MONTHLY", "2026-01-31", "2026-02-28"},
+		{"FREQ=MONTHLY", "2024-01-31", "2024-02-29"},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", "2026-02-28", "2026-03-31"},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", "2026-03-31", "2026-04-30"},
+		{"FREQ=MONTHLY;BYMONTHDAY=20", "2026-10-16", "2026-10-20"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-31", "2026-02-28"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-02-10", "2026-02-28"},
+		{"FREQ=MONTHLY;INTERVAL=3", "2026-11-30", "2027-02-28"},
+		{"FREQ=YEARLY", "2026-10-16", "2027-10-16"},
+		{"FREQ=YEARLY", "2024-02-29", "2025-02-28"},
+		{"FREQ=YEARLY;BYMONTHDAY=29", "2027-02-28", "2028-02-29"},
+		{"FREQ=YEARLY;INTERVAL=4", "2024-02-29", "2028-02-29"},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRepeatRule(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseRepeatRule(%q) returned error: %v", tt.rule, err)
+		}
+		got := rule.Next(date(tt.after)).Format("2006-01-02")
+		if got != tt.want {
+			t.Errorf("%s: Next(%s) = %s, want %s", tt.rule, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestRepeatRuleNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		due   string
+		today string
+		want  []string // successive due dates; the last step runs out when it ends in ""
+	}{
+		{
+			name:  "month end",
+			rule:  "FREQ=MONTHLY",
+			due:   "2026-01-31",
+			today: "2026-01-01",
+			want:  []string{"2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"},
+		},
+		{
+			name:  "month end in a leap year",
+			rule:  "FREQ=MONTHLY",
+			due:   "2024-01-30",
+			today: "2024-01-01",
+			want:  []string{"2024-02-29", "2024-03-30"},
+		},
+		{
+			name:  "leap day",
+			rule:  "FREQ=YEARLY",
+			due:   "2024-02-29",
+			today: "2024-01-01",
+			want:  []string{"2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"},
+		},
+		{
+			name:  "skips past occurrences",
+			rule:  "FREQ=WEEKLY",
+			due:   "2026-09-01",
+			today: "2026-10-17",
+			want:  []string{"2026-10-20"},
+		},
+		{
+			name:  "count runs out",
+			rule:  "FREQ=DAILY;COUNT=3",
+			due:   "2026-10-17",
+			today: "2026-10-17",
+			want:  []string{"2026-10-18", "2026-10-19", ""},
+		},
+		{
+			name:  "until runs out",
+			rule:  "FREQ=MONTHLY;UNTIL=20261231",
+			due:   "2026-10-31",
+			today: "2026-10-17",
+			want:  []string{"2026-11-30", "2026-12-31", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRepeatRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRepeatRule(%q) returned error: %v", tt.rule, err)
+			}
+			// Each step stores the returned rule and reparses it, like completing the
+			// new task would.
+			due := tt.due
+			for i, want := range tt.want {
+				next, nextRule, ok := rule.NextOccurrence(due, date(tt.today))
+				if want == "" {
+					if ok {
+						t.Fatalf("step %d: got %s, want the rule to have run out", i, next.Format("2006-01-02"))
+					}
+					break
+				}
+				if !ok {
+					t.Fatalf("step %d: rule ran out, want %s", i, want)
+				}
+				if got := next.Format("2006-01-02"); got != want {
+					t.Fatalf("step %d: got %s, want %s", i, got, want)
+				}
+				if rule, err = ParseRepeatRule(nextRule.String()); err != nil {
+					t.Fatalf("step %d: stored rule %q does not parse: %v", i, nextRule.String(), err)
+				}
+				due = want
+			}
+		})
+	}
+}
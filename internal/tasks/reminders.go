@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"fmt"
+	"time"
+)
+
+// DefaultReminderTime is the time of day reminders count from when a task has a due date
+// but no due time.
+const DefaultReminderTime = "09:00"
+
+// reminderGrace is how long after the due instant a reminder may still be sent, e.g. after
+// the server was down. Older reminders are skipped rather than delivered late.
+const reminderGrace = time.Hour
+
+// ReminderPreset is one of the reminder offsets offered in the task form.
+type ReminderPreset struct {
+	Minutes int
+	Label   string
+}
+
+// ReminderPresets lists the reminder offsets offered in the task form, in minutes before due.
+var ReminderPresets = []ReminderPreset{
+	{0, "At due time"},
+	{15, "15 minutes before"},
+	{60, "1 hour before"},
+	{1440, "1 day before"},
+	{10080, "1 week before"},
+}
+
+// IsReminderPreset reports whether minutes is one of ReminderPresets.
+func IsReminderPreset(minutes int) bool {
+	for _, p := range ReminderPresets {
+		if p.Minutes == minutes {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeReminderOffset returns a label such as "1 day before".
+func DescribeReminderOffset(minutes int) string {
+	for _, p := range ReminderPresets {
+		if p.Minutes == minutes {
+			return p.Label
+		}
+	}
+	switch {
+	case minutes%1440 == 0:
+		return fmt.Sprintf("%d days before", minutes/1440)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d hours before", minutes/60)
+	}
+	return fmt.Sprintf("%d minutes before", minutes)
+}
+
+// UserLocation loads a user's timezone, falling back to UTC when it is empty or unknown.
+func UserLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// DueInstant returns the moment a task is due: its due date at the due time (or
+// DefaultReminderTime) in the given timezone. ok is false when the date does not parse.
+func DueInstant(dueDate, dueTime, timezone string) (time.Time, bool) {
+	if dueDate == "" {
+		return time.Time{}, false
+	}
+	if dueTime == "" {
+		dueTime = DefaultReminderTime
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04", dueDate+" "+dueTime, UserLocation(timezone))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// DueReminder is a reminder whose send time has come.
+type DueReminder struct {
+	storage.ReminderCandidate
+	DueAt time.Time
+}
+
+// DueReminders returns the reminders that should be sent at now and have not yet been
+// delivered for the task's current due instant.
+func DueReminders(now time.Time) ([]DueReminder, error) {
+	candidates, err := storage.ListReminderCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	due := make([]DueReminder, 0)
+	for _, c := range candidates {
+		dueAt, ok := DueInstant(c.DueDate, c.DueTime, c.Timezone)
+		if !ok {
+			continue
+		}
+		if c.SentFor != nil && c.SentFor.Equal(dueAt) {
+			continue
+		}
+		sendAt := dueAt.Add(-time.Duration(c.OffsetMinutes) * time.Minute)
+		if sendAt.After(now) || now.Sub(dueAt) > reminderGrace {
+			continue
+		}
+		due = append(due, DueReminder{ReminderCandidate: c, DueAt: dueAt})
+	}
+	return due, nil
+}
+
+// DueLabel formats the due date and time for display, e.g. "2026-10-17 15:00".
+func (t Task) DueLabel() string {
+	if t.DueTime == "" {
+		return t.DueDate
+	}
+	return t.DueDate + " " + t.DueTime
+}
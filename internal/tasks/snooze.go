@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSnoozeDate is returned for a snooze date that is not a valid day after today.
+var ErrSnoozeDate = errors.New("pick a date after today")
+
+// LocalToday returns today's date (YYYY-MM-DD) in the user's timezone.
+func LocalToday(timezone string) string {
+	return time.Now().In(UserLocation(timezone)).Format("2006-01-02")
+}
+
+// SnoozeUntil resolves a snooze preset, or a YYYY-MM-DD date when preset is "date", to the
+// day a task reappears, relative to now in the user's timezone. "weekend" is the coming
+// Saturday and "next_week" the coming Monday.
+func SnoozeUntil(preset, date string, now time.Time, timezone string) (string, error) {
+	local := now.In(UserLocation(timezone))
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	var until time.Time
+	switch preset {
+	case "tomorrow":
+		until = today.AddDate(0, 0, 1)
+	case "weekend":
+		until = today.AddDate(0, 0, daysUntil(today.Weekday(), time.Saturday))
+	case "next_week":
+		until = today.AddDate(0, 0, daysUntil(today.Weekday(), time.Monday))
+	case "date":
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil || !d.After(today) {
+			return "", ErrSnoozeDate
+		}
+		until = d
+	default:
+		return "", ErrSnoozeDate
+	}
+	return until.Format("2006-01-02"), nil
+}
+
+// daysUntil counts the days from one weekday to the next occurrence of another, 1 to 7.
+func daysUntil(from, to time.Weekday) int {
+	n := (int(to) - int(from) + 7) % 7
+	if n == 0 {
+		n = 7
+	}
+	return n
+}
+
+// HiddenUntilLabel formats the day a snoozed task reappears, e.g. "Mon, Jan 2".
+func (t Task) HiddenUntilLabel() string {
+	d, err := time.Parse("2006-01-02", t.HiddenUntil)
+	if err != nil {
+		return t.HiddenUntil
+	}
+	return d.Format("Mon, Jan 2")
+}
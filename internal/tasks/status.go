@@ -0,0 +1,25 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// attachStatuses sets the workflow status of every task in the slice, and the statuses
+// of its workflow for moving it to another one.
+func attachStatuses(pool *pgxpool.Pool, list []Task, userID int) error {
+	if len(list) == 0 {
+		return nil
+	}
+	statuses, err := storage.QueryStatuses(pool, userID)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		workflow, _ := storage.WorkflowFor(statuses, list[i].ProjectID)
+		list[i].Statuses = workflow
+		list[i].Status = storage.ResolveStatus(workflow, list[i].StatusID, list[i].Completed)
+	}
+	return nil
+}
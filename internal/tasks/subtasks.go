@@ -0,0 +1,65 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// attachSubtasks loads the child tasks for every task in the slice and stores them on
+// the parent. Children are ordered by their position within the parent.
+func attachSubtasks(pool *pgxpool.Pool, list []Task, timezone string) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(list))
+	index := make(map[int]int, len(list))
+	for i, t := range list {
+		ids = append(ids, t.ID)
+		index[t.ID] = i
+	}
+
+	rows, err := pool.Query(context.Background(), `SELECT t.id, t.parent_id, t.title, COALESCE(t.description,''), t.completed,
+		TO_CHAR((t.time_stamp AT TIME ZONE 'UTC') AT TIME ZONE $2, 'YYYY/MM/DD HH:MI AM') AS date_added,
+		COALESCE(CAST(t.due_date AS TEXT), '') AS due_date,
+		COALESCE(t.position,0)
+		FROM tasks t WHERE t.parent_id = ANY($1) AND t.deleted_at IS NULL ORDER BY t.position, t.id`, ids, timezone)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st Task
+		if err := rows.Scan(&st.ID, &st.ParentID, &st.Title, &st.Description, &st.Completed, &st.DateAdded, &st.DueDate, &st.Position); err != nil {
+			return err
+		}
+		if i, ok := index[st.ParentID]; ok {
+			list[i].Subtasks = append(list[i].Subtasks, st)
+		}
+	}
+	return rows.Err()
+}
+
+// ReturnTaskForUser fetches a single task (with its subtasks) owned by the user.
+// It is used to re-render one row after an in-place update.
+func ReturnTaskForUser(id int, userID int, timezone string) (*Task, error) {
+	pool, err := storage.OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer storage.CloseDatabase(pool)
+
+	t, err := scanTask(pool.QueryRow(context.Background(), taskColumns+`WHERE t.id = $2 AND t.user_id = $3`, timezone, id, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	list := []Task{t}
+	if err := attachRelated(pool, list, userID, timezone); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TaskFilter narrows a task list beyond the project filter and picks its order. The zero
+// value matches every task in position order.
+type TaskFilter struct {
+	TagIDs         []int
+	MatchAllTags   bool   // true: task must carry every tag (AND); false: any of them (OR)
+	SortByPriority bool   // order by priority (highest first), then by position
+	Deferred       bool   // list only tasks snoozed past Today instead of hiding them
+	Today          string // the user's local date (YYYY-MM-DD) snoozes are compared with
+	DueFrom        string // keep tasks due on or after this date (YYYY-MM-DD), empty for no bound
+	DueTo          string // keep tasks due on or before this date (YYYY-MM-DD), empty for no bound
+	View           string // smart view (ViewToday, ...) keeping open tasks due relative to Today
+	Project        *int   // keep tasks of this project (0 for none), for queries without a project filter
+	Completed      *bool  // keep completed (true) or open (false) tasks, nil for both
+
+	// Custom fields of the filtered project: keep tasks whose FilterField value matches
+	// FilterValue (normalized), and/or order by the SortField value.
+	FilterField *storage.ProjectField
+	FilterValue string
+	SortField   *storage.ProjectField
+	SortDesc    bool
+}
+
+// IsEmpty reports whether the filter matches every task. The sort order does not count,
+// and neither does hiding snoozed tasks from the default list.
+func (f TaskFilter) IsEmpty() bool {
+	return len(f.TagIDs) == 0 && !f.Deferred && f.FilterField == nil && f.DueFrom == "" && f.DueTo == "" && f.View == "" &&
+		f.Project == nil && f.Completed == nil
+}
+
+// sqlArgs collects the values bound to the placeholders of a query.
+type sqlArgs []interface{}
+
+// bind adds v to the arguments and returns its placeholder.
+func (a *sqlArgs) bind(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// sqlCondition returns the clause appended to a WHERE on tasks aliased as t. Values that
+// come from the user are bound to placeholders added to args, so args must already hold
+// the arguments of the query before the clause. Tag ids are integers and dates are
+// validated, so they are inlined the same way the project filter is.
+func (f TaskFilter) sqlCondition(args *sqlArgs) string {
+	return f.tagCondition() + f.fieldCondition(args) + f.dueCondition() + f.viewCondition() + f.scopeCondition()
+}
+
+// scopeCondition returns the clause that keeps tasks of Project and with the Completed state.
+func (f TaskFilter) scopeCondition() string {
+	cond := ""
+	if f.Project != nil {
+		if *f.Project == 0 {
+			cond += " AND (t.project_id IS NULL)"
+		} else {
+			cond += fmt.Sprintf(" AND (t.project_id = %d)", *f.Project)
+		}
+	}
+	if f.Completed != nil {
+		if *f.Completed {
+			cond += " AND t.completed = true"
+		} else {
+			cond += " AND (t.completed IS NULL OR t.completed = false)"
+		}
+	}
+	return cond
+}
+
+// tagCondition returns the clause that keeps tasks carrying the filtered tags.
+func (f TaskFilter) tagCondition() string {
+	if len(f.TagIDs) == 0 {
+		return ""
+	}
+	ids := make([]string, 0, len(f.TagIDs))
+	for _, id := range f.TagIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	array := "ARRAY[" + strings.Join(ids, ",") + "]::int[]"
+	if f.MatchAllTags {
+		return fmt.Sprintf(" AND (SELECT COUNT(DISTINCT tt.tag_id) FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id = ANY(%s)) = %d", array, len(f.TagIDs))
+	}
+	return fmt.Sprintf(" AND EXISTS (SELECT 1 FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id = ANY(%s))", array)
+}
+
+// dueCondition returns the clause that keeps tasks due within DueFrom and DueTo. The
+// bounds are inlined like Today, so a bound that is not a date is ignored.
+func (f TaskFilter) dueCondition() string {
+	cond := ""
+	if d, err := time.Parse("2006-01-02", f.DueFrom); err == nil {
+		cond += fmt.Sprintf(" AND t.due_date >= DATE '%s'", d.Format("2006-01-02"))
+	}
+	if d, err := time.Parse("2006-01-02", f.DueTo); err == nil {
+		cond += fmt.Sprintf(" AND t.due_date <= DATE '%s'", d.Format("2006-01-02"))
+	}
+	return cond
+}
+
+// deferCondition returns the clause that hides tasks snoozed past Today from a task list,
+// or with Deferred keeps only those. Today is a formatted date, so it is inlined.
+func (f TaskFilter) deferCondition() string {
+	if f.Today == "" {
+		return ""
+	}
+	if f.Deferred {
+		return fmt.Sprintf(" AND t.hidden_until > DATE '%s'", f.Today)
+	}
+	return fmt.Sprintf(" AND (t.hidden_until IS NULL OR t.hidden_until <= DATE '%s')", f.Today)
+}
+
+// orderBy returns the ORDER BY clause for tasks aliased as t. A custom field sort comes
+// first, with tasks lacking a value last, then the priority or manual order.
+func (f TaskFilter) orderBy() string {
+	if f.SortField != nil {
+		dir := " ASC"
+		if f.SortDesc {
+			dir = " DESC"
+		}
+		next := " t.position, t.id"
+		if f.SortByPriority {
+			next = " t.priority DESC, t.position, t.id"
+		}
+		return " ORDER BY " + f.fieldSortKey() + dir + " NULLS LAST," + next
+	}
+	if f.SortByPriority {
+		return " ORDER BY t.priority DESC, t.position, t.id"
+	}
+	return " ORDER BY t.position"
+}
+
+// attachRelated loads the subtasks, tags, custom field values, blockers and workflow
+// statuses for every task in the slice.
+func attachRelated(pool *pgxpool.Pool, list []Task, userID int, timezone string) error {
+	if err := attachSubtasks(pool, list, timezone); err != nil {
+		return err
+	}
+	if err := attachTags(pool, list); err != nil {
+		return err
+	}
+	if err := attachFieldValues(pool, list); err != nil {
+		return err
+	}
+	if err := attachBlockers(pool, list); err != nil {
+		return err
+	}
+	return attachStatuses(pool, list, userID)
+}
+
+// attachTags loads the tags assigned to every task in the slice, ordered by name.
+func attachTags(pool *pgxpool.Pool, list []Task) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(list))
+	index := make(map[int]int, len(list))
+	for i, t := range list {
+		ids = append(ids, t.ID)
+		index[t.ID] = i
+	}
+
+	rows, err := pool.Query(context.Background(), `SELECT tt.task_id, g.id, g.user_id, g.name, g.color, g.created_at
+		FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+		WHERE tt.task_id = ANY($1) ORDER BY g.name`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var g storage.Tag
+		if err := rows.Scan(&taskID, &g.ID, &g.UserID, &g.Name, &g.Color, &g.CreatedAt); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			list[i].Tags = append(list[i].Tags, g)
+		}
+	}
+	return rows.Err()
+}
+
+// CountCompletionForUser returns the completed and incomplete top-level task counts for
+// a user, scoped by the optional project filter and the task filter. Like
+// storage.CountTasksByDone it counts tasks by their workflow status.
+func CountCompletionForUser(userID int, projectFilter *int, filter TaskFilter) (completed int, incomplete int, err error) {
+	pool, err := storage.OpenDatabase()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer storage.CloseDatabase(pool)
+
+	cond := ""
+	if projectFilter != nil {
+		if *projectFilter == 0 {
+			cond = " AND (t.project_id IS NULL)"
+		} else {
+			cond = fmt.Sprintf(" AND (t.project_id = %d)", *projectFilter)
+		}
+	}
+	args := sqlArgs{userID}
+	cond += filter.sqlCondition(&args)
+	// Count what the list shows: snoozed tasks only in the Deferred view
+	cond += filter.deferCondition()
+
+	err = pool.QueryRow(context.Background(), `SELECT
+		COUNT(*) FILTER (WHERE COALESCE(ts.is_done, t.completed, false)),
+		COUNT(*) FILTER (WHERE NOT COALESCE(ts.is_done, t.completed, false))
+		FROM tasks t LEFT JOIN task_statuses ts ON ts.id = t.status_id WHERE t.user_id = $1 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.archived_at IS NULL`+cond, args...).Scan(&completed, &incomplete)
+	return completed, incomplete, err
+}
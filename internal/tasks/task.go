@@ -1,24 +1,48 @@
 package tasks
 
 import (
+	"GoTodo/internal/storage"
 	"fmt"
+	"html/template"
 	"sync"
 )
 
 type Task struct {
-	ID           int
-	Title        string
-	Description  string
-	Completed    bool
-	DateAdded    string // time_stamp formatted for display
-	DueDate      string // Due date (YYYY-MM-DD format)
-	DateCreated  string // time_stamp formatted for tooltip
-	DateModified string // date_modified formatted for tooltip
-	Page         int
-	IsFavorite   bool
-	Position     int
-	ProjectID    int
-	ProjectName  string
+	ID              int
+	Title           string
+	Description     string
+	TitleHTML       template.HTML // Title with search matches marked, set only in search results
+	DescriptionHTML template.HTML // Description with search matches marked, as TitleHTML
+	Completed       bool
+	DateAdded       string // time_stamp formatted for display
+	DueDate         string // Due date (YYYY-MM-DD format)
+	DueTime         string // Optional due time (HH:MM), only set with a due date
+	DateCreated     string // time_stamp formatted for tooltip
+	DateModified    string // date_modified formatted for tooltip
+	Page            int
+	IsFavorite      bool
+	Position        int
+	ProjectID       int
+	ProjectName     string
+	ParentID        int    // 0 for top-level tasks
+	Subtasks        []Task // child tasks ordered by position (top-level tasks only)
+	RepeatRule      string // RRULE subset, empty when the task does not repeat
+	Tags            []storage.Tag
+	Priority        int               // PriorityNone .. PriorityUrgent
+	Blockers        []storage.TaskRef // tasks this one is blocked by (top-level tasks only)
+	Notes           string            // long-form Markdown, rendered with NotesHTML
+	CommentCount    int
+	AttachmentCount int
+	TrackedSeconds  int64 // time logged on the task, including a running timer
+	TimerRunning    bool
+	Archived        bool                 // hidden from the task list, still found by search
+	HiddenUntil     string               // YYYY-MM-DD a snoozed task reappears, empty when it is not snoozed
+	Estimate        float64              // effort estimate in EstimateUnit, 0 when unset
+	EstimateUnit    string               // EstimateHours or EstimatePoints, empty when unset
+	Fields          []FieldValue         // custom field values, in the project's field order
+	StatusID        int                  // stored workflow status, 0 when unset; see Status
+	Status          storage.TaskStatus   // status the task is in (top-level tasks only)
+	Statuses        []storage.TaskStatus // statuses of the task's workflow (top-level tasks only)
 }
 
 type TaskManager struct {
@@ -45,6 +69,43 @@ func (tm *TaskManager) GetTasks() []Task {
 	return tasks
 }
 
+// SubtaskCount returns the number of child tasks.
+func (t Task) SubtaskCount() int {
+	return len(t.Subtasks)
+}
+
+// SubtasksCompleted returns how many child tasks are complete.
+func (t Task) SubtasksCompleted() int {
+	count := 0
+	for _, st := range t.Subtasks {
+		if st.Completed {
+			count++
+		}
+	}
+	return count
+}
+
+// HasOpenSubtasks reports whether any child task is still incomplete.
+func (t Task) HasOpenSubtasks() bool {
+	return t.SubtasksCompleted() < len(t.Subtasks)
+}
+
+// OpenBlockers returns the blockers that are not complete yet.
+func (t Task) OpenBlockers() []storage.TaskRef {
+	open := make([]storage.TaskRef, 0)
+	for _, b := range t.Blockers {
+		if !b.Completed {
+			open = append(open, b)
+		}
+	}
+	return open
+}
+
+// IsBlocked reports whether any blocker is still incomplete.
+func (t Task) IsBlocked() bool {
+	return len(t.OpenBlockers()) > 0
+}
+
 func (t *Task) Validate() error {
 	if t.Title == "" {
 		return fmt.Errorf("title cannot be empty")
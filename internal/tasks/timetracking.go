@@ -0,0 +1,72 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxManualEntry is the longest span a single manual time entry may cover.
+const MaxManualEntry = 24 * time.Hour
+
+// FormatDuration renders logged seconds as hours and minutes, e.g. "1h 05m" or "12m".
+func FormatDuration(seconds int64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+// FormatHours renders logged seconds as decimal hours with two places, for exports.
+func FormatHours(seconds int64) string {
+	return strconv.FormatFloat(float64(seconds)/3600, 'f', 2, 64)
+}
+
+// ParseDurationInput reads a manually entered duration: "90" (minutes), "1:30",
+// "1.5h", "1h30m" or "45m". The result is between one minute and MaxManualEntry.
+func ParseDurationInput(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if s == "" {
+		return 0, errors.New("enter a duration such as 1h30m or 45")
+	}
+
+	var d time.Duration
+	if h, m, ok := strings.Cut(s, ":"); ok {
+		hours, err1 := strconv.Atoi(h)
+		mins, err2 := strconv.Atoi(m)
+		if err1 != nil || err2 != nil || hours < 0 || mins < 0 || mins > 59 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(hours)*time.Hour + time.Duration(mins)*time.Minute
+	} else if mins, err := strconv.Atoi(s); err == nil {
+		d = time.Duration(mins) * time.Minute
+	} else {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = parsed.Round(time.Minute)
+	}
+
+	if d < time.Minute {
+		return 0, errors.New("durations must be at least one minute")
+	}
+	if d > MaxManualEntry {
+		return 0, errors.New("a single entry cannot exceed 24 hours")
+	}
+	return d, nil
+}
+
+// TrackedLabel returns the total time logged on the task, or "" when none is.
+func (t Task) TrackedLabel() string {
+	if t.TrackedSeconds <= 0 && !t.TimerRunning {
+		return ""
+	}
+	return FormatDuration(t.TrackedSeconds)
+}
@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"GoTodo/internal/storage"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Smart views list open tasks by their due date relative to the user's local today.
+const (
+	ViewToday    = "today"
+	ViewOverdue  = "overdue"
+	ViewUpcoming = "upcoming"
+	ViewNoDue    = "nodue"
+)
+
+// UpcomingDays is how far ahead of today the Upcoming view looks.
+const UpcomingDays = 7
+
+// SmartView is a built-in view as listed in the sidebar.
+type SmartView struct {
+	Key   string
+	Label string
+	Icon  string // Bootstrap icon name
+}
+
+// SmartViews are the built-in views in sidebar order.
+var SmartViews = []SmartView{
+	{ViewToday, "Today", "bi-calendar-day"},
+	{ViewOverdue, "Overdue", "bi-exclamation-circle"},
+	{ViewUpcoming, "Upcoming", "bi-calendar-week"},
+	{ViewNoDue, "No due date", "bi-calendar-x"},
+}
+
+// IsSmartView reports whether key names one of the built-in views.
+func IsSmartView(key string) bool {
+	for _, v := range SmartViews {
+		if v.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// viewCondition returns the clause that keeps the open tasks of the smart view in View.
+// Due dates are compared with Today, the user's local date, so it is empty until Today
+// is set. Upcoming covers the UpcomingDays days after today; today has its own view.
+func (f TaskFilter) viewCondition() string {
+	if f.Today == "" || !IsSmartView(f.View) {
+		return ""
+	}
+	today := fmt.Sprintf("DATE '%s'", f.Today)
+	open := " AND (t.completed IS NULL OR t.completed = false)"
+	switch f.View {
+	case ViewToday:
+		return " AND t.due_date = " + today + open
+	case ViewOverdue:
+		return " AND t.due_date < " + today + open
+	case ViewUpcoming:
+		return fmt.Sprintf(" AND t.due_date > %s AND t.due_date <= %s + %d", today, today, UpcomingDays) + open
+	default:
+		return " AND t.due_date IS NULL" + open
+	}
+}
+
+// CountSmartViews returns the number of tasks in each smart view, keyed by view, for the
+// user's local today in timezone. Like the views themselves the counts follow the optional
+// project filter and leave out archived and snoozed tasks.
+func CountSmartViews(userID int, timezone string, projectFilter *int) (map[string]int, error) {
+	pool, err := storage.OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer storage.CloseDatabase(pool)
+
+	base := TaskFilter{Today: LocalToday(timezone)}
+	cond := base.deferCondition()
+	if projectFilter != nil {
+		if *projectFilter == 0 {
+			cond += " AND (t.project_id IS NULL)"
+		} else {
+			cond += fmt.Sprintf(" AND (t.project_id = %d)", *projectFilter)
+		}
+	}
+
+	cols := make([]string, 0, len(SmartViews))
+	for _, v := range SmartViews {
+		f := base
+		f.View = v.Key
+		cols = append(cols, "COUNT(*) FILTER (WHERE true"+f.viewCondition()+")")
+	}
+	counts := make([]int, len(SmartViews))
+	dest := make([]interface{}, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	err = pool.QueryRow(context.Background(), "SELECT "+strings.Join(cols, ", ")+`
+		FROM tasks t WHERE t.user_id = $1 AND t.parent_id IS NULL AND t.deleted_at IS NULL AND t.archived_at IS NULL`+cond, userID).Scan(dest...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count smart views: %v", err)
+	}
+
+	out := make(map[string]int, len(SmartViews))
+	for i, v := range SmartViews {
+		out[v.Key] = counts[i]
+	}
+	return out, nil
+}